@@ -1,12 +1,14 @@
 package main
 
 import (
+	"GoWork_7/internal/config"
 	"GoWork_7/internal/database"
 	"GoWork_7/internal/router"
 	"GoWork_7/internal/utils"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 )
 
 // 柔兮姐姐
@@ -14,27 +16,65 @@ import (
 //美少女珠珠
 
 func main() {
+	// 子命令：数据库迁移
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("数据库迁移失败: %v", err)
+		}
+		return
+	}
+
+	// 加载配置 (默认值 < 配置文件 < 环境变量 < 命令行参数)
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
 	// 初始化日志记录器
-	if err := utils.InitLoggers(); err != nil {
+	if err := utils.InitLoggers(cfg.Log.Dir); err != nil {
 		log.Fatalf("初始化日志记录器失败: %v", err)
 	}
 	utils.SystemLogger.Info("日志记录器初始化成功")
 
+	// 加载 JWT 签名密钥
+	verifyKeys := make([]utils.KeySpec, 0, len(cfg.JWT.VerifyKeys))
+	for _, k := range cfg.JWT.VerifyKeys {
+		verifyKeys = append(verifyKeys, k.Spec())
+	}
+	signingKey := cfg.JWT.SigningKey.Spec()
+	if err := utils.InitKeyManager(&signingKey, verifyKeys); err != nil {
+		log.Fatalf("加载 JWT 密钥失败: %v", err)
+	}
+
 	// 连接数据库
 	utils.SystemLogger.Info("正在连接数据库...")
-	database.ConnectDB()
+	database.ConnectDB(cfg.Database)
 	defer database.DB.Close()
 	utils.SystemLogger.Info("数据库连接成功")
 
+	// 启动时自动执行未应用的迁移
+	if cfg.Database.AutoMigrate {
+		n, err := database.MigrateUp(database.DB)
+		if err != nil {
+			utils.SystemLogger.Error("数据库迁移失败: %v", err)
+			log.Fatalf("数据库迁移失败: %v", err)
+		}
+		utils.SystemLogger.Info("数据库迁移完成，本次应用 %d 个迁移", n)
+	}
+
 	// 设置路由
 	utils.SystemLogger.Info("正在设置路由...")
-	r := router.SetupRouter()
+	r, err := router.SetupRouter(cfg)
+	if err != nil {
+		utils.SystemLogger.Error("设置路由失败: %v", err)
+		log.Fatalf("设置路由失败: %v", err)
+	}
 	utils.SystemLogger.Info("路由设置成功")
 
 	// 启动服务器
-	addr := ":8090"
-	utils.SystemLogger.Info("服务器已启动，监听地址：http://localhost:%s", addr)
-	fmt.Printf("服务器已启动，监听地址：http://localhost:%s\n", addr)
+	addr := cfg.Server.Addr
+	utils.SystemLogger.Info("服务器已启动，监听地址：%s", addr)
+	fmt.Printf("服务器已启动，监听地址：%s\n", addr)
 	if err := http.ListenAndServe(addr, r); err != nil {
 		utils.SystemLogger.Error("服务器启动失败: %v", err)
 		fmt.Printf("服务器启动失败:%v\n", err)
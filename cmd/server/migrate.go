@@ -0,0 +1,65 @@
+package main
+
+import (
+	"GoWork_7/internal/config"
+	"GoWork_7/internal/database"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// runMigrate 处理 migrate 子命令
+// 用法: server migrate up|down [步数]|status [配置参数...]
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: %s migrate up|down [步数]|status [-config 文件] [-db-host ...]", os.Args[0])
+	}
+	action, rest := args[0], args[1:]
+
+	steps := 1
+	if action == "down" && len(rest) > 0 {
+		if n, err := strconv.Atoi(rest[0]); err == nil {
+			if n < 1 {
+				return fmt.Errorf("回滚步数必须大于 0: %d", n)
+			}
+			steps, rest = n, rest[1:]
+		}
+	}
+
+	cfg, err := config.Load(rest)
+	if err != nil {
+		return err
+	}
+	database.ConnectDB(cfg.Database)
+	defer database.DB.Close()
+
+	switch action {
+	case "up":
+		n, err := database.MigrateUp(database.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已应用 %d 个迁移\n", n)
+	case "down":
+		n, err := database.MigrateDown(database.DB, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已回滚 %d 个迁移\n", n)
+	case "status":
+		statuses, err := database.MigrationStatuses(database.DB)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", st.Version, st.Name, state)
+		}
+	default:
+		return fmt.Errorf("未知的 migrate 操作: %s", action)
+	}
+	return nil
+}
@@ -1,22 +0,0 @@
--- 创建用户表
-CREATE TABLE IF NOT EXISTS users (
-    id BIGINT AUTO_INCREMENT PRIMARY KEY,
-    username VARCHAR(50) NOT NULL UNIQUE,
-    password VARCHAR(255) NOT NULL,
-    last_login VARCHAR(50),
-    role VARCHAR(20) DEFAULT 'user',
-    status VARCHAR(20) DEFAULT 'enabled',
-    avatar VARCHAR(255),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-);
-
--- 创建默认管理员用户
-INSERT INTO users (username, password, role, status, avatar) 
-VALUES ('admin', '123456', 'admin', 'enabled', '1_admin.jpg') 
-ON DUPLICATE KEY UPDATE username = username;
-
--- 创建索引
-CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
-CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
-CREATE INDEX IF NOT EXISTS idx_users_status ON users(status);
@@ -0,0 +1,690 @@
+package config
+
+import (
+	"GoWork_7/internal/utils"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile 未指定 -config 时尝试加载的配置文件
+const DefaultConfigFile = "config.yaml"
+
+// Config 应用配置
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Upload   UploadConfig   `yaml:"upload" toml:"upload"`
+	Log      LogConfig      `yaml:"log" toml:"log"`
+	JWT      JWTConfig      `yaml:"jwt" toml:"jwt"`
+	RBAC     RBACConfig     `yaml:"rbac" toml:"rbac"`
+	Org      OrgConfig      `yaml:"org" toml:"org"`
+	Lockout  LockoutConfig  `yaml:"lockout" toml:"lockout"`
+	MFA      MFAConfig      `yaml:"mfa" toml:"mfa"`
+	Mail     MailConfig     `yaml:"mail" toml:"mail"`
+	Password PasswordConfig `yaml:"password" toml:"password"`
+	Trash    TrashConfig    `yaml:"trash" toml:"trash"`
+}
+
+// ServerConfig HTTP 服务配置
+type ServerConfig struct {
+	Addr string `yaml:"addr" toml:"addr"`
+	// PublicURL 对外访问地址，用于生成邮件中的链接
+	PublicURL string `yaml:"public_url" toml:"public_url"`
+}
+
+// DatabaseConfig MySQL 连接配置
+type DatabaseConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	// Params 额外的连接参数 (如 charset=utf8mb4&timeout=5s)；parseTime 始终开启，未指定 loc 时使用 Local
+	Params string `yaml:"params" toml:"params"`
+	// AutoMigrate 启动时自动执行未应用的迁移
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+// DSN 生成 go-sql-driver/mysql 连接串
+// 用户名、密码中的特殊字符由驱动转义；parseTime 始终开启 (时间列均扫描为 time.Time)，Params 中未指定 loc 时使用 Local
+// 返回: string 连接串, error Params 格式错误
+func (c DatabaseConfig) DSN() (string, error) {
+	cfg, err := mysql.ParseDSN("/?" + c.Params)
+	if err != nil {
+		return "", fmt.Errorf("database.params 格式错误: %v", err)
+	}
+	if q, _ := url.ParseQuery(c.Params); !q.Has("loc") {
+		cfg.Loc = time.Local
+	}
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	cfg.DBName = c.Name
+	cfg.ParseTime = true
+	return cfg.FormatDSN(), nil
+}
+
+// UploadConfig 上传文件配置
+type UploadConfig struct {
+	// Driver 存储后端：local (本地磁盘，由 /images/ 静态路由提供访问) 或 s3 (S3 兼容对象存储，多实例部署时使用)
+	Driver string `yaml:"driver" toml:"driver"`
+	// Dir local 方式的保存目录
+	Dir string `yaml:"dir" toml:"dir"`
+	// BaseURL local 方式的访问前缀，可改为 CDN 地址
+	BaseURL string         `yaml:"base_url" toml:"base_url"`
+	S3      UploadS3Config `yaml:"s3" toml:"s3"`
+	// TempTTLMinutes 新建用户前上传的头像的有效期，超过后不能再使用；也是清理不再被引用的头像文件前的等待时间
+	TempTTLMinutes int `yaml:"temp_ttl_minutes" toml:"temp_ttl_minutes"`
+	// JanitorIntervalMinutes 头像及过期上传清理任务的检查间隔
+	JanitorIntervalMinutes int `yaml:"janitor_interval_minutes" toml:"janitor_interval_minutes"`
+	// DefaultAvatarStyle 未上传头像的用户的默认头像样式：identicon (由用户名生成的对称图案) 或 initials (用户名首字母)
+	DefaultAvatarStyle string `yaml:"default_avatar_style" toml:"default_avatar_style"`
+	// DefaultAvatarSecret 计算默认头像地址签名的密钥；多实例部署时须配置为相同的值，未配置时使用进程内临时密钥
+	DefaultAvatarSecret string `yaml:"default_avatar_secret" toml:"default_avatar_secret"`
+	// Tus 可续传上传 (/api/uploads/) 的限制
+	Tus UploadTusConfig `yaml:"tus" toml:"tus"`
+}
+
+// UploadTusConfig 可续传上传配置
+type UploadTusConfig struct {
+	// MaxSizeMB 单个文件的大小上限
+	MaxSizeMB int `yaml:"max_size_mb" toml:"max_size_mb"`
+	// QuotaMB 每个用户可占用的空间 (已完成及未过期的未完成上传)
+	QuotaMB int `yaml:"quota_mb" toml:"quota_mb"`
+	// ExpiryHours 未完成的上传在最后一次写入后的保留时长，过期后由清理任务删除
+	ExpiryHours int `yaml:"expiry_hours" toml:"expiry_hours"`
+}
+
+// UploadS3Config S3 兼容对象存储配置 (AWS S3、MinIO 等)
+type UploadS3Config struct {
+	// Endpoint 服务地址，不含协议，如 s3.amazonaws.com 或 minio:9000
+	Endpoint  string `yaml:"endpoint" toml:"endpoint"`
+	Region    string `yaml:"region" toml:"region"`
+	Bucket    string `yaml:"bucket" toml:"bucket"`
+	AccessKey string `yaml:"access_key" toml:"access_key"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key"`
+	UseSSL    bool   `yaml:"use_ssl" toml:"use_ssl"`
+	// PathStyle 使用 endpoint/bucket/key 形式的地址 (MinIO 通常需要开启)
+	PathStyle bool `yaml:"path_style" toml:"path_style"`
+	// PublicURL 桶可公开读取时的访问前缀 (如 CDN 地址)；为空时生成预签名地址
+	PublicURL string `yaml:"public_url" toml:"public_url"`
+	// PresignExpirySeconds 预签名地址有效期
+	PresignExpirySeconds int `yaml:"presign_expiry_seconds" toml:"presign_expiry_seconds"`
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Dir string `yaml:"dir" toml:"dir"`
+}
+
+// JWTConfig 令牌配置
+type JWTConfig struct {
+	// SigningKey 当前签名密钥，未配置时使用进程内临时密钥
+	SigningKey KeyConfig `yaml:"signing_key" toml:"signing_key"`
+	// VerifyKeys 轮换窗口内仍需接受的旧密钥
+	VerifyKeys []KeyConfig `yaml:"verify_keys" toml:"verify_keys"`
+	// RevocationStore 撤销列表实现：memory 或 sql
+	RevocationStore string `yaml:"revocation_store" toml:"revocation_store"`
+}
+
+// RBACConfig 角色权限配置
+type RBACConfig struct {
+	// DefaultRole 注册用户及未指定角色的新用户的默认角色
+	DefaultRole string `yaml:"default_role" toml:"default_role"`
+}
+
+// OrgConfig 组织 (租户) 配置
+type OrgConfig struct {
+	// DefaultID 自助注册用户加入的默认组织ID (该组织不可删除)
+	DefaultID int `yaml:"default_id" toml:"default_id"`
+}
+
+// LockoutConfig 登录失败锁定配置 (时间单位均为秒)
+type LockoutConfig struct {
+	// MaxFailures 同一账号在计数窗口内允许的连续失败次数，超过后锁定；0 表示关闭账号维度的锁定
+	MaxFailures int `yaml:"max_failures" toml:"max_failures"`
+	// IPMaxFailures 同一 IP 在计数窗口内允许的失败次数；0 表示关闭 IP 维度的锁定
+	IPMaxFailures int `yaml:"ip_max_failures" toml:"ip_max_failures"`
+	// LockSeconds 首次锁定时长，之后每多失败一次翻倍
+	LockSeconds int `yaml:"lock_seconds" toml:"lock_seconds"`
+	// MaxLockSeconds 单次锁定时长上限
+	MaxLockSeconds int `yaml:"max_lock_seconds" toml:"max_lock_seconds"`
+	// WindowSeconds 失败计数窗口，距上次失败超过该时间后重新计数
+	WindowSeconds int `yaml:"window_seconds" toml:"window_seconds"`
+}
+
+// MFAConfig 二次验证配置
+type MFAConfig struct {
+	// Issuer 认证器 App 中显示的签发方名称
+	Issuer string `yaml:"issuer" toml:"issuer"`
+}
+
+// PasswordConfig 密码策略配置 (注册、管理员创建、本人修改与找回密码统一生效)
+type PasswordConfig struct {
+	MinLength     int  `yaml:"min_length" toml:"min_length"`
+	MaxLength     int  `yaml:"max_length" toml:"max_length"`
+	RequireUpper  bool `yaml:"require_upper" toml:"require_upper"`
+	RequireLower  bool `yaml:"require_lower" toml:"require_lower"`
+	RequireDigit  bool `yaml:"require_digit" toml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol" toml:"require_symbol"`
+	// BlocklistFile 额外的弱密码黑名单文件 (每行一个)，与内置常见弱密码合并
+	BlocklistFile string `yaml:"blocklist_file" toml:"blocklist_file"`
+	// History 不能与最近 N 个密码 (含当前密码) 相同；0 表示不限制
+	History int `yaml:"history" toml:"history"`
+	// MaxAgeDays 密码最长有效天数，过期后登录时提示修改；0 表示永不过期
+	MaxAgeDays int `yaml:"max_age_days" toml:"max_age_days"`
+}
+
+// TrashConfig 用户回收站配置
+type TrashConfig struct {
+	// RetentionDays 已删除用户在回收站中的保留天数，超过后自动彻底删除；0 表示永久保留
+	RetentionDays int `yaml:"retention_days" toml:"retention_days"`
+	// PurgeIntervalMinutes 自动清理过期用户的检查间隔
+	PurgeIntervalMinutes int `yaml:"purge_interval_minutes" toml:"purge_interval_minutes"`
+}
+
+// MailConfig 邮件发送配置
+type MailConfig struct {
+	// Driver 发送方式：log (仅写日志)、file (保存为 .eml 文件) 或 smtp
+	Driver string `yaml:"driver" toml:"driver"`
+	From   string `yaml:"from" toml:"from"`
+	// Dir file 方式的保存目录
+	Dir  string     `yaml:"dir" toml:"dir"`
+	SMTP SMTPConfig `yaml:"smtp" toml:"smtp"`
+}
+
+// SMTPConfig SMTP 服务器配置
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+	// TLS 加密方式：starttls、tls 或 none
+	TLS string `yaml:"tls" toml:"tls"`
+}
+
+// KeyConfig 签名密钥配置
+type KeyConfig struct {
+	ID             string `yaml:"id" toml:"id"`
+	Algorithm      string `yaml:"algorithm" toml:"algorithm"`
+	Secret         string `yaml:"secret" toml:"secret"`
+	SecretFile     string `yaml:"secret_file" toml:"secret_file"`
+	PrivateKeyFile string `yaml:"private_key_file" toml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file" toml:"public_key_file"`
+}
+
+// Spec 转换为密钥管理器使用的密钥描述
+func (k KeyConfig) Spec() utils.KeySpec {
+	return utils.KeySpec{
+		ID:             k.ID,
+		Algorithm:      k.Algorithm,
+		Secret:         k.Secret,
+		SecretFile:     k.SecretFile,
+		PrivateKeyFile: k.PrivateKeyFile,
+		PublicKeyFile:  k.PublicKeyFile,
+	}
+}
+
+// Default 返回开发环境默认配置 (与历史硬编码值保持一致)
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{Addr: ":8090", PublicURL: "http://localhost:8090"},
+		Database: DatabaseConfig{
+			// 密码没有默认值，须通过配置文件、DB_PASSWORD 或 -db-password 设置
+			Host:   "127.0.0.1",
+			Port:   3306,
+			User:   "root",
+			Name:   "backstage",
+			Params: "loc=Local",
+			// 开发环境默认自动迁移，生产环境可关闭后使用 migrate 子命令
+			AutoMigrate: true,
+		},
+		Upload: UploadConfig{
+			Driver:  "local",
+			Dir:     filepath.Join("view", "images"),
+			BaseURL: "/images",
+			S3:      UploadS3Config{Region: "us-east-1", UseSSL: true, PresignExpirySeconds: 3600},
+
+			TempTTLMinutes:         60,
+			JanitorIntervalMinutes: 60,
+			DefaultAvatarStyle:     "identicon",
+			Tus:                    UploadTusConfig{MaxSizeMB: 20, QuotaMB: 100, ExpiryHours: 24},
+		},
+		Log:  LogConfig{Dir: filepath.Join("logs", "app")},
+		JWT:  JWTConfig{RevocationStore: "memory"},
+		RBAC: RBACConfig{DefaultRole: "common"},
+		Org:  OrgConfig{DefaultID: 1},
+		Lockout: LockoutConfig{
+			MaxFailures:    5,
+			IPMaxFailures:  20,
+			LockSeconds:    60,
+			MaxLockSeconds: 3600,
+			WindowSeconds:  900,
+		},
+		MFA: MFAConfig{Issuer: "GoWork_7"},
+		Password: PasswordConfig{
+			MinLength:    8,
+			MaxLength:    64,
+			RequireLower: true,
+			RequireDigit: true,
+			History:      5,
+		},
+		Trash: TrashConfig{RetentionDays: 30, PurgeIntervalMinutes: 60},
+		Mail: MailConfig{
+			Driver: "log",
+			From:   "GoWork_7 <no-reply@localhost>",
+			Dir:    filepath.Join("logs", "mail"),
+			SMTP:   SMTPConfig{Port: 587, TLS: "starttls"},
+		},
+	}
+}
+
+// Load 按 默认值 < 配置文件 < 环境变量 < 命令行参数 的优先级加载配置并校验
+// 参数: args 命令行参数 (不含程序名)
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", "", "配置文件路径 (.yaml/.yml/.toml)")
+	addr := fs.String("addr", "", "HTTP 监听地址，如 :8090")
+	dbHost := fs.String("db-host", "", "数据库主机")
+	dbPort := fs.Int("db-port", 0, "数据库端口")
+	dbUser := fs.String("db-user", "", "数据库用户名")
+	dbPassword := fs.String("db-password", "", "数据库密码")
+	dbName := fs.String("db-name", "", "数据库名")
+	uploadDir := fs.String("upload-dir", "", "上传文件目录")
+	logDir := fs.String("log-dir", "", "日志目录")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// 1. 配置文件：-config > CONFIG_FILE > 当前目录下的 config.yaml (可选)
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	} else if _, err := os.Stat(DefaultConfigFile); err == nil {
+		if err := loadFile(cfg, DefaultConfigFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. 环境变量
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	// 3. 命令行参数：仅覆盖显式传入的参数
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Server.Addr = *addr
+		case "db-host":
+			cfg.Database.Host = *dbHost
+		case "db-port":
+			cfg.Database.Port = *dbPort
+		case "db-user":
+			cfg.Database.User = *dbUser
+		case "db-password":
+			cfg.Database.Password = *dbPassword
+		case "db-name":
+			cfg.Database.Name = *dbName
+		case "upload-dir":
+			cfg.Upload.Dir = *uploadDir
+		case "log-dir":
+			cfg.Log.Dir = *logDir
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile 根据扩展名解析 YAML 或 TOML 配置文件
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件 %s 失败: %w", path, err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("不支持的配置文件格式: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv 使用环境变量覆盖配置
+func applyEnv(cfg *Config) error {
+	setString(&cfg.Server.Addr, "SERVER_ADDR")
+	setString(&cfg.Server.PublicURL, "SERVER_PUBLIC_URL")
+	setString(&cfg.Database.Host, "DB_HOST")
+	setString(&cfg.Database.User, "DB_USER")
+	setString(&cfg.Database.Password, "DB_PASSWORD")
+	setString(&cfg.Database.Name, "DB_NAME")
+	setString(&cfg.Database.Params, "DB_PARAMS")
+	if err := setInt(&cfg.Database.Port, "DB_PORT"); err != nil {
+		return err
+	}
+	if err := setBool(&cfg.Database.AutoMigrate, "DB_AUTO_MIGRATE"); err != nil {
+		return err
+	}
+	setString(&cfg.Upload.Driver, "UPLOAD_DRIVER")
+	setString(&cfg.Upload.Dir, "UPLOAD_DIR")
+	setString(&cfg.Upload.BaseURL, "UPLOAD_BASE_URL")
+	setString(&cfg.Upload.S3.Endpoint, "S3_ENDPOINT")
+	setString(&cfg.Upload.S3.Region, "S3_REGION")
+	setString(&cfg.Upload.S3.Bucket, "S3_BUCKET")
+	setString(&cfg.Upload.S3.AccessKey, "S3_ACCESS_KEY")
+	setString(&cfg.Upload.S3.SecretKey, "S3_SECRET_KEY")
+	setString(&cfg.Upload.S3.PublicURL, "S3_PUBLIC_URL")
+	if err := setBool(&cfg.Upload.S3.UseSSL, "S3_USE_SSL"); err != nil {
+		return err
+	}
+	if err := setBool(&cfg.Upload.S3.PathStyle, "S3_PATH_STYLE"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Upload.S3.PresignExpirySeconds, "S3_PRESIGN_EXPIRY_SECONDS"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Upload.TempTTLMinutes, "UPLOAD_TEMP_TTL_MINUTES"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Upload.JanitorIntervalMinutes, "UPLOAD_JANITOR_INTERVAL_MINUTES"); err != nil {
+		return err
+	}
+	setString(&cfg.Upload.DefaultAvatarStyle, "UPLOAD_DEFAULT_AVATAR_STYLE")
+	setString(&cfg.Upload.DefaultAvatarSecret, "UPLOAD_DEFAULT_AVATAR_SECRET")
+	if err := setInt(&cfg.Upload.Tus.MaxSizeMB, "UPLOAD_TUS_MAX_SIZE_MB"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Upload.Tus.QuotaMB, "UPLOAD_TUS_QUOTA_MB"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Upload.Tus.ExpiryHours, "UPLOAD_TUS_EXPIRY_HOURS"); err != nil {
+		return err
+	}
+	setString(&cfg.Log.Dir, "LOG_DIR")
+
+	// JWT 签名密钥
+	setString(&cfg.JWT.SigningKey.ID, "JWT_KEY_ID")
+	setString(&cfg.JWT.SigningKey.Algorithm, "JWT_ALG")
+	setString(&cfg.JWT.SigningKey.Secret, "JWT_SECRET")
+	setString(&cfg.JWT.SigningKey.SecretFile, "JWT_SECRET_FILE")
+	setString(&cfg.JWT.SigningKey.PrivateKeyFile, "JWT_PRIVATE_KEY_FILE")
+	setString(&cfg.JWT.RevocationStore, "TOKEN_REVOCATION_STORE")
+	setString(&cfg.RBAC.DefaultRole, "RBAC_DEFAULT_ROLE")
+	setString(&cfg.MFA.Issuer, "MFA_ISSUER")
+
+	// 邮件
+	setString(&cfg.Mail.Driver, "MAIL_DRIVER")
+	setString(&cfg.Mail.From, "MAIL_FROM")
+	setString(&cfg.Mail.Dir, "MAIL_DIR")
+	setString(&cfg.Mail.SMTP.Host, "SMTP_HOST")
+	setString(&cfg.Mail.SMTP.Username, "SMTP_USERNAME")
+	setString(&cfg.Mail.SMTP.Password, "SMTP_PASSWORD")
+	setString(&cfg.Mail.SMTP.TLS, "SMTP_TLS")
+	if err := setInt(&cfg.Mail.SMTP.Port, "SMTP_PORT"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Org.DefaultID, "ORG_DEFAULT_ID"); err != nil {
+		return err
+	}
+
+	// 密码策略
+	setString(&cfg.Password.BlocklistFile, "PASSWORD_BLOCKLIST_FILE")
+	for key, dst := range map[string]*int{
+		"PASSWORD_MIN_LENGTH":   &cfg.Password.MinLength,
+		"PASSWORD_MAX_LENGTH":   &cfg.Password.MaxLength,
+		"PASSWORD_HISTORY":      &cfg.Password.History,
+		"PASSWORD_MAX_AGE_DAYS": &cfg.Password.MaxAgeDays,
+	} {
+		if err := setInt(dst, key); err != nil {
+			return err
+		}
+	}
+	for key, dst := range map[string]*bool{
+		"PASSWORD_REQUIRE_UPPER":  &cfg.Password.RequireUpper,
+		"PASSWORD_REQUIRE_LOWER":  &cfg.Password.RequireLower,
+		"PASSWORD_REQUIRE_DIGIT":  &cfg.Password.RequireDigit,
+		"PASSWORD_REQUIRE_SYMBOL": &cfg.Password.RequireSymbol,
+	} {
+		if err := setBool(dst, key); err != nil {
+			return err
+		}
+	}
+
+	// 用户回收站
+	if err := setInt(&cfg.Trash.RetentionDays, "TRASH_RETENTION_DAYS"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Trash.PurgeIntervalMinutes, "TRASH_PURGE_INTERVAL_MINUTES"); err != nil {
+		return err
+	}
+
+	// 登录失败锁定
+	for key, dst := range map[string]*int{
+		"LOGIN_MAX_FAILURES":           &cfg.Lockout.MaxFailures,
+		"LOGIN_IP_MAX_FAILURES":        &cfg.Lockout.IPMaxFailures,
+		"LOGIN_LOCK_SECONDS":           &cfg.Lockout.LockSeconds,
+		"LOGIN_MAX_LOCK_SECONDS":       &cfg.Lockout.MaxLockSeconds,
+		"LOGIN_FAILURE_WINDOW_SECONDS": &cfg.Lockout.WindowSeconds,
+	} {
+		if err := setInt(dst, key); err != nil {
+			return err
+		}
+	}
+
+	// JWT_VERIFY_KEYS="kid:alg:path[,kid:alg:path]"，HS256 的 path 为密钥文件，RS256/EdDSA 为公钥 PEM
+	if v, ok := os.LookupEnv("JWT_VERIFY_KEYS"); ok {
+		cfg.JWT.VerifyKeys = nil
+		for _, item := range strings.Split(v, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			parts := strings.SplitN(item, ":", 3)
+			if len(parts) != 3 {
+				return fmt.Errorf("JWT_VERIFY_KEYS 格式错误: %s", item)
+			}
+			key := KeyConfig{ID: parts[0], Algorithm: parts[1]}
+			if key.Algorithm == utils.AlgHS256 {
+				key.SecretFile = parts[2]
+			} else {
+				key.PublicKeyFile = parts[2]
+			}
+			cfg.JWT.VerifyKeys = append(cfg.JWT.VerifyKeys, key)
+		}
+	}
+	return nil
+}
+
+// Validate 启动时校验配置
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr 不能为空"))
+	} else if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("server.addr 格式错误: %v", err))
+	}
+	if c.Database.Host == "" || c.Database.User == "" || c.Database.Name == "" {
+		errs = append(errs, errors.New("database.host、database.user、database.name 不能为空"))
+	}
+	if c.Database.Password == "" {
+		errs = append(errs, errors.New("database.password 不能为空，请通过配置文件、DB_PASSWORD 或 -db-password 设置"))
+	}
+	if c.Database.Port <= 0 || c.Database.Port > 65535 {
+		errs = append(errs, fmt.Errorf("database.port 超出范围: %d", c.Database.Port))
+	}
+	if _, err := c.Database.DSN(); err != nil {
+		errs = append(errs, err)
+	}
+	switch c.Upload.Driver {
+	case "local":
+		if c.Upload.Dir == "" {
+			errs = append(errs, errors.New("upload.dir 不能为空"))
+		}
+		if c.Upload.BaseURL == "" {
+			errs = append(errs, errors.New("upload.base_url 不能为空"))
+		}
+	case "s3":
+		s3 := c.Upload.S3
+		if s3.Endpoint == "" || s3.Bucket == "" || s3.AccessKey == "" || s3.SecretKey == "" {
+			errs = append(errs, errors.New("upload.driver 为 s3 时须配置 upload.s3.endpoint、bucket、access_key、secret_key"))
+		} else if strings.Contains(s3.Endpoint, "://") || strings.Contains(s3.Endpoint, "/") {
+			errs = append(errs, fmt.Errorf("upload.s3.endpoint 只能为主机[:端口]，不含协议与路径: %q", s3.Endpoint))
+		}
+		// 预签名 URL 最长有效 7 天 (S3 签名 V4 的限制)
+		if s3.PublicURL == "" && (s3.PresignExpirySeconds <= 0 || s3.PresignExpirySeconds > 7*24*3600) {
+			errs = append(errs, fmt.Errorf("upload.s3.presign_expiry_seconds 必须在 1 到 604800 之间: %d", s3.PresignExpirySeconds))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("upload.driver 只能为 local 或 s3: %q", c.Upload.Driver))
+	}
+	if c.Upload.TempTTLMinutes <= 0 || c.Upload.JanitorIntervalMinutes <= 0 {
+		errs = append(errs, fmt.Errorf("upload.temp_ttl_minutes、upload.janitor_interval_minutes 必须为正整数: %d/%d",
+			c.Upload.TempTTLMinutes, c.Upload.JanitorIntervalMinutes))
+	}
+	if s := c.Upload.DefaultAvatarStyle; s != "identicon" && s != "initials" {
+		errs = append(errs, fmt.Errorf("upload.default_avatar_style 只能为 identicon 或 initials: %q", s))
+	}
+	if t := c.Upload.Tus; t.MaxSizeMB <= 0 || t.QuotaMB <= 0 || t.ExpiryHours <= 0 {
+		errs = append(errs, fmt.Errorf("upload.tus.max_size_mb、quota_mb、expiry_hours 必须为正整数: %d/%d/%d",
+			t.MaxSizeMB, t.QuotaMB, t.ExpiryHours))
+	}
+	if c.Log.Dir == "" {
+		errs = append(errs, errors.New("log.dir 不能为空"))
+	}
+	if c.JWT.SigningKey.ID != "" && c.JWT.SigningKey.Algorithm == "" {
+		c.JWT.SigningKey.Algorithm = utils.AlgHS256
+	}
+	if c.RBAC.DefaultRole == "" {
+		errs = append(errs, errors.New("rbac.default_role 不能为空"))
+	}
+	if c.Org.DefaultID <= 0 {
+		errs = append(errs, fmt.Errorf("org.default_id 必须为正整数: %d", c.Org.DefaultID))
+	}
+	if c.Lockout.MaxFailures < 0 || c.Lockout.IPMaxFailures < 0 {
+		errs = append(errs, errors.New("lockout.max_failures、lockout.ip_max_failures 不能为负数"))
+	}
+	if c.Lockout.LockSeconds <= 0 || c.Lockout.MaxLockSeconds < c.Lockout.LockSeconds {
+		errs = append(errs, fmt.Errorf("lockout.lock_seconds 必须为正整数且不大于 lockout.max_lock_seconds: %d/%d",
+			c.Lockout.LockSeconds, c.Lockout.MaxLockSeconds))
+	}
+	if c.MFA.Issuer == "" || strings.Contains(c.MFA.Issuer, ":") {
+		errs = append(errs, fmt.Errorf("mfa.issuer 不能为空且不能包含冒号: %q", c.MFA.Issuer))
+	}
+	if c.Lockout.WindowSeconds <= 0 {
+		errs = append(errs, fmt.Errorf("lockout.window_seconds 必须为正整数: %d", c.Lockout.WindowSeconds))
+	}
+	// bcrypt 只接受 72 字节以内的密码
+	if c.Password.MinLength < 1 || c.Password.MaxLength < c.Password.MinLength || c.Password.MaxLength > 72 {
+		errs = append(errs, fmt.Errorf("password.min_length 必须为正整数且不大于 password.max_length (最大 72): %d/%d",
+			c.Password.MinLength, c.Password.MaxLength))
+	}
+	if c.Password.History < 0 || c.Password.MaxAgeDays < 0 {
+		errs = append(errs, errors.New("password.history、password.max_age_days 不能为负数"))
+	}
+	if c.Password.BlocklistFile != "" {
+		if _, err := os.Stat(c.Password.BlocklistFile); err != nil {
+			errs = append(errs, fmt.Errorf("password.blocklist_file 无法读取: %v", err))
+		}
+	}
+	if c.Trash.RetentionDays < 0 {
+		errs = append(errs, fmt.Errorf("trash.retention_days 不能为负数: %d", c.Trash.RetentionDays))
+	}
+	if c.Trash.PurgeIntervalMinutes <= 0 {
+		errs = append(errs, fmt.Errorf("trash.purge_interval_minutes 必须为正整数: %d", c.Trash.PurgeIntervalMinutes))
+	}
+	if c.Server.PublicURL == "" {
+		errs = append(errs, errors.New("server.public_url 不能为空"))
+	}
+	c.Server.PublicURL = strings.TrimRight(c.Server.PublicURL, "/")
+	if c.Mail.From == "" {
+		errs = append(errs, errors.New("mail.from 不能为空"))
+	}
+	switch c.Mail.Driver {
+	case "log":
+	case "file":
+		if c.Mail.Dir == "" {
+			errs = append(errs, errors.New("mail.driver 为 file 时 mail.dir 不能为空"))
+		}
+	case "smtp":
+		if c.Mail.SMTP.Host == "" || c.Mail.SMTP.Port <= 0 || c.Mail.SMTP.Port > 65535 {
+			errs = append(errs, fmt.Errorf("mail.driver 为 smtp 时须配置 mail.smtp.host 与有效端口: %q:%d", c.Mail.SMTP.Host, c.Mail.SMTP.Port))
+		}
+		switch c.Mail.SMTP.TLS {
+		case "starttls", "tls", "none":
+		default:
+			errs = append(errs, fmt.Errorf("mail.smtp.tls 只能为 starttls、tls 或 none: %q", c.Mail.SMTP.TLS))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mail.driver 只能为 log、file 或 smtp: %q", c.Mail.Driver))
+	}
+	switch c.JWT.RevocationStore {
+	case "memory", "sql":
+	default:
+		errs = append(errs, fmt.Errorf("jwt.revocation_store 只能为 memory 或 sql: %q", c.JWT.RevocationStore))
+	}
+	return errors.Join(errs...)
+}
+
+// setString 环境变量存在时覆盖字符串配置
+func setString(dst *string, key string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = v
+	}
+}
+
+// setInt 环境变量存在时覆盖整数配置
+func setInt(dst *int, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("环境变量 %s 不是有效整数: %s", key, v)
+	}
+	*dst = n
+	return nil
+}
+
+// setBool 环境变量存在时覆盖布尔配置
+func setBool(dst *bool, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("环境变量 %s 不是有效布尔值: %s", key, v)
+	}
+	*dst = b
+	return nil
+}
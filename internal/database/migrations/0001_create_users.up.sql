@@ -0,0 +1,18 @@
+-- 创建用户表 (兼容由旧版 init.sql 初始化的数据库)
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    username VARCHAR(50) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    last_login VARCHAR(50),
+    role VARCHAR(20) DEFAULT 'user',
+    status VARCHAR(20) DEFAULT 'enabled',
+    avatar VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_users_role (role),
+    INDEX idx_users_status (status)
+);
+
+-- 创建默认管理员用户 (密码 123456 的 bcrypt 哈希，首次登录后请及时修改)
+INSERT IGNORE INTO users (username, password, role, status, avatar)
+VALUES ('admin', '$2a$10$.EjvZv/2OtOmurL0acHEs.J1blwYkDJXbCuwhAuT.ZqhDOpJtBe9W', 'admin', 'enabled', '1_admin.jpg');
@@ -0,0 +1,12 @@
+-- 创建访问令牌撤销表 (按 jti 撤销单个令牌，过期后可清理)
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at DATETIME NOT NULL,
+    INDEX idx_revoked_tokens_expires (expires_at)
+);
+
+-- 创建用户级令牌撤销表 (revoked_before 之前签发的令牌全部失效)
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+    user_id BIGINT PRIMARY KEY,
+    revoked_before DATETIME NOT NULL
+);
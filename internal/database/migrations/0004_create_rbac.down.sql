@@ -0,0 +1,4 @@
+ALTER TABLE users MODIFY role VARCHAR(20) DEFAULT 'user';
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,48 @@
+-- 创建角色表 (is_system 为内置角色，禁止删除)
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建权限表
+CREATE TABLE IF NOT EXISTS permissions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+-- 创建角色-权限关联表
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT NOT NULL,
+    permission_id BIGINT NOT NULL,
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+
+-- 放宽 users.role 长度以容纳自定义角色名，默认角色与注册逻辑保持一致
+ALTER TABLE users MODIFY role VARCHAR(50) DEFAULT 'common';
+
+-- 内置权限
+INSERT IGNORE INTO permissions (name, description) VALUES
+    ('users:read', '查看用户列表'),
+    ('users:create', '新建用户'),
+    ('users:update', '修改其他用户的资料、角色、状态与头像'),
+    ('users:delete', '删除用户'),
+    ('sessions:revoke', '强制下线其他用户'),
+    ('roles:manage', '管理角色与权限');
+
+-- 内置角色：admin 拥有全部权限；common/user 为普通用户 (user 为旧版 users.role 默认值)
+INSERT IGNORE INTO roles (name, description, is_system) VALUES
+    ('admin', '管理员', TRUE),
+    ('common', '普通用户', TRUE),
+    ('user', '普通用户 (旧版默认角色)', TRUE);
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin';
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name = 'users:read' WHERE r.name IN ('common', 'user');
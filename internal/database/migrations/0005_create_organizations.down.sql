@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(50) DEFAULT 'common' AFTER last_login, ADD INDEX idx_users_role (role);
+
+-- 恢复用户在默认组织中的角色 (superadmin 降级为 admin)
+UPDATE users u JOIN org_members m ON m.user_id = u.id AND m.org_id = 1
+SET u.role = IF(m.role = 'superadmin', 'admin', m.role);
+
+ALTER TABLE refresh_tokens DROP COLUMN org_id;
+
+DELETE FROM roles WHERE name = 'superadmin';
+DELETE FROM permissions WHERE name = 'orgs:manage';
+
+DROP TABLE IF EXISTS org_members;
+DROP TABLE IF EXISTS organizations;
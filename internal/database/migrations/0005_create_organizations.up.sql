@@ -0,0 +1,47 @@
+-- 创建组织 (租户) 表
+CREATE TABLE IF NOT EXISTS organizations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建组织成员表 (用户可属于多个组织，role 为该用户在组织内的角色)
+CREATE TABLE IF NOT EXISTS org_members (
+    org_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    role VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id),
+    INDEX idx_org_members_user (user_id),
+    INDEX idx_org_members_role (role),
+    CONSTRAINT fk_org_members_org FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_org_members_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 默认组织：现有用户全部加入，并沿用原有角色
+INSERT IGNORE INTO organizations (id, name) VALUES (1, 'default');
+
+INSERT IGNORE INTO org_members (org_id, user_id, role)
+SELECT 1, id, COALESCE(role, 'common') FROM users;
+
+-- 角色改为按组织保存在 org_members 中
+ALTER TABLE users DROP COLUMN role;
+
+-- 刷新令牌记录签发时的组织，轮换后保持同一组织
+ALTER TABLE refresh_tokens ADD COLUMN org_id BIGINT NOT NULL DEFAULT 1 AFTER user_id;
+
+-- 平台级权限：管理组织及跨组织成员
+INSERT IGNORE INTO permissions (name, description) VALUES
+    ('orgs:manage', '管理组织及组织成员');
+
+-- 内置角色 superadmin：拥有全部权限 (含 orgs:manage)；admin 仅管理所在组织
+INSERT IGNORE INTO roles (name, description, is_system) VALUES
+    ('superadmin', '平台管理员', TRUE);
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'superadmin';
+
+-- 初始管理员账号升级为平台管理员
+UPDATE org_members m JOIN users u ON u.id = m.user_id
+SET m.role = 'superadmin'
+WHERE m.org_id = 1 AND u.username = 'admin' AND m.role = 'admin';
@@ -0,0 +1,26 @@
+-- 创建审计日志表 (before_data/after_data 仅保存发生变化的字段)
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    org_id BIGINT NULL,
+    actor_id BIGINT NULL,
+    actor_name VARCHAR(50) NOT NULL DEFAULT '',
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(30) NOT NULL DEFAULT '',
+    target_id VARCHAR(64) NOT NULL DEFAULT '',
+    before_data JSON NULL,
+    after_data JSON NULL,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_audit_logs_org_created (org_id, created_at),
+    INDEX idx_audit_logs_actor (actor_id),
+    INDEX idx_audit_logs_target (target_type, target_id),
+    INDEX idx_audit_logs_action (action)
+);
+
+-- 查看审计日志权限：授予 admin 与 superadmin
+INSERT IGNORE INTO permissions (name, description) VALUES
+    ('audit:read', '查看审计日志');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name = 'audit:read' WHERE r.name IN ('admin', 'superadmin');
@@ -0,0 +1,10 @@
+-- 登录失败计数 (scope 为 user 时 key_value 为小写用户名，为 ip 时为客户端 IP)
+CREATE TABLE IF NOT EXISTS login_attempts (
+    scope VARCHAR(10) NOT NULL,
+    key_value VARCHAR(100) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure DATETIME NOT NULL,
+    locked_until DATETIME NULL,
+    PRIMARY KEY (scope, key_value),
+    INDEX idx_login_attempts_last_failure (last_failure)
+);
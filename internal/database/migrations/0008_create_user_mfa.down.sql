@@ -0,0 +1,5 @@
+ALTER TABLE roles DROP COLUMN mfa_required;
+
+DROP TABLE IF EXISTS mfa_recovery_codes;
+
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS email_verifications;
+-- 待验证账号在回滚后视为禁用
+UPDATE users SET status = 'disabled' WHERE status = 'pending';
+ALTER TABLE users
+    DROP INDEX uk_users_email,
+    DROP COLUMN email_verified_at,
+    DROP COLUMN email;
@@ -0,0 +1,32 @@
+-- 用户邮箱：历史账号允许为空，非空时全局唯一 (统一保存为小写)
+ALTER TABLE users
+    ADD COLUMN email VARCHAR(254) NULL AFTER username,
+    ADD COLUMN email_verified_at DATETIME NULL AFTER email;
+
+-- 以邮箱作为用户名的历史账号：回填为未验证的邮箱，验证后方可用于找回密码
+-- 仅回填小写后唯一的用户名 (如 Bob@x.com 与 bob@x.com 同时存在时均不回填，避免违反唯一索引导致迁移中断)
+UPDATE users u
+JOIN (
+    SELECT LOWER(username) AS email FROM users
+    WHERE username LIKE '%_@_%._%' AND username NOT LIKE '% %'
+    GROUP BY LOWER(username)
+    HAVING COUNT(*) = 1
+) d ON LOWER(u.username) = d.email
+SET u.email = d.email
+WHERE u.email IS NULL;
+
+-- 回填完成后再建唯一索引
+ALTER TABLE users ADD UNIQUE INDEX uk_users_email (email);
+
+-- 邮箱验证令牌 (仅保存 SHA-256 摘要，email 记录签发时待验证的地址，used_at 非空表示已使用或已作废)
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    email VARCHAR(254) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_email_verifications_user (user_id, created_at),
+    CONSTRAINT fk_email_verifications_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
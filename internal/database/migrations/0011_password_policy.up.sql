@@ -0,0 +1,13 @@
+-- 密码最近一次修改时间 (用于最长有效期)；已有账号从迁移时开始计算
+ALTER TABLE users ADD COLUMN password_changed_at DATETIME NULL AFTER password;
+UPDATE users SET password_changed_at = NOW() WHERE password_changed_at IS NULL;
+
+-- 历史密码哈希 (修改密码时保存被替换的旧哈希，用于禁止重复使用最近的密码)
+CREATE TABLE IF NOT EXISTS password_history (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_password_history_user (user_id, id),
+    CONSTRAINT fk_password_history_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+-- 回收站中的账号保留为禁用状态，避免回滚后被重新启用
+UPDATE users SET status = 'disabled' WHERE deleted_at IS NOT NULL;
+DROP INDEX idx_users_deleted_at ON users;
+ALTER TABLE users DROP COLUMN deleted_by;
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,4 @@
+-- 用户软删除：deleted_at 非空表示账号在回收站中，可由管理员恢复，超过保留期后彻底删除
+ALTER TABLE users ADD COLUMN deleted_at DATETIME NULL AFTER status;
+ALTER TABLE users ADD COLUMN deleted_by BIGINT NULL AFTER deleted_at;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
@@ -0,0 +1,6 @@
+-- 导出用户列表权限：授予 admin 与 superadmin
+INSERT IGNORE INTO permissions (name, description) VALUES
+    ('users:export', '导出用户列表');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name = 'users:export' WHERE r.name IN ('admin', 'superadmin');
@@ -0,0 +1,3 @@
+-- 用户列表按最后登录与注册时间排序、筛选及游标分页所用索引
+CREATE INDEX idx_users_last_login ON users (last_login);
+CREATE INDEX idx_users_created_at ON users (created_at);
@@ -0,0 +1,2 @@
+DROP INDEX idx_users_avatar ON users;
+DROP TABLE IF EXISTS avatar_uploads;
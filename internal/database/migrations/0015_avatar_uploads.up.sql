@@ -0,0 +1,13 @@
+-- 临时头像上传：通用上传接口 (新建用户前) 保存的头像，创建用户时在同一事务中认领并删除记录，过期未认领的由清理任务删除
+CREATE TABLE IF NOT EXISTS avatar_uploads (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    avatar VARCHAR(255) NOT NULL,
+    org_id BIGINT NOT NULL,
+    uploaded_by BIGINT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_avatar_uploads_avatar (avatar),
+    INDEX idx_avatar_uploads_created (created_at)
+);
+
+-- 清理任务按文件名查询头像是否仍被引用
+CREATE INDEX idx_users_avatar ON users (avatar);
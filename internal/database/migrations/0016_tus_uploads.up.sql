@@ -0,0 +1,27 @@
+-- 可续传上传 (tus 1.0)：upload_offset 为已接收的字节数，每次 PATCH 收到的数据保存为一个分片对象，全部接收后合并为 storage_key
+-- 未完成的上传在 expires_at 后由清理任务删除；已完成的上传计入用户配额，直至本人删除
+CREATE TABLE IF NOT EXISTS tus_uploads (
+    id CHAR(32) PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    org_id BIGINT NOT NULL,
+    upload_length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    metadata VARCHAR(4096) NOT NULL DEFAULT '',
+    storage_key VARCHAR(255) NULL,
+    expires_at DATETIME NOT NULL,
+    completed_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_tus_uploads_user (user_id),
+    INDEX idx_tus_uploads_expires (expires_at),
+    CONSTRAINT fk_tus_uploads_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 已接收的分片 (按 part_offset 顺序合并)
+CREATE TABLE IF NOT EXISTS tus_upload_parts (
+    upload_id CHAR(32) NOT NULL,
+    part_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    PRIMARY KEY (upload_id, part_offset),
+    CONSTRAINT fk_tus_upload_parts_upload FOREIGN KEY (upload_id) REFERENCES tus_uploads(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name = 'roles:manage' WHERE r.name = 'admin';
@@ -0,0 +1,5 @@
+-- 角色为全局共享，管理角色只允许平台管理员：收回 admin 的 roles:manage，避免组织管理员修改影响其它组织的角色
+DELETE rp FROM role_permissions rp
+JOIN roles r ON r.id = rp.role_id
+JOIN permissions p ON p.id = rp.permission_id
+WHERE r.name = 'admin' AND p.name = 'roles:manage';
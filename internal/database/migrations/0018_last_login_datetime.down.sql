@@ -0,0 +1 @@
+ALTER TABLE users MODIFY COLUMN last_login VARCHAR(50) NULL;
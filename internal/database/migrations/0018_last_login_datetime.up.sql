@@ -0,0 +1,5 @@
+-- users.last_login 由 VARCHAR 改为 DATETIME：按时间而非字符串比较与排序，取值由应用以 time.Time 绑定，不再依赖会话时区
+-- 无法识别为时间的历史值置为 NULL (视为从未登录)
+UPDATE users SET last_login = NULL
+WHERE last_login IS NOT NULL AND last_login NOT REGEXP '^[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2}$';
+ALTER TABLE users MODIFY COLUMN last_login DATETIME NULL;
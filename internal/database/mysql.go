@@ -1,6 +1,7 @@
 package database
 
 import (
+	"GoWork_7/internal/config"
 	"GoWork_7/internal/utils"
 	"database/sql"
 	_ "github.com/go-sql-driver/mysql"
@@ -11,8 +12,13 @@ var (
 	err error
 )
 
-func ConnectDB() {
-	dsn := "root:231792@tcp(127.0.0.1:3306)/backstage"
+// ConnectDB 根据配置连接 MySQL 数据库
+func ConnectDB(cfg config.DatabaseConfig) {
+	dsn, err := cfg.DSN()
+	if err != nil {
+		utils.SystemLogger.Error("连接配置错误：%v", err)
+		panic(err)
+	}
 	DB, err = sql.Open("mysql", dsn)
 	if err != nil {
 		utils.SystemLogger.Error("连接配置错误：%v", err)
@@ -23,5 +29,5 @@ func ConnectDB() {
 		utils.SystemLogger.Error("数据库无法访问：%v", err)
 		panic(err)
 	}
-	utils.SystemLogger.Info("成功连接到MySQL数据库！")
+	utils.SystemLogger.Info("成功连接到MySQL数据库！(%s:%d/%s)", cfg.Host, cfg.Port, cfg.Name)
 }
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AuthHandler 令牌会话控制器 (刷新令牌等)
+type AuthHandler struct {
+	tokenService *service.TokenService
+	loginService *service.LoginService
+	rbacService  *service.RBACService
+	orgService   *service.OrgService
+	lockout      *service.LockoutService
+}
+
+// NewAuthHandler 创建令牌会话控制器实例
+func NewAuthHandler(tokenService *service.TokenService, loginService *service.LoginService, rbacService *service.RBACService, orgService *service.OrgService,
+	lockout *service.LockoutService) *AuthHandler {
+	return &AuthHandler{tokenService: tokenService, loginService: loginService, rbacService: rbacService, orgService: orgService, lockout: lockout}
+}
+
+// Refresh 使用刷新令牌换取新的令牌对 (RESTful: POST /api/auth/refresh)
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	user, tokens, err := h.tokenService.Refresh(req.RefreshToken)
+	if err != nil {
+		utils.AuthLogger.Error("刷新令牌失败: %v", err)
+		switch {
+		case errors.Is(err, service.ErrRefreshTokenReused):
+			utils.ErrorResponse(w, http.StatusUnauthorized, "刷新令牌已失效，请重新登录")
+		case errors.Is(err, service.ErrRefreshTokenInvalid):
+			utils.ErrorResponse(w, http.StatusUnauthorized, "刷新令牌无效或已过期")
+		case err.Error() == "ACCOUNT_DISABLED":
+			utils.ErrorResponse(w, http.StatusForbidden, "账户已被禁用")
+		default:
+			utils.ErrorResponse(w, http.StatusInternalServerError, "刷新令牌失败")
+		}
+		return
+	}
+
+	utils.SuccessResponse(w, "刷新成功", map[string]interface{}{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"id":            user.ID,
+		"role":          user.Role,
+		"org_id":        user.OrgID,
+		"username":      user.Username,
+		"permissions":   h.rbacService.Permissions(user.Role),
+	})
+}
+
+// Orgs 获取当前用户加入的全部组织 (RESTful: GET /api/auth/orgs)
+func (h *AuthHandler) Orgs(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int64)
+	orgID, _ := r.Context().Value("orgID").(int64)
+
+	memberships, err := h.orgService.Memberships(userID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+		return
+	}
+	utils.SuccessResponse(w, "查询成功", map[string]interface{}{
+		"org_id": orgID,
+		"orgs":   memberships,
+	})
+}
+
+// SwitchOrg 切换当前组织 (RESTful: POST /api/auth/switch-org)
+// 撤销当前访问令牌 (及可选提交的刷新令牌家族)，并签发目标组织下的新令牌对；
+// 目标组织内的角色需要二次验证时与登录相同返回 mfa_token，客户端提交验证码至 POST /api/auth/mfa/verify 换取目标组织的令牌
+func (h *AuthHandler) SwitchOrg(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int64)
+	jti, _ := r.Context().Value("tokenID").(string)
+	expiresAt, _ := r.Context().Value("tokenExpiresAt").(time.Time)
+
+	var req models.SwitchOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	current, err := h.tokenService.Session(userID, jti, expiresAt, req.RefreshToken)
+	if err != nil {
+		utils.AuthLogger.Error("用户 %d 切换组织时查询当前会话失败: %v", userID, err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "切换组织失败")
+		return
+	}
+
+	user, tokens, challenge, err := h.loginService.SwitchOrg(actorFromRequest(r), req.OrgID, current)
+	var expired *service.PasswordExpiredError
+	if errors.As(err, &expired) {
+		writePasswordExpired(w, expired, nil)
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotOrgMember):
+			utils.ErrorResponse(w, http.StatusForbidden, "您不是该组织的成员")
+		case err.Error() == "ACCOUNT_DISABLED":
+			utils.ErrorResponse(w, http.StatusForbidden, "账户已被禁用")
+		default:
+			utils.AuthLogger.Error("用户 %d 切换组织失败: %v", userID, err)
+			utils.ErrorResponse(w, http.StatusInternalServerError, "切换组织失败")
+		}
+		return
+	}
+
+	// 需要二次验证：当前会话保留至验证通过 (mfa_token 中记录了当前会话，验证通过后撤销)，仍可继续使用
+	if challenge != nil {
+		utils.AuthLogger.Info("用户 %d 切换到组织 %d，等待二次验证", userID, user.OrgID)
+		utils.SuccessResponse(w, "请输入二次验证码", map[string]interface{}{
+			"mfa_required": true,
+			"mfa_token":    challenge.Token,
+			"expires_in":   challenge.ExpiresIn,
+			"enrollment":   challenge.Enrollment,
+			"username":     user.Username,
+		})
+		return
+	}
+
+	// 旧组织的会话作废，失败不影响新令牌的使用
+	if err := h.tokenService.RevokeSession(current); err != nil {
+		utils.AuthLogger.Error("用户 %d 切换组织时撤销旧会话失败: %v", userID, err)
+	}
+
+	utils.AuthLogger.Info("用户 %d 切换到组织 %d", userID, user.OrgID)
+	utils.SuccessResponse(w, "切换成功", map[string]interface{}{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"id":            user.ID,
+		"role":          user.Role,
+		"org_id":        user.OrgID,
+		"username":      user.Username,
+		"permissions":   h.rbacService.Permissions(user.Role),
+	})
+}
+
+// Logout 注销当前会话 (RESTful: POST /api/auth/logout)
+// 请求体可选携带 refresh_token，一并撤销该刷新令牌所属家族
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int64)
+	jti, _ := r.Context().Value("tokenID").(string)
+	expiresAt, _ := r.Context().Value("tokenExpiresAt").(time.Time)
+
+	var req models.RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+	}
+
+	if err := h.tokenService.Logout(userID, jti, expiresAt, req.RefreshToken); err != nil {
+		utils.AuthLogger.Error("用户 %d 注销失败: %v", userID, err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "注销失败")
+		return
+	}
+
+	utils.AuthLogger.Info("用户 %d 已注销会话", userID)
+	utils.SuccessResponse(w, "注销成功", nil)
+}
+
+// RevokeUserSessions 强制下线当前组织内指定用户的全部会话 (RESTful: DELETE /api/users/{id}/sessions，需 sessions:revoke 权限)
+func (h *AuthHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	targetID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	if err := h.tokenService.RevokeUserSessions(actorFromRequest(r), targetID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, "找不到用户")
+			return
+		}
+		utils.AuthLogger.Error("撤销用户 %d 的会话失败: %v", targetID, err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "撤销会话失败")
+		return
+	}
+
+	operatorID, _ := r.Context().Value("userID").(int64)
+	utils.AuthLogger.Info("管理员 %d 已撤销用户 %d 的全部会话", operatorID, targetID)
+	utils.SuccessResponse(w, "已撤销该用户的全部会话", nil)
+}
+
+// UnlockUser 解除当前组织内指定用户的登录失败锁定 (RESTful: DELETE /api/users/{id}/lockout，需 users:update 权限)
+func (h *AuthHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	targetID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	cleared, err := h.lockout.Unlock(actorFromRequest(r), targetID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, "找不到用户")
+			return
+		}
+		utils.AuthLogger.Error("解除用户 %d 的登录锁定失败: %v", targetID, err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "解除锁定失败")
+		return
+	}
+
+	if !cleared {
+		utils.SuccessResponse(w, "该用户没有登录失败记录", nil)
+		return
+	}
+	operatorID, _ := r.Context().Value("userID").(int64)
+	utils.AuthLogger.Info("管理员 %d 已解除用户 %d 的登录锁定", operatorID, targetID)
+	utils.SuccessResponse(w, "已解除该用户的登录锁定", nil)
+}
+
+// JWKS 公开当前验签公钥集合 (GET /.well-known/jwks.json)，供其它服务离线校验本服务签发的令牌
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.SetCORSHeaders(w, "GET, OPTIONS")
+	json.NewEncoder(w).Encode(utils.CurrentKeyManager().JWKS())
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAvatarHandler 默认头像控制器
+type DefaultAvatarHandler struct {
+	defaultAvatarService *service.DefaultAvatarService
+}
+
+// NewDefaultAvatarHandler 创建默认头像控制器实例
+func NewDefaultAvatarHandler(defaultAvatarService *service.DefaultAvatarService) *DefaultAvatarHandler {
+	return &DefaultAvatarHandler{defaultAvatarService: defaultAvatarService}
+}
+
+// Serve 返回用户的默认头像 (RESTful: GET /images/default/{id}-{签名}.svg 或 .png)
+// 供 <img> 直接引用，无需登录；地址由用户列表等已登录接口返回，签名错误时返回 404
+// 响应带 ETag，浏览器每次使用前重新验证，用户名未变时返回 304
+func (h *DefaultAvatarHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	name, format, _ := strings.Cut(r.PathValue("file"), ".")
+	idStr, sig, _ := strings.Cut(name, "-")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	avatar, err := h.defaultAvatarService.Render(id, sig, format)
+	if err != nil {
+		if errors.Is(err, service.ErrDefaultAvatarFormat) || errors.Is(err, repository.ErrUserNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		utils.UserLogger.Error("生成用户 %d 的默认头像失败: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", avatar.ContentType)
+	w.Header().Set("ETag", avatar.ETag)
+	w.Header().Set("Cache-Control", "public, no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// SVG 被直接打开时禁止加载脚本及外部资源
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	// ServeContent 处理 If-None-Match (返回 304) 及 HEAD 请求
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(avatar.Data))
+}
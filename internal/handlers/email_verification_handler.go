@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// EmailVerificationHandler 邮箱验证控制器
+type EmailVerificationHandler struct {
+	verifyService *service.EmailVerificationService
+}
+
+// NewEmailVerificationHandler 创建邮箱验证控制器实例
+func NewEmailVerificationHandler(verifyService *service.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{verifyService: verifyService}
+}
+
+// VerifyEmail 凭验证令牌确认邮箱，待验证的账号同时激活 (RESTful: POST /api/auth/verify-email)
+func (h *EmailVerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	user, err := h.verifyService.Verify(actorFromRequest(r), req.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrVerificationTokenInvalid) {
+			utils.ErrorResponse(w, http.StatusBadRequest, "验证链接无效或已过期，请重新发送验证邮件")
+			return
+		}
+		utils.AuthLogger.Error("验证邮箱失败: %v", err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "验证邮箱失败")
+		return
+	}
+	utils.SuccessResponse(w, "邮箱验证成功", map[string]interface{}{
+		"username": user.Username,
+		"email":    user.Email,
+		"status":   user.Status,
+	})
+}
+
+// ResendVerification 重新发送验证邮件 (RESTful: POST /api/auth/resend-verification)
+// 无论账号是否存在均返回相同结果，避免枚举用户名或邮箱
+func (h *EmailVerificationHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req models.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "用户名或邮箱不能为空")
+		return
+	}
+
+	if err := h.verifyService.Resend(actorFromRequest(r), req.Username); err != nil {
+		utils.AuthLogger.Error("处理验证邮件重发申请失败: %v", err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	utils.SuccessResponse(w, "如果该账号存在且邮箱尚未验证，验证邮件已发送，请查收", nil)
+}
@@ -2,20 +2,27 @@ package handlers
 
 import (
 	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
 	"GoWork_7/internal/service"
 	"GoWork_7/internal/utils"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 )
 
 // LoginHandler 登录控制器
 type LoginHandler struct {
 	loginService *service.LoginService
+	rbacService  *service.RBACService
+	orgService   *service.OrgService
+	mfaService   *service.MFAService
 }
 
 // NewLoginHandler 创建登录控制器实例
-func NewLoginHandler(loginService *service.LoginService) *LoginHandler {
-	return &LoginHandler{loginService: loginService}
+func NewLoginHandler(loginService *service.LoginService, rbacService *service.RBACService, orgService *service.OrgService,
+	mfaService *service.MFAService) *LoginHandler {
+	return &LoginHandler{loginService: loginService, rbacService: rbacService, orgService: orgService, mfaService: mfaService}
 }
 
 // Login 处理用户登录请求
@@ -32,21 +39,120 @@ func (h *LoginHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, token, err := h.loginService.Login(req.Username, req.Password)
+	user, tokens, challenge, err := h.loginService.Login(actorFromRequest(r), req.Username, req.Password, req.OrgID)
 	if err != nil {
 		utils.AuthLogger.Error("登录失败: %v", err)
-		if err.Error() == "ACCOUNT_DISABLED" {
-			utils.ErrorResponse(w, http.StatusForbidden, "账户已被禁用")
-		} else {
-			utils.ErrorResponse(w, http.StatusUnauthorized, "用户名或密码错误")
+		writeLoginError(w, err)
+		return
+	}
+
+	// 需要二次验证：返回 mfa_token，客户端提交验证码至 POST /api/auth/mfa/verify
+	if challenge != nil {
+		utils.SuccessResponse(w, "请输入二次验证码", map[string]interface{}{
+			"mfa_required": true,
+			"mfa_token":    challenge.Token,
+			"expires_in":   challenge.ExpiresIn,
+			"enrollment":   challenge.Enrollment,
+			"username":     user.Username,
+		})
+		return
+	}
+
+	utils.SuccessResponse(w, "登录成功", h.loginData(user, tokens))
+}
+
+// VerifyMFA 使用 mfa_token 与验证码 (TOTP 或恢复码) 完成登录 (RESTful: POST /api/auth/mfa/verify)
+func (h *LoginHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req models.MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	user, tokens, recoveryCodes, err := h.mfaService.Verify(actorFromRequest(r), req.MFAToken, req.Code)
+	var expired *service.PasswordExpiredError
+	if errors.As(err, &expired) {
+		// 强制登记时恢复码仅此一次返回，密码过期也不能丢弃
+		writePasswordExpired(w, expired, recoveryCodes)
+		return
+	}
+	if err != nil {
+		utils.AuthLogger.Error("二次验证失败: %v", err)
+		switch {
+		case errors.Is(err, service.ErrMFATokenInvalid):
+			utils.ErrorResponse(w, http.StatusUnauthorized, "验证已过期，请重新登录")
+		case errors.Is(err, service.ErrMFAInvalidCode):
+			utils.ErrorResponse(w, http.StatusBadRequest, "验证码错误")
+		default:
+			writeLoginError(w, err)
 		}
 		return
 	}
 
-	utils.SuccessResponse(w, "登录成功", map[string]interface{}{
-		"token":    token,
-		"id":       user.ID,
-		"role":     user.Role,
-		"username": user.Username,
-	})
+	data := h.loginData(user, tokens)
+	if recoveryCodes != nil {
+		// 强制登记时首次验证即完成激活，恢复码仅此一次返回
+		data["recovery_codes"] = recoveryCodes
+	}
+	utils.SuccessResponse(w, "登录成功", data)
+}
+
+// loginData 构造登录成功的响应数据
+func (h *LoginHandler) loginData(user *models.User, tokens *models.TokenPair) map[string]interface{} {
+	orgs, err := h.orgService.Memberships(user.ID)
+	if err != nil {
+		utils.AuthLogger.Error("查询用户 %d 的组织失败: %v", user.ID, err)
+	}
+
+	return map[string]interface{}{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"id":            user.ID,
+		"role":          user.Role,
+		"org_id":        user.OrgID,
+		"orgs":          orgs,
+		"username":      user.Username,
+		"email":         user.Email,
+		"permissions":   h.rbacService.Permissions(user.Role),
+	}
+}
+
+// writePasswordExpired 密码已过期：返回 403 及一次性重置令牌，客户端凭 reset_token 调用 POST /api/auth/reset-password 设置新密码后重新登录
+func writePasswordExpired(w http.ResponseWriter, expired *service.PasswordExpiredError, recoveryCodes []string) {
+	data := map[string]interface{}{
+		"password_expired": true,
+		"reset_token":      expired.Token,
+		"expires_in":       expired.ExpiresIn,
+	}
+	if recoveryCodes != nil {
+		data["recovery_codes"] = recoveryCodes
+	}
+	utils.ErrorDetailResponse(w, http.StatusForbidden, "密码已过期，请设置新密码后重新登录", data)
+}
+
+// writeLoginError 将登录失败原因映射为响应
+func writeLoginError(w http.ResponseWriter, err error) {
+	var locked *service.LockedError
+	var expired *service.PasswordExpiredError
+	if errors.As(err, &expired) {
+		writePasswordExpired(w, expired, nil)
+	} else if errors.As(err, &locked) {
+		w.Header().Set("Retry-After", strconv.Itoa(locked.RetryAfter()))
+		if locked.Scope == repository.AttemptScopeIP {
+			utils.ErrorResponse(w, http.StatusTooManyRequests, "登录失败次数过多，请稍后再试")
+		} else {
+			utils.ErrorResponse(w, http.StatusLocked, "登录失败次数过多，账户已临时锁定，请稍后再试")
+		}
+	} else if err.Error() == "ACCOUNT_DISABLED" {
+		utils.ErrorResponse(w, http.StatusForbidden, "账户已被禁用")
+	} else if errors.Is(err, service.ErrAccountPending) {
+		utils.ErrorResponse(w, http.StatusForbidden, "账户尚未验证邮箱，请查收验证邮件")
+	} else if errors.Is(err, service.ErrNoOrganization) {
+		utils.ErrorResponse(w, http.StatusForbidden, "账户未加入任何组织")
+	} else if errors.Is(err, service.ErrNotOrgMember) {
+		utils.ErrorResponse(w, http.StatusForbidden, "您不是该组织的成员")
+	} else {
+		utils.ErrorResponse(w, http.StatusUnauthorized, "用户名或密码错误")
+	}
 }
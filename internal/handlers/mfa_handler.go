@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// MFAHandler 二次验证管理控制器 (登录后的登记、激活、关闭与恢复码)
+type MFAHandler struct {
+	mfaService *service.MFAService
+}
+
+// NewMFAHandler 创建二次验证控制器实例
+func NewMFAHandler(mfaService *service.MFAService) *MFAHandler {
+	return &MFAHandler{mfaService: mfaService}
+}
+
+// Status 查询当前用户的二次验证状态 (RESTful: GET /api/auth/mfa)
+func (h *MFAHandler) Status(w http.ResponseWriter, r *http.Request) {
+	actor := actorFromRequest(r)
+	status, err := h.mfaService.Status(actor.UserID, actor.Role)
+	if err != nil {
+		utils.AuthLogger.Error("查询用户 %d 的二次验证状态失败: %v", actor.UserID, err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+		return
+	}
+	utils.SuccessResponse(w, "查询成功", status)
+}
+
+// Enroll 生成待激活的 TOTP 密钥，返回 otpauth URI 与二维码 (RESTful: POST /api/auth/mfa/enroll)
+func (h *MFAHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	enrollment, err := h.mfaService.Enroll(actorFromRequest(r))
+	if err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "请使用认证器 App 扫描二维码，并提交验证码完成激活", enrollment)
+}
+
+// Activate 提交验证码激活二次验证，返回一次性恢复码 (RESTful: POST /api/auth/mfa/activate)
+func (h *MFAHandler) Activate(w http.ResponseWriter, r *http.Request) {
+	var req models.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	codes, err := h.mfaService.Activate(actorFromRequest(r), req.Code)
+	if err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "二次验证已启用，请妥善保存恢复码", map[string]interface{}{"recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废 (RESTful: POST /api/auth/mfa/recovery-codes)
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req models.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(actorFromRequest(r), req.Code)
+	if err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "恢复码已重新生成，请妥善保存", map[string]interface{}{"recovery_codes": codes})
+}
+
+// Disable 提交验证码关闭自己的二次验证 (RESTful: DELETE /api/auth/mfa)
+func (h *MFAHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	var req models.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := h.mfaService.Disable(actorFromRequest(r), req.Code); err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "二次验证已关闭", nil)
+}
+
+// ResetUser 管理员重置当前组织内用户的二次验证 (RESTful: DELETE /api/users/{id}/mfa，需 users:update 权限)
+func (h *MFAHandler) ResetUser(w http.ResponseWriter, r *http.Request) {
+	targetID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	reset, err := h.mfaService.Reset(actorFromRequest(r), targetID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, "找不到用户")
+			return
+		}
+		h.writeMFAError(w, err)
+		return
+	}
+	if !reset {
+		utils.SuccessResponse(w, "该用户未登记二次验证", nil)
+		return
+	}
+	operatorID, _ := r.Context().Value("userID").(int64)
+	utils.AuthLogger.Info("管理员 %d 已重置用户 %d 的二次验证", operatorID, targetID)
+	utils.SuccessResponse(w, "已重置该用户的二次验证，下次登录需重新登记", nil)
+}
+
+// writeMFAError 将二次验证错误映射为响应
+func (h *MFAHandler) writeMFAError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrAccountLocked):
+		writeLoginError(w, err)
+	case errors.Is(err, service.ErrMFAInvalidCode):
+		utils.ErrorResponse(w, http.StatusBadRequest, "验证码错误")
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		utils.ErrorResponse(w, http.StatusConflict, "二次验证已启用")
+	case errors.Is(err, service.ErrMFANotEnabled):
+		utils.ErrorResponse(w, http.StatusConflict, "尚未启用二次验证")
+	case errors.Is(err, service.ErrMFANotEnrolled):
+		utils.ErrorResponse(w, http.StatusConflict, "请先获取二次验证密钥")
+	case errors.Is(err, service.ErrMFARequiredByRole):
+		utils.ErrorResponse(w, http.StatusForbidden, "当前角色要求启用二次验证，不能关闭")
+	case errors.Is(err, service.ErrMFAResetSelf):
+		utils.ErrorResponse(w, http.StatusForbidden, "关闭自己的二次验证请使用 DELETE /api/auth/mfa 并提交验证码")
+	case errors.Is(err, service.ErrMFAResetForbidden):
+		utils.ErrorResponse(w, http.StatusForbidden, "不能重置角色权限高于自己的用户")
+	default:
+		utils.AuthLogger.Error("二次验证操作失败: %v", err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "操作失败")
+	}
+}
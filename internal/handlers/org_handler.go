@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// OrgHandler 组织 (租户) 管理控制器 (需 orgs:manage 权限，由路由中间件校验)
+type OrgHandler struct {
+	orgService  *service.OrgService
+	userService *service.UserService
+}
+
+// NewOrgHandler 创建组织控制器实例
+func NewOrgHandler(orgService *service.OrgService, userService *service.UserService) *OrgHandler {
+	return &OrgHandler{orgService: orgService, userService: userService}
+}
+
+// ListOrgs 获取全部组织 (RESTful: GET /api/orgs)
+func (h *OrgHandler) ListOrgs(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.orgService.ListOrgs()
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+		return
+	}
+	utils.SuccessResponse(w, "查询成功", map[string]interface{}{"orgs": orgs})
+}
+
+// CreateOrg 创建组织 (RESTful: POST /api/orgs)
+func (h *OrgHandler) CreateOrg(w http.ResponseWriter, r *http.Request) {
+	var req models.OrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	id, err := h.orgService.CreateOrg(actorFromRequest(r), req.Name)
+	if err != nil {
+		h.writeOrgError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "创建成功", map[string]interface{}{"id": id})
+}
+
+// RenameOrg 修改组织名称 (RESTful: PUT /api/orgs/{id})
+func (h *OrgHandler) RenameOrg(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+	var req models.OrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := h.orgService.RenameOrg(actorFromRequest(r), id, req.Name); err != nil {
+		h.writeOrgError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "修改成功", nil)
+}
+
+// DeleteOrg 删除组织 (RESTful: DELETE /api/orgs/{id})
+func (h *OrgHandler) DeleteOrg(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.DeleteOrg(actorFromRequest(r), id); err != nil {
+		h.writeOrgError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "删除成功", nil)
+}
+
+// ListMembers 分页获取组织成员 (RESTful: GET /api/orgs/{id}/members)
+func (h *OrgHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.orgService.GetOrg(id); err != nil {
+		h.writeOrgError(w, err)
+		return
+	}
+
+	f, ok := parseUserListFilter(w, r)
+	if !ok {
+		return
+	}
+	f.OrgID = id
+	users, total, next, err := h.userService.GetAllUsers(f)
+	if err != nil {
+		if !writeUserListError(w, err) {
+			utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+		}
+		return
+	}
+	data := map[string]interface{}{
+		"users":       users,
+		"next_cursor": next,
+	}
+	if !f.UseCursor {
+		data["total"] = total
+	}
+	utils.SuccessResponse(w, "查询成功", data)
+}
+
+// SetMember 将已有用户加入组织或修改其组织内角色 (RESTful: POST /api/orgs/{id}/members)
+func (h *OrgHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+	var req models.OrgMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := h.orgService.SetMember(actorFromRequest(r), id, req); err != nil {
+		h.writeOrgError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "设置成功", nil)
+}
+
+// RemoveMember 将用户移出组织，账号保留 (RESTful: DELETE /api/orgs/{id}/members/{userID})
+func (h *OrgHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseInt(r.PathValue("userID"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	if err := h.orgService.RemoveMember(actorFromRequest(r), id, userID); err != nil {
+		h.writeOrgError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "移除成功", nil)
+}
+
+// orgID 解析路径中的组织ID，失败时直接写入错误响应
+func (h *OrgHandler) orgID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "无效的组织ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// writeOrgError 将组织服务错误映射为 HTTP 响应
+func (h *OrgHandler) writeOrgError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidOrgName):
+		utils.ErrorResponse(w, http.StatusBadRequest, "组织名称不能为空且不超过 100 个字符")
+	case errors.Is(err, service.ErrOrgExists):
+		utils.ErrorResponse(w, http.StatusConflict, "组织名称已存在")
+	case errors.Is(err, repository.ErrOrgNotFound):
+		utils.ErrorResponse(w, http.StatusNotFound, "组织不存在")
+	case errors.Is(err, service.ErrDefaultOrg):
+		utils.ErrorResponse(w, http.StatusForbidden, "默认组织不可删除")
+	case errors.Is(err, service.ErrOrgNotEmpty):
+		utils.ErrorResponse(w, http.StatusConflict, "组织仍有成员，无法删除")
+	case errors.Is(err, repository.ErrUserNotFound):
+		utils.ErrorResponse(w, http.StatusNotFound, "找不到用户")
+	case errors.Is(err, repository.ErrRoleNotFound):
+		utils.ErrorResponse(w, http.StatusBadRequest, "角色不存在")
+	case errors.Is(err, service.ErrNotOrgMember):
+		utils.ErrorResponse(w, http.StatusNotFound, "该用户不是组织成员")
+	default:
+		utils.SystemLogger.Error("组织操作失败: %v", err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "操作失败")
+	}
+}
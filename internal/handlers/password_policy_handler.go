@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"errors"
+	"net/http"
+)
+
+// PasswordPolicyHandler 密码策略控制器
+type PasswordPolicyHandler struct {
+	policy *service.PasswordPolicyService
+}
+
+// NewPasswordPolicyHandler 创建密码策略控制器实例
+func NewPasswordPolicyHandler(policy *service.PasswordPolicyService) *PasswordPolicyHandler {
+	return &PasswordPolicyHandler{policy: policy}
+}
+
+// Get 查询当前密码策略，供注册、改密页面提示与预校验 (RESTful: GET /api/auth/password-policy)
+func (h *PasswordPolicyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	utils.SuccessResponse(w, "查询成功", h.policy.Info())
+}
+
+// writePasswordPolicyError 将密码策略错误映射为 400 响应，data.violations 为逐条违规项
+// 返回: bool 是否已写入响应
+func writePasswordPolicyError(w http.ResponseWriter, err error) bool {
+	var policyErr *service.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	utils.ErrorDetailResponse(w, http.StatusBadRequest, "密码不符合安全策略", map[string]interface{}{
+		"violations": policyErr.Violations,
+	})
+	return true
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// PasswordResetHandler 找回密码控制器
+type PasswordResetHandler struct {
+	resetService *service.PasswordResetService
+}
+
+// NewPasswordResetHandler 创建找回密码控制器实例
+func NewPasswordResetHandler(resetService *service.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{resetService: resetService}
+}
+
+// ForgotPassword 申请重置密码，向账号邮箱发送重置链接 (RESTful: POST /api/auth/forgot-password)
+// 无论账号是否存在均返回相同结果，避免枚举用户名或邮箱
+func (h *PasswordResetHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "用户名或邮箱不能为空")
+		return
+	}
+
+	if err := h.resetService.RequestReset(actorFromRequest(r), req.Username); err != nil {
+		utils.AuthLogger.Error("处理密码重置申请失败: %v", err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	utils.SuccessResponse(w, "如果该账号存在且绑定了已验证的邮箱，重置链接已发送，请查收邮件", nil)
+}
+
+// ResetPassword 凭重置令牌设置新密码 (RESTful: POST /api/auth/reset-password)
+func (h *PasswordResetHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := h.resetService.ResetPassword(actorFromRequest(r), req.Token, req.Password); err != nil {
+		if errors.Is(err, service.ErrResetTokenInvalid) {
+			utils.ErrorResponse(w, http.StatusBadRequest, "重置链接无效或已过期，请重新申请")
+			return
+		}
+		if writePasswordPolicyError(w, err) {
+			return
+		}
+		utils.AuthLogger.Error("重置密码失败: %v", err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "重置密码失败")
+		return
+	}
+	utils.SuccessResponse(w, "密码已重置，请使用新密码登录", nil)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// profileFields PATCH /api/users/me 允许修改的字段白名单
+var profileFields = map[string]bool{"email": true}
+
+// ProfileHandler 个人资料控制器 (当前登录用户查看、修改自己的资料与密码)
+type ProfileHandler struct {
+	profileService *service.ProfileService
+	rbacService    *service.RBACService
+	avatarService  *service.AvatarService
+}
+
+// NewProfileHandler 创建个人资料控制器实例
+func NewProfileHandler(profileService *service.ProfileService, rbacService *service.RBACService, avatarService *service.AvatarService) *ProfileHandler {
+	return &ProfileHandler{profileService: profileService, rbacService: rbacService, avatarService: avatarService}
+}
+
+// Get 查询本人资料 (RESTful: GET /api/users/me)
+func (h *ProfileHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := h.profileService.Get(actorFromRequest(r))
+	if err != nil {
+		h.writeProfileError(w, err)
+		return
+	}
+	h.writeProfile(w, r, "查询成功", user)
+}
+
+// Update 修改本人资料，仅允许白名单内的字段 (RESTful: PATCH /api/users/me)
+func (h *ProfileHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	var rejected []string
+	for field := range raw {
+		if !profileFields[field] {
+			rejected = append(rejected, field)
+		}
+	}
+	if len(rejected) > 0 {
+		sort.Strings(rejected)
+		utils.ErrorDetailResponse(w, http.StatusBadRequest, "不允许修改字段: "+strings.Join(rejected, ", "),
+			map[string]interface{}{"fields": rejected})
+		return
+	}
+
+	var req models.ProfileUpdateRequest
+	if v, ok := raw["email"]; ok {
+		var email string
+		if err := json.Unmarshal(v, &email); err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
+			return
+		}
+		req.Email = &email
+	}
+
+	user, err := h.profileService.UpdateProfile(actorFromRequest(r), req)
+	if err != nil {
+		if !writeEmailError(w, err) {
+			h.writeProfileError(w, err)
+		}
+		return
+	}
+	h.writeProfile(w, r, "修改成功", user)
+}
+
+// ChangePassword 校验当前密码后修改本人密码，成功后需重新登录 (RESTful: POST /api/users/me/password)
+func (h *ProfileHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "当前密码和新密码不能为空")
+		return
+	}
+
+	err := h.profileService.ChangePassword(actorFromRequest(r), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		var locked *service.LockedError
+		switch {
+		case errors.As(err, &locked):
+			writeLoginError(w, err)
+		case errors.Is(err, service.ErrCurrentPasswordInvalid):
+			utils.ErrorResponse(w, http.StatusBadRequest, "当前密码错误")
+		default:
+			if !writePasswordPolicyError(w, err) {
+				h.writeProfileError(w, err)
+			}
+		}
+		return
+	}
+	utils.SuccessResponse(w, "密码已修改，请使用新密码重新登录", nil)
+}
+
+// writeProfile 返回本人资料，附带当前组织内的权限与密码过期标记
+func (h *ProfileHandler) writeProfile(w http.ResponseWriter, r *http.Request, msg string, user *models.User) {
+	setAvatarURLs(r, h.avatarService, user)
+	utils.SuccessResponse(w, msg, map[string]interface{}{
+		"user":             user,
+		"permissions":      h.rbacService.Permissions(user.Role),
+		"password_expired": h.profileService.PasswordExpired(user),
+	})
+}
+
+// writeProfileError 将个人资料通用错误映射为响应
+func (h *ProfileHandler) writeProfileError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrUserNotFound) {
+		utils.ErrorResponse(w, http.StatusNotFound, "找不到用户")
+		return
+	}
+	utils.UserLogger.Error("个人资料操作失败: %v", err)
+	utils.ErrorResponse(w, http.StatusInternalServerError, "操作失败")
+}
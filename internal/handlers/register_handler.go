@@ -27,28 +27,29 @@ func (h *RegisterHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 解析 JSON 请求体
-	var req models.LoginRequest // 复用 LoginRequest 结构，因为字段相同
+	var req models.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, "无效的 JSON 数据")
 		return
 	}
 
-	if req.Username == "" || len(req.Password) != 6 {
-		utils.ErrorResponse(w, http.StatusBadRequest, "格式错误：用户名不能为空且密码必须为6位")
+	if req.Username == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "格式错误：用户名不能为空")
 		return
 	}
 
-	uid, err := h.registerService.Register(req.Username, req.Password)
+	user, err := h.registerService.Register(actorFromRequest(r), req.Username, req.Email, req.Password)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "注册失败：用户名可能已被占用")
+		if !writeUsernameError(w, err) && !writePasswordPolicyError(w, err) && !writeEmailError(w, err) {
+			utils.ErrorResponse(w, http.StatusInternalServerError, "注册失败：用户名可能已被占用")
+		}
 		return
 	}
 
-	// 注册成功后直接生成 token
-	token, _ := utils.GenerateToken(uid, req.Username, "common")
-
-	utils.SuccessResponse(w, "注册成功", map[string]interface{}{
-		"user_id": uid,
-		"token":   token,
+	// 账号需验证邮箱后才能登录，因此注册后不再直接签发 token
+	utils.SuccessResponse(w, "注册成功，请查收验证邮件完成激活", map[string]interface{}{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"status":  user.Status,
 	})
 }
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// RoleHandler 角色与权限管理控制器 (需 roles:manage 权限，由路由中间件校验)
+type RoleHandler struct {
+	rbacService *service.RBACService
+}
+
+// NewRoleHandler 创建角色控制器实例
+func NewRoleHandler(rbacService *service.RBACService) *RoleHandler {
+	return &RoleHandler{rbacService: rbacService}
+}
+
+// ListRoles 获取全部角色及其权限 (RESTful: GET /api/roles)
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.rbacService.ListRoles()
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+		return
+	}
+	utils.SuccessResponse(w, "查询成功", map[string]interface{}{"roles": roles})
+}
+
+// ListPermissions 获取全部可授予的权限 (RESTful: GET /api/permissions)
+func (h *RoleHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	perms, err := h.rbacService.ListPermissions()
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+		return
+	}
+	utils.SuccessResponse(w, "查询成功", map[string]interface{}{"permissions": perms})
+}
+
+// CreateRole 创建自定义角色 (RESTful: POST /api/roles)
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	id, err := h.rbacService.CreateRole(actorFromRequest(r), req)
+	if err != nil {
+		h.writeRoleError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "创建成功", map[string]interface{}{"id": id})
+}
+
+// UpdateRole 修改角色描述与权限 (RESTful: PUT /api/roles/{name})
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := h.rbacService.UpdateRole(actorFromRequest(r), name, req); err != nil {
+		h.writeRoleError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "修改成功", nil)
+}
+
+// DeleteRole 删除自定义角色 (RESTful: DELETE /api/roles/{name})
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := h.rbacService.DeleteRole(actorFromRequest(r), name); err != nil {
+		h.writeRoleError(w, err)
+		return
+	}
+	utils.SuccessResponse(w, "删除成功", nil)
+}
+
+// writeRoleError 将角色服务错误映射为 HTTP 响应
+func (h *RoleHandler) writeRoleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRoleName):
+		utils.ErrorResponse(w, http.StatusBadRequest, "角色名只能包含小写字母、数字、下划线和连字符，且以字母开头")
+	case errors.Is(err, repository.ErrPermissionNotFound):
+		utils.ErrorResponse(w, http.StatusBadRequest, "包含不存在的权限")
+	case errors.Is(err, service.ErrRoleExists):
+		utils.ErrorResponse(w, http.StatusConflict, "角色已存在")
+	case errors.Is(err, repository.ErrRoleNotFound):
+		utils.ErrorResponse(w, http.StatusNotFound, "角色不存在")
+	case errors.Is(err, service.ErrSystemRole):
+		utils.ErrorResponse(w, http.StatusForbidden, "内置角色不可删除，其权限不可修改")
+	case errors.Is(err, service.ErrRoleEscalation):
+		utils.ErrorResponse(w, http.StatusForbidden, "只能管理权限不超出自身权限的角色")
+	case errors.Is(err, service.ErrRoleInUse):
+		utils.ErrorResponse(w, http.StatusConflict, "仍有用户使用该角色，无法删除")
+	default:
+		utils.SystemLogger.Error("角色操作失败: %v", err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "操作失败")
+	}
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// tusVersion 支持的 tus 协议版本
+	tusVersion = "1.0.0"
+	// tusExtensions 支持的 tus 扩展
+	tusExtensions = "creation,expiration,termination"
+	// tusContentType PATCH 请求体的内容类型
+	tusContentType = "application/offset+octet-stream"
+)
+
+// TusHandler 可续传上传控制器 (tus 1.0，https://tus.io/protocols/resumable-upload)
+type TusHandler struct {
+	tusService *service.TusService
+}
+
+// NewTusHandler 创建可续传上传控制器实例
+func NewTusHandler(tusService *service.TusService) *TusHandler {
+	return &TusHandler{tusService: tusService}
+}
+
+// Options 返回服务端支持的协议版本、扩展及大小上限 (RESTful: OPTIONS /api/uploads/)，同时作为跨域预检响应，无需登录
+func (h *TusHandler) Options(w http.ResponseWriter, r *http.Request) {
+	setTusHeaders(w)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.tusService.MaxSize(), 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Create 创建上传 (RESTful: POST /api/uploads/)
+// 请求头: Upload-Length 文件总字节数 (必填，不支持 Upload-Defer-Length), Upload-Metadata 可选的元数据
+// 成功返回 201，Location 为后续 HEAD/PATCH/DELETE 的地址
+func (h *TusHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		tusError(w, http.StatusBadRequest, "缺少或无效的 Upload-Length")
+		return
+	}
+
+	u, err := h.tusService.Create(r.Context(), actorFromRequest(r), length, r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTusTooLarge):
+			tusError(w, http.StatusRequestEntityTooLarge, "文件超过大小上限")
+		case errors.Is(err, repository.ErrTusQuotaExceeded):
+			tusError(w, http.StatusRequestEntityTooLarge, "上传空间不足")
+		case errors.Is(err, service.ErrTusInvalidMetadata):
+			tusError(w, http.StatusBadRequest, "无效的 Upload-Metadata")
+		default:
+			utils.UserLogger.Error("创建上传失败: %v", err)
+			tusError(w, http.StatusInternalServerError, "创建上传失败")
+		}
+		return
+	}
+
+	setTusHeaders(w)
+	w.Header().Set("Location", "/api/uploads/"+u.ID)
+	setTusUploadHeaders(w, u)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Head 查询上传进度 (RESTful: HEAD /api/uploads/{id})，客户端据此从 Upload-Offset 处继续上传
+func (h *TusHandler) Head(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	u, err := h.tusService.Get(actorFromRequest(r), r.PathValue("id"))
+	if err != nil {
+		tusUploadError(w, err)
+		return
+	}
+
+	setTusHeaders(w)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	if u.Metadata != "" {
+		w.Header().Set("Upload-Metadata", u.Metadata)
+	}
+	setTusUploadHeaders(w, u)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Patch 从 Upload-Offset 处追加数据 (RESTful: PATCH /api/uploads/{id})
+// 连接中断时已收到的数据同样保存，客户端通过 HEAD 查询新的偏移量后继续
+func (h *TusHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != tusContentType {
+		tusError(w, http.StatusUnsupportedMediaType, "Content-Type 必须为 "+tusContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		tusError(w, http.StatusBadRequest, "缺少或无效的 Upload-Offset")
+		return
+	}
+
+	u, err := h.tusService.Write(r.Context(), actorFromRequest(r), r.PathValue("id"), offset, r.Body)
+	if err != nil && u == nil {
+		tusUploadError(w, err)
+		return
+	}
+	if err != nil {
+		// 读取请求体中断：已收到的数据已保存，客户端多半已断开，仅记录日志
+		utils.UserLogger.Info("上传 %s 在偏移量 %d 处中断: %v", u.ID, u.Offset, err)
+	}
+
+	setTusHeaders(w)
+	setTusUploadHeaders(w, u)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Delete 终止并删除上传 (RESTful: DELETE /api/uploads/{id})
+func (h *TusHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	if err := h.tusService.Delete(r.Context(), actorFromRequest(r), r.PathValue("id")); err != nil {
+		tusUploadError(w, err)
+		return
+	}
+	setTusHeaders(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkTusResumable 校验 Tus-Resumable 请求头，版本不支持时返回 412
+func checkTusResumable(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Tus-Resumable") == tusVersion {
+		return true
+	}
+	w.Header().Set("Tus-Version", tusVersion)
+	tusError(w, http.StatusPreconditionFailed, "不支持的 Tus-Resumable 版本")
+	return false
+}
+
+// setTusHeaders 设置 tus 通用响应头及跨域头部 (允许 tus 请求头并向浏览器暴露 tus 响应头)
+func setTusHeaders(w http.ResponseWriter) {
+	h := w.Header()
+	h.Set("Tus-Resumable", tusVersion)
+	h.Set("Access-Control-Allow-Origin", "*")
+	h.Set("Access-Control-Allow-Methods", "POST, HEAD, PATCH, DELETE, OPTIONS")
+	h.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata")
+	h.Set("Access-Control-Expose-Headers", "New-Token, Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Offset, Upload-Length, Upload-Expires, Upload-Metadata")
+}
+
+// setTusUploadHeaders 设置上传的偏移量及过期时间 (未完成的上传才有过期时间)
+func setTusUploadHeaders(w http.ResponseWriter, u *models.TusUpload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	if !u.Completed() {
+		w.Header().Set("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// tusUploadError 将查询/写入/删除上传的错误映射为响应
+// 他人的上传与不存在的上传同样返回 404，不暴露上传是否存在
+func tusUploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrTusUploadNotFound):
+		tusError(w, http.StatusNotFound, "上传不存在")
+	case errors.Is(err, service.ErrTusExpired):
+		tusError(w, http.StatusGone, "上传已过期")
+	case errors.Is(err, service.ErrTusOffsetMismatch):
+		tusError(w, http.StatusConflict, "Upload-Offset 与已上传的字节数不一致")
+	case errors.Is(err, service.ErrTusTooLarge):
+		tusError(w, http.StatusRequestEntityTooLarge, "数据超出 Upload-Length")
+	default:
+		utils.UserLogger.Error("处理上传失败: %v", err)
+		tusError(w, http.StatusInternalServerError, "处理上传失败")
+	}
+}
+
+// tusError 返回 JSON 错误响应 (与 utils.ErrorResponse 相同的格式，但保留 tus 跨域头部)
+func tusError(w http.ResponseWriter, code int, message string) {
+	setTusHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(models.APIResponse{
+		Success: false,
+		Code:    code,
+		Message: message,
+	})
+}
@@ -1,29 +1,34 @@
 package handlers
 
 import (
+	"GoWork_7/internal/models"
 	"GoWork_7/internal/service"
 	"GoWork_7/internal/utils"
-	"fmt"
+	"errors"
+	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
-	"time"
 )
 
+// maxAvatarSize 上传头像原图的大小上限
+const maxAvatarSize = 5 << 20
+
 // UploadHandler 专门处理文件上传的控制器
 type UploadHandler struct {
-	userService *service.UserService
+	userService   *service.UserService
+	rbacService   *service.RBACService
+	orgService    *service.OrgService
+	avatarService *service.AvatarService
 }
 
 // NewUploadHandler 创建上传控制器实例
-func NewUploadHandler(userService *service.UserService) *UploadHandler {
-	return &UploadHandler{userService: userService}
+func NewUploadHandler(userService *service.UserService, rbacService *service.RBACService, orgService *service.OrgService, avatarService *service.AvatarService) *UploadHandler {
+	return &UploadHandler{userService: userService, rbacService: rbacService, orgService: orgService, avatarService: avatarService}
 }
 
 // UploadAvatar 处理头像上传 (RESTful: POST /api/users/{id}/avatar 或 POST /api/uploads/avatar)
-// 实现逻辑：通过 ID 查询数据库获取用户名，生成 ID_用户名.扩展名 格式的文件名
+// 实现逻辑：按文件内容校验并完整解码图片，重新编码为 JPEG 并生成各尺寸缩略图，按内容哈希命名保存
+// 通用上传的头像登记为临时上传，须在有效期内由同一操作者新建用户时认领，否则由清理任务删除
 func (h *UploadHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	// 1. 检查请求方法
 	if r.Method != http.MethodPost {
@@ -54,89 +59,100 @@ func (h *UploadHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	operatorRole, _ := r.Context().Value("role").(string)
+	orgID, _ := r.Context().Value("orgID").(int64)
 
-	// 4. 权限校验
+	// 4. 权限校验 (与 PUT /api/users/{id} 相同)
 	if !isGeneric {
-		// 特定用户上传：只能上传自己的头像，除非是管理员
-		if operatorID != targetID && operatorRole != "admin" {
-			utils.ErrorResponse(w, http.StatusForbidden, "无权修改他人头像")
+		// 目标用户必须属于当前组织
+		targetUser, err := h.userService.GetUserByID(orgID, targetID)
+		if err != nil {
+			utils.ErrorResponse(w, http.StatusNotFound, "找不到用户")
 			return
 		}
+		// 特定用户上传：只能上传自己的头像，除非拥有 users:update 权限，且不能修改同样拥有管理权限的用户
+		if operatorID != targetID {
+			if !h.rbacService.HasPermission(operatorRole, service.PermUsersUpdate) {
+				utils.ErrorResponse(w, http.StatusForbidden, "无权修改他人头像")
+				return
+			}
+			if h.rbacService.HasPermission(targetUser.Role, service.PermUsersUpdate) {
+				utils.ErrorResponse(w, http.StatusForbidden, "禁止修改其他管理员")
+				return
+			}
+			// 账号信息在组织间共享：同时属于其它组织的用户只能由平台管理员修改
+			if !h.rbacService.HasPermission(operatorRole, service.PermOrgsManage) {
+				shared, err := h.orgService.IsShared(targetID)
+				if err != nil {
+					utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+					return
+				}
+				if shared {
+					utils.ErrorResponse(w, http.StatusForbidden, "该用户同时属于其它组织，仅平台管理员可修改")
+					return
+				}
+			}
+		}
 	}
 
-	// 5. 解析表单 (2MB 限制)
+	// 5. 解析表单 (原图不超过 5MB)
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarSize+1<<20)
 	if err := r.ParseMultipartForm(2 * 1024 * 1024); err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, "File too large")
 		return
 	}
 
 	// 6. 获取文件
-	file, fileHeader, err := r.FormFile("avatar")
+	file, _, err := r.FormFile("avatar")
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, "No file uploaded")
 		return
 	}
 	defer file.Close()
 
-	// 7. 验证文件类型
-	contentType := fileHeader.Header.Get("Content-Type")
-	if !h.isAllowedType(contentType) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid file type")
+	data, err := io.ReadAll(io.LimitReader(file, maxAvatarSize+1))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Failed to read file")
 		return
 	}
-
-	// 8. 创建上传目录
-	uploadDir := filepath.Join("view", "images")
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to create upload directory")
+	if len(data) > maxAvatarSize {
+		utils.ErrorResponse(w, http.StatusBadRequest, "File too large")
 		return
 	}
 
-	// 9. 生成文件名 (核心逻辑：ID + 用户名)
-	ext := filepath.Ext(fileHeader.Filename)
+	// 7. 校验图片内容 (不信任客户端声明的 Content-Type)，重新编码并写入各尺寸
 	var fileName string
 	if isGeneric {
-		// 通用上传：使用时间戳+随机数
-		fileName = fmt.Sprintf("temp_%d%s", time.Now().UnixNano(), ext)
+		fileName, err = h.avatarService.SaveTemp(r.Context(), actorFromRequest(r), data)
 	} else {
-		// 特定用户上传：通过 ID 查询数据库获取用户名
-		targetUser, err := h.userService.GetUserByID(targetID)
-		username := "user"
-		if err == nil && targetUser.Username != "" {
-			// 处理用户名，替换空格为下划线
-			username = strings.ReplaceAll(targetUser.Username, " ", "_")
-		}
-		// 格式：ID_用户名.扩展名
-		fileName = fmt.Sprintf("%d_%s%s", targetID, username, ext)
+		fileName, err = h.avatarService.Save(r.Context(), data)
 	}
-	filePath := filepath.Join(uploadDir, fileName)
-
-	// 10. 保存文件 (覆盖旧文件)
-	dst, err := os.Create(filePath)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to save file")
+		switch {
+		case errors.Is(err, service.ErrAvatarInvalid):
+			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid file type")
+		case errors.Is(err, service.ErrAvatarTooLarge):
+			utils.ErrorResponse(w, http.StatusBadRequest, "Image dimensions too large")
+		default:
+			utils.UserLogger.Error("保存头像失败: %v", err)
+			utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to save file")
+		}
 		return
 	}
-	defer dst.Close()
 
-	if _, err := dst.ReadFrom(file); err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to save file")
-		return
+	// 8. 特定用户上传：更新用户头像 (同时写入审计日志；旧头像由清理任务删除)
+	if !isGeneric {
+		if err := h.userService.UpdateAvatar(actorFromRequest(r), targetID, fileName); err != nil {
+			utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to update avatar")
+			return
+		}
 	}
 
-	// 11. 返回文件名
+	// 9. 返回文件名及各尺寸地址 (由存储后端生成)
+	uploaded := models.User{Avatar: fileName}
+	setAvatarURLs(r, h.avatarService, &uploaded)
 	utils.SuccessResponse(w, "Upload success", map[string]interface{}{
-		"path": fileName,
+		"path":       fileName,
+		"url":        uploaded.Avatar,
+		"thumbnails": uploaded.AvatarThumbnails,
 	})
 }
-
-// isAllowedType 检查文件类型是否允许
-func (h *UploadHandler) isAllowedType(contentType string) bool {
-	allowedTypes := []string{"image/jpeg", "image/png", "image/gif"}
-	for _, allowedType := range allowedTypes {
-		if contentType == allowedType {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// UserBulkHandler 批量操作用户控制器
+type UserBulkHandler struct {
+	bulkService *service.UserBulkService
+}
+
+// NewUserBulkHandler 创建批量操作用户控制器实例
+func NewUserBulkHandler(bulkService *service.UserBulkService) *UserBulkHandler {
+	return &UserBulkHandler{bulkService: bulkService}
+}
+
+// Bulk 批量启用、禁用、删除、设置角色或重置密码 (RESTful: POST /api/users/bulk)
+// 操作对象为 ids 或 filter 二选一；delete 需 users:delete 权限，其余操作需 users:update 权限，在 Service 中校验
+func (h *UserBulkHandler) Bulk(w http.ResponseWriter, r *http.Request) {
+	var req models.UserBulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	report, err := h.bulkService.Apply(actorFromRequest(r), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrBulkInvalidAction):
+			utils.ErrorResponse(w, http.StatusBadRequest, "action 只能为 enable、disable、delete、set-role 或 reset-password")
+		case errors.Is(err, service.ErrBulkNoTarget):
+			utils.ErrorResponse(w, http.StatusBadRequest, "请指定 ids 或 filter 其中之一")
+		case errors.Is(err, service.ErrBulkTooManyUsers):
+			utils.ErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("单次最多操作 %d 个用户", service.UserBulkMaxUsers))
+		case errors.Is(err, service.ErrBulkForbidden):
+			utils.ErrorResponse(w, http.StatusForbidden, "权限不足")
+		case errors.Is(err, service.ErrBulkRoleNotFound):
+			utils.ErrorResponse(w, http.StatusBadRequest, "角色不存在")
+		case errors.Is(err, service.ErrBulkRoleForbidden):
+			utils.ErrorResponse(w, http.StatusForbidden, "不能授予超出自身权限的角色")
+		default:
+			utils.UserLogger.Error("批量操作用户失败: %v", err)
+			utils.ErrorResponse(w, http.StatusInternalServerError, "批量操作失败")
+		}
+		return
+	}
+
+	utils.SuccessResponse(w, fmt.Sprintf("批量操作完成：成功 %d 个，失败 %d 个", report.Succeeded, report.Failed), report)
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// exportColumns 导出文件的列 (不含密码等敏感字段)
+var exportColumns = []string{"id", "username", "email", "email_verified", "role", "status", "last_login"}
+
+// UserExportHandler 导出用户列表控制器
+type UserExportHandler struct {
+	userService *service.UserService
+}
+
+// NewUserExportHandler 创建导出用户列表控制器实例
+func NewUserExportHandler(userService *service.UserService) *UserExportHandler {
+	return &UserExportHandler{userService: userService}
+}
+
+// Export 导出当前组织内符合筛选条件的全部用户 (RESTful: GET /api/users/export，需 users:export 权限)
+// 参数 format 为 csv (默认) / xlsx / json，筛选与排序参数与用户列表相同；不分页，数据从数据库游标逐行写出
+func (h *UserExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	exporter := newUserExporter(format, w)
+	if exporter == nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "format 只能为 csv、xlsx 或 json")
+		return
+	}
+	f, ok := parseUserListFilter(w, r)
+	if !ok {
+		return
+	}
+
+	// 首行数据到达时才写响应头，查询失败时仍可返回错误响应
+	started := false
+	begin := func() error {
+		started = true
+		filename := fmt.Sprintf("users_%s.%s", time.Now().Format("20060102_150405"), format)
+		w.Header().Set("Content-Type", exporter.contentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("Cache-Control", "no-store")
+		return exporter.begin()
+	}
+
+	_, err := h.userService.ExportUsers(actorFromRequest(r), format, f, func(u *models.User) error {
+		if !started {
+			if err := begin(); err != nil {
+				return err
+			}
+		}
+		return exporter.write(u)
+	})
+	if err == nil && !started {
+		err = begin()
+	}
+	if err == nil {
+		err = exporter.close()
+	}
+	if err != nil {
+		if !started {
+			utils.UserLogger.Error("导出用户失败: %v", err)
+			utils.ErrorResponse(w, http.StatusInternalServerError, "导出失败")
+			return
+		}
+		// 响应已开始写出，中断连接使客户端得知文件不完整
+		utils.UserLogger.Error("导出用户中断: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// userExporter 按格式逐行写出用户
+type userExporter interface {
+	contentType() string
+	begin() error
+	write(u *models.User) error
+	close() error
+}
+
+// newUserExporter 创建指定格式的导出器，格式不支持时返回 nil
+func newUserExporter(format string, w io.Writer) userExporter {
+	switch format {
+	case "csv":
+		return &csvUserExporter{w: w}
+	case "xlsx":
+		return &xlsxUserExporter{w: w}
+	case "json":
+		return &jsonUserExporter{w: w}
+	}
+	return nil
+}
+
+// exportRow 将用户转换为导出行 (与 exportColumns 对应)
+func exportRow(u *models.User) []string {
+	return []string{
+		strconv.FormatInt(u.ID, 10),
+		u.Username,
+		u.Email,
+		strconv.FormatBool(u.EmailVerified),
+		u.Role,
+		u.Status,
+		u.LastLogin,
+	}
+}
+
+// csvUserExporter CSV 导出 (带 UTF-8 BOM，便于 Excel 直接打开)
+type csvUserExporter struct {
+	w  io.Writer
+	cw *csv.Writer
+}
+
+func (e *csvUserExporter) contentType() string { return "text/csv; charset=utf-8" }
+
+func (e *csvUserExporter) begin() error {
+	if _, err := io.WriteString(e.w, "\ufeff"); err != nil {
+		return err
+	}
+	e.cw = csv.NewWriter(e.w)
+	return e.cw.Write(exportColumns)
+}
+
+func (e *csvUserExporter) write(u *models.User) error {
+	row := exportRow(u)
+	for i := range row {
+		row[i] = escapeCSVFormula(row[i])
+	}
+	return e.cw.Write(row)
+}
+
+func (e *csvUserExporter) close() error {
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+// escapeCSVFormula 以 = + - @ 等开头的单元格前加单引号，防止在电子表格中被当作公式执行
+func escapeCSVFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// xlsxUserExporter XLSX 导出 (流式写入工作表，超出内存阈值的行暂存于临时文件)
+type xlsxUserExporter struct {
+	w    io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	row  int
+}
+
+func (e *xlsxUserExporter) contentType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func (e *xlsxUserExporter) begin() error {
+	e.file = excelize.NewFile()
+	sw, err := e.file.NewStreamWriter(e.file.GetSheetName(0))
+	if err != nil {
+		return err
+	}
+	e.sw = sw
+	header := make([]interface{}, len(exportColumns))
+	for i, c := range exportColumns {
+		header[i] = c
+	}
+	return e.writeRow(header)
+}
+
+func (e *xlsxUserExporter) write(u *models.User) error {
+	row := exportRow(u)
+	cells := make([]interface{}, len(row))
+	for i, v := range row {
+		cells[i] = v
+	}
+	cells[0] = u.ID
+	cells[3] = u.EmailVerified
+	return e.writeRow(cells)
+}
+
+func (e *xlsxUserExporter) writeRow(cells []interface{}) error {
+	e.row++
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	return e.sw.SetRow(cell, cells)
+}
+
+func (e *xlsxUserExporter) close() error {
+	if e.file == nil {
+		return nil
+	}
+	defer e.file.Close()
+	if err := e.sw.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
+
+// jsonUserExporter JSON 导出，输出用户对象数组
+type jsonUserExporter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func (e *jsonUserExporter) contentType() string { return "application/json; charset=utf-8" }
+
+func (e *jsonUserExporter) begin() error {
+	e.enc = json.NewEncoder(e.w)
+	_, err := io.WriteString(e.w, "[\n")
+	return err
+}
+
+func (e *jsonUserExporter) write(u *models.User) error {
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	return e.enc.Encode(map[string]interface{}{
+		"id":             u.ID,
+		"username":       u.Username,
+		"email":          u.Email,
+		"email_verified": u.EmailVerified,
+		"role":           u.Role,
+		"status":         u.Status,
+		"last_login":     u.LastLogin,
+	})
+}
+
+func (e *jsonUserExporter) close() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
@@ -2,102 +2,180 @@ package handlers
 
 import (
 	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
 	"GoWork_7/internal/service"
 	"GoWork_7/internal/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // UserHandler 用户模块控制器
 type UserHandler struct {
-	userService *service.UserService
+	userService        *service.UserService
+	rbacService        *service.RBACService
+	orgService         *service.OrgService
+	avatarService      *service.AvatarService
+	trashRetentionDays int
 }
 
 // NewUserHandler 创建用户控制器实例
-func NewUserHandler(userService *service.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+// 参数: trashRetentionDays 回收站保留天数 (0 表示永久保留)，用于在回收站列表中提示
+func NewUserHandler(userService *service.UserService, rbacService *service.RBACService, orgService *service.OrgService,
+	avatarService *service.AvatarService, trashRetentionDays int) *UserHandler {
+	return &UserHandler{userService: userService, rbacService: rbacService, orgService: orgService, avatarService: avatarService,
+		trashRetentionDays: trashRetentionDays}
 }
 
-// GetAllUsers 获取所有用户列表（分页+搜索）
+// GetAllUsers 获取当前组织的用户列表（分页+筛选+排序）
+// 查询参数见 parseUserListFilter；传入 cursor 参数 (第一页为空值) 时改用游标分页，响应不含 total
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		utils.ErrorResponse(w, http.StatusMethodNotAllowed, "Method Not Allowed")
 		return
 	}
 
-	// 解析分页和筛选参数
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	keyword := r.URL.Query().Get("keyword")
-	status := r.URL.Query().Get("status")
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 10
+	f, ok := parseUserListFilter(w, r)
+	if !ok {
+		return
 	}
-
-	users, total, err := h.userService.GetAllUsers(page, limit, keyword, status)
+	f.OrgID, _ = r.Context().Value("orgID").(int64)
+	users, total, next, err := h.userService.GetAllUsers(f)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+		if !writeUserListError(w, err) {
+			utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+		}
 		return
 	}
 
 	// 处理头像 URL 拼接
-	protocol := "http"
-	if r.TLS != nil {
-		protocol = "https"
+	for i := range users {
+		setAvatarURLs(r, h.avatarService, &users[i])
 	}
-	host := r.Host
-	if host == "" {
-		host = "localhost:8090"
+
+	data := map[string]interface{}{
+		"users":       users,
+		"next_cursor": next,
+	}
+	if !f.UseCursor {
+		data["total"] = total
 	}
+	utils.SuccessResponse(w, "查询成功", data)
+}
 
-	for i := range users {
-		if users[i].Avatar != "" {
-			users[i].Avatar = fmt.Sprintf("%s://%s/images/%s", protocol, host, users[i].Avatar)
-		}
+// parseUserListFilter 解析用户列表的筛选、排序与分页参数，参数不合法时直接写入错误响应
+// 查询参数: keyword, status, role, last_login_from, last_login_to, created_from, created_to (RFC3339 或 2006-01-02，to 不含),
+// sort (id / username / last_login / created_at / role), order (asc / desc), page, limit, cursor
+// 返回: models.UserListFilter 查询条件 (OrgID 由调用方填写), bool 是否解析成功
+func parseUserListFilter(w http.ResponseWriter, r *http.Request) (models.UserListFilter, bool) {
+	q := r.URL.Query()
+	f := models.UserListFilter{
+		Keyword: q.Get("keyword"),
+		Status:  q.Get("status"),
+		Role:    q.Get("role"),
+		Sort:    q.Get("sort"),
+		Cursor:  q.Get("cursor"),
+	}
+	f.UseCursor = q.Has("cursor")
+	f.Page, _ = strconv.Atoi(q.Get("page"))
+	f.Limit, _ = strconv.Atoi(q.Get("limit"))
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit < 1 {
+		f.Limit = 10
 	}
 
-	utils.SuccessResponse(w, "查询成功", map[string]interface{}{
-		"users": users,
-		"total": total,
-	})
+	if !repository.ValidUserSort(f.Sort) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "sort 只能为 id、username、last_login、created_at 或 role")
+		return f, false
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		f.Desc = true
+	default:
+		utils.ErrorResponse(w, http.StatusBadRequest, "order 只能为 asc 或 desc")
+		return f, false
+	}
+
+	times := []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"last_login_from", &f.LastLoginFrom},
+		{"last_login_to", &f.LastLoginTo},
+		{"created_from", &f.CreatedFrom},
+		{"created_to", &f.CreatedTo},
+	}
+	for _, t := range times {
+		v, err := parseTimeParam(q.Get(t.name))
+		if err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "无效的 "+t.name+" 时间")
+			return f, false
+		}
+		*t.dst = v
+	}
+	return f, true
 }
 
-// NewUser 创建新用户（仅管理员）
-func (h *UserHandler) NewUser(w http.ResponseWriter, r *http.Request) {
-	role, _ := r.Context().Value("role").(string)
-	if role != "admin" {
-		utils.ErrorResponse(w, http.StatusForbidden, "权限不足")
-		return
+// writeUserListError 将用户列表查询条件错误映射为响应
+// 返回: bool 是否已写入响应
+func writeUserListError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, repository.ErrInvalidUserSort):
+		utils.ErrorResponse(w, http.StatusBadRequest, "sort 只能为 id、username、last_login、created_at 或 role")
+	case errors.Is(err, repository.ErrInvalidCursor):
+		utils.ErrorResponse(w, http.StatusBadRequest, "无效的 cursor，请从第一页重新查询")
+	default:
+		return false
 	}
+	return true
+}
 
+// NewUser 在当前组织内创建新用户（需 users:create 权限，由路由中间件校验）
+func (h *UserHandler) NewUser(w http.ResponseWriter, r *http.Request) {
 	var data struct {
 		Username string `json:"username"`
+		Email    string `json:"email"`
 		Password string `json:"password"`
+		Role     string `json:"role"`
+		Avatar   string `json:"avatar"` // POST /api/uploads/avatar 返回的 path
 	}
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
-	lastID, err := h.userService.CreateUser(data.Username, data.Password)
+	operatorRole, _ := r.Context().Value("role").(string)
+	if data.Role != "" && !h.assignableRole(w, operatorRole, data.Role) {
+		return
+	}
+
+	lastID, err := h.userService.CreateUser(actorFromRequest(r), data.Username, data.Email, data.Password, data.Role, data.Avatar)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "插入数据库失败")
+		if errors.Is(err, repository.ErrAvatarUploadNotFound) {
+			utils.ErrorResponse(w, http.StatusBadRequest, "头像已失效，请重新上传")
+			return
+		}
+		if !writeUsernameError(w, err) && !writePasswordPolicyError(w, err) && !writeEmailError(w, err) {
+			utils.ErrorResponse(w, http.StatusInternalServerError, "插入数据库失败")
+		}
 		return
 	}
 
 	utils.SuccessResponse(w, "新建成功", map[string]interface{}{"id": lastID})
 }
 
-// PutUser 修改用户信息 (RESTful: PUT /api/users/{id})
+// PutUser 修改当前组织内的用户信息 (RESTful: PUT /api/users/{id})
 func (h *UserHandler) PutUser(w http.ResponseWriter, r *http.Request) {
 	operatorRole, _ := r.Context().Value("role").(string)
 	operatorID, _ := r.Context().Value("userID").(int64)
+	orgID, _ := r.Context().Value("orgID").(int64)
 
 	// 从 URL 路径中获取 ID
 	idStr := r.PathValue("id")
@@ -107,48 +185,73 @@ func (h *UserHandler) PutUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var u models.User
+	targetUser, err := h.userService.GetUserByID(orgID, targetID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, "找不到用户")
+		return
+	}
+
+	// 请求中未提供 email 时保留原邮箱 (显式传空字符串才解除绑定)
+	u := models.User{Email: targetUser.Email}
 	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, "无效的请求参数")
 		return
 	}
 	u.ID = targetID // 强制使用 URL 中的 ID
 
-	targetUser, err := h.userService.GetUserByID(u.ID)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusNotFound, "找不到用户")
+	// 权限检查逻辑：修改他人需 users:update 权限，且不能修改同样拥有管理权限的用户
+	if operatorID != u.ID {
+		if !h.rbacService.HasPermission(operatorRole, service.PermUsersUpdate) {
+			utils.ErrorResponse(w, http.StatusForbidden, "无权修改他人信息")
+			return
+		}
+		if h.rbacService.HasPermission(targetUser.Role, service.PermUsersUpdate) {
+			utils.ErrorResponse(w, http.StatusForbidden, "禁止修改其他管理员")
+			return
+		}
+		// 账号信息在组织间共享：同时属于其它组织的用户只能由平台管理员修改
+		if !h.rbacService.HasPermission(operatorRole, service.PermOrgsManage) {
+			shared, err := h.orgService.IsShared(u.ID)
+			if err != nil {
+				utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+				return
+			}
+			if shared {
+				utils.ErrorResponse(w, http.StatusForbidden, "该用户同时属于其它组织，仅平台管理员可修改")
+				return
+			}
+		}
+	}
+
+	// 角色和状态只能由管理员修改；普通用户修改自己的资料请使用 PATCH /api/users/me
+	if (u.Role != targetUser.Role || u.Enable != targetUser.Enable) && !h.rbacService.HasPermission(operatorRole, service.PermUsersUpdate) {
+		utils.ErrorResponse(w, http.StatusForbidden, "仅管理员可修改角色和状态")
+		return
+	}
+	if u.Role != targetUser.Role && !h.assignableRole(w, operatorRole, u.Role) {
 		return
 	}
 
-	// 权限检查逻辑
-	if operatorRole == "admin" {
-		if targetUser.Role == "admin" && operatorID != u.ID {
-			utils.ErrorResponse(w, http.StatusForbidden, "禁止修改其他管理员")
+	if err := h.userService.UpdateUser(actorFromRequest(r), &u); err != nil {
+		if errors.Is(err, service.ErrSelfPasswordChange) {
+			utils.ErrorResponse(w, http.StatusBadRequest, "修改自己的密码请使用 POST /api/users/me/password")
 			return
 		}
-	} else {
-		if operatorID != u.ID {
-			utils.ErrorResponse(w, http.StatusForbidden, "无权修改他人信息")
+		if errors.Is(err, repository.ErrUserExists) {
+			utils.ErrorResponse(w, http.StatusConflict, "用户名或邮箱已被占用")
 			return
 		}
-	}
-
-	if err := h.userService.UpdateUser(&u); err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "修改失败")
+		if !writeUsernameError(w, err) && !writePasswordPolicyError(w, err) && !writeEmailError(w, err) {
+			utils.ErrorResponse(w, http.StatusInternalServerError, "修改失败")
+		}
 		return
 	}
 
 	utils.SuccessResponse(w, "修改成功", u)
 }
 
-// DeleteUser 删除用户 (RESTful: DELETE /api/users/{id})
+// DeleteUser 将用户移出当前组织，不再属于任何组织时移入回收站 (RESTful: DELETE /api/users/{id}，需 users:delete 权限)
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	role, _ := r.Context().Value("role").(string)
-	if role != "admin" {
-		utils.ErrorResponse(w, http.StatusForbidden, "权限不足")
-		return
-	}
-
 	// 从 URL 路径中获取 ID
 	idStr := r.PathValue("id")
 	finalID, err := strconv.ParseInt(idStr, 10, 64)
@@ -163,11 +266,181 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	affected, err := h.userService.DeleteUser(finalID)
+	affected, err := h.userService.DeleteUser(actorFromRequest(r), finalID)
 	if err != nil {
+		if errors.Is(err, service.ErrUserManageForbidden) {
+			utils.ErrorResponse(w, http.StatusForbidden, "禁止删除权限超出自身的用户或其他管理员")
+			return
+		}
 		utils.ErrorResponse(w, http.StatusInternalServerError, "删除失败")
 		return
 	}
+	if affected == 0 {
+		utils.ErrorResponse(w, http.StatusNotFound, "找不到用户")
+		return
+	}
 
 	utils.SuccessResponse(w, "删除成功", map[string]interface{}{"affected_rows": affected})
 }
+
+// ListDeletedUsers 获取当前组织回收站中的用户 (RESTful: GET /api/users/trash，需 users:delete 权限)
+func (h *UserHandler) ListDeletedUsers(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	orgID, _ := r.Context().Value("orgID").(int64)
+	users, total, err := h.userService.GetDeletedUsers(orgID, page, limit)
+	if err != nil {
+		utils.UserLogger.Error("查询组织 %d 的回收站失败: %v", orgID, err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "数据库查询失败")
+		return
+	}
+	for i := range users {
+		setAvatarURLs(r, h.avatarService, &users[i])
+	}
+
+	utils.SuccessResponse(w, "查询成功", map[string]interface{}{
+		"users":          users,
+		"total":          total,
+		"retention_days": h.trashRetentionDays,
+	})
+}
+
+// RestoreUser 从回收站恢复用户 (RESTful: POST /api/users/{id}/restore，需 users:delete 权限)
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	if err := h.userService.RestoreUser(actorFromRequest(r), id); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, "回收站中找不到该用户")
+			return
+		}
+		if errors.Is(err, service.ErrUserManageForbidden) {
+			utils.ErrorResponse(w, http.StatusForbidden, "禁止恢复权限超出自身的用户或其他管理员")
+			return
+		}
+		utils.UserLogger.Error("恢复用户 %d 失败: %v", id, err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "恢复失败")
+		return
+	}
+	utils.SuccessResponse(w, "恢复成功", map[string]interface{}{"id": id})
+}
+
+// PurgeUser 彻底删除回收站中的用户，删除后无法恢复 (RESTful: DELETE /api/users/{id}/purge，需 users:delete 权限)
+func (h *UserHandler) PurgeUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	if err := h.userService.PurgeUser(actorFromRequest(r), id); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, "回收站中找不到该用户")
+			return
+		}
+		if errors.Is(err, service.ErrUserManageForbidden) {
+			utils.ErrorResponse(w, http.StatusForbidden, "禁止删除权限超出自身的用户或其他管理员")
+			return
+		}
+		utils.UserLogger.Error("彻底删除用户 %d 失败: %v", id, err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, "删除失败")
+		return
+	}
+	utils.SuccessResponse(w, "已彻底删除", map[string]interface{}{"id": id})
+}
+
+// assignableRole 校验角色存在且操作者有权授予，否则直接写入错误响应
+func (h *UserHandler) assignableRole(w http.ResponseWriter, operatorRole, role string) bool {
+	exists, err := h.rbacService.RoleExists(role)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "查询角色失败")
+		return false
+	}
+	if !exists {
+		utils.ErrorResponse(w, http.StatusBadRequest, "角色不存在")
+		return false
+	}
+	if !h.rbacService.CanAssign(operatorRole, role) {
+		utils.ErrorResponse(w, http.StatusForbidden, "不能授予超出自身权限的角色")
+		return false
+	}
+	return true
+}
+
+// writeEmailError 将邮箱校验错误映射为响应
+// 返回: bool 是否已写入响应
+func writeEmailError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrInvalidEmail):
+		utils.ErrorResponse(w, http.StatusBadRequest, "邮箱格式错误")
+	case errors.Is(err, service.ErrEmailExists):
+		utils.ErrorResponse(w, http.StatusConflict, "该邮箱已被其他账号使用")
+	default:
+		return false
+	}
+	return true
+}
+
+// writeUsernameError 将用户名校验错误映射为 400 响应
+// 返回: bool 是否已写入响应
+func writeUsernameError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrUsernameRequired):
+		utils.ErrorResponse(w, http.StatusBadRequest, "用户名不能为空")
+	case errors.Is(err, service.ErrUsernameTooLong):
+		utils.ErrorResponse(w, http.StatusBadRequest, "用户名过长")
+	case errors.Is(err, service.ErrUsernameInvalid):
+		utils.ErrorResponse(w, http.StatusBadRequest, "用户名不能包含空白、控制字符或 @")
+	default:
+		return false
+	}
+	return true
+}
+
+// setAvatarURLs 将用户的头像文件名替换为存储后端提供的访问地址，并附带各尺寸缩略图地址
+// 未上传头像的用户使用默认头像 (SVG，各尺寸均为同一地址；回收站中的用户没有默认头像)
+func setAvatarURLs(r *http.Request, avatars *service.AvatarService, u *models.User) {
+	link, thumbs := avatars.URLs(r.Context(), u.Avatar)
+	if link == "" && u.ID > 0 && u.DeletedAt == nil {
+		link = avatars.DefaultURL(u.ID)
+		thumbs = make(map[string]string, len(service.AvatarSizes))
+		for _, size := range service.AvatarSizes {
+			thumbs[strconv.Itoa(size)] = link
+		}
+	}
+	u.Avatar = absoluteURL(r, link)
+	u.AvatarThumbnails = nil
+	if len(thumbs) > 0 {
+		u.AvatarThumbnails = make(map[string]string, len(thumbs))
+		for size, thumb := range thumbs {
+			u.AvatarThumbnails[size] = absoluteURL(r, thumb)
+		}
+	}
+}
+
+// absoluteURL 本地存储返回的站内路径 (以 / 开头) 补全为带协议与主机的完整地址，其余地址原样返回
+func absoluteURL(r *http.Request, link string) string {
+	if !strings.HasPrefix(link, "/") {
+		return link
+	}
+	protocol := "http"
+	if r.TLS != nil {
+		protocol = "https"
+	}
+	host := r.Host
+	if host == "" {
+		host = "localhost:8090"
+	}
+	return fmt.Sprintf("%s://%s%s", protocol, host, link)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"GoWork_7/internal/service"
+	"GoWork_7/internal/utils"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// UserImportHandler 批量导入用户控制器
+type UserImportHandler struct {
+	importService *service.UserImportService
+}
+
+// NewUserImportHandler 创建批量导入控制器实例
+func NewUserImportHandler(importService *service.UserImportService) *UserImportHandler {
+	return &UserImportHandler{importService: importService}
+}
+
+// Import 上传 CSV/XLSX 批量导入用户 (RESTful: POST /api/users/import，需 users:create 权限)
+// 表单字段 file 为导入文件；dry_run 默认为 true，仅校验并返回逐行报告，传 false 时创建全部校验通过的行
+func (h *UserImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, service.UserImportMaxFileSize+1<<20)
+	if err := r.ParseMultipartForm(service.UserImportMaxFileSize); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("文件过大，不能超过 %d MB", service.UserImportMaxFileSize>>20))
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "请选择要导入的文件")
+		return
+	}
+	defer file.Close()
+
+	dryRun := true
+	if v := r.FormValue("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "dry_run 只能为 true 或 false")
+			return
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, service.UserImportMaxFileSize+1))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "读取文件失败")
+		return
+	}
+	if len(data) > service.UserImportMaxFileSize {
+		utils.ErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("文件过大，不能超过 %d MB", service.UserImportMaxFileSize>>20))
+		return
+	}
+
+	report, err := h.importService.Import(actorFromRequest(r), header.Filename, data, dryRun)
+	if err != nil {
+		var headerErr *service.ImportHeaderError
+		switch {
+		case errors.As(err, &headerErr):
+			utils.ErrorDetailResponse(w, http.StatusBadRequest, "表头错误："+headerErr.Error(), map[string]interface{}{
+				"missing": headerErr.Missing,
+				"unknown": headerErr.Unknown,
+			})
+		case errors.Is(err, service.ErrImportUnsupportedFormat):
+			utils.ErrorResponse(w, http.StatusBadRequest, "仅支持 UTF-8 编码的 CSV 或 XLSX 文件")
+		case errors.Is(err, service.ErrImportNoRows):
+			utils.ErrorResponse(w, http.StatusBadRequest, "文件中没有可导入的数据")
+		case errors.Is(err, service.ErrImportTooManyRows):
+			utils.ErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("单次最多导入 %d 行", service.UserImportMaxRows))
+		case errors.Is(err, service.ErrImportConflict):
+			utils.ErrorResponse(w, http.StatusConflict, "导入期间部分用户名或邮箱已被占用，请重新校验后再导入")
+		default:
+			utils.UserLogger.Error("批量导入用户失败: %v", err)
+			utils.ErrorResponse(w, http.StatusInternalServerError, "导入失败")
+		}
+		return
+	}
+
+	msg := fmt.Sprintf("校验完成：%d 行通过，%d 行有误", report.Valid, report.Invalid)
+	if !dryRun {
+		msg = fmt.Sprintf("导入完成：已创建 %d 个用户，跳过 %d 行有误的数据", report.Created, report.Invalid)
+	}
+	utils.SuccessResponse(w, msg, report)
+}
@@ -10,12 +10,14 @@ import (
 
 // AuthMiddlewareProvider 认证中间件提供者
 type AuthMiddlewareProvider struct {
-	userRepo *repository.UserRepository
+	userRepo    *repository.UserRepository
+	revocations repository.RevocationStore
+	permissions PermissionChecker
 }
 
 // NewAuthMiddlewareProvider 创建认证中间件提供者实例
-func NewAuthMiddlewareProvider(userRepo *repository.UserRepository) *AuthMiddlewareProvider {
-	return &AuthMiddlewareProvider{userRepo: userRepo}
+func NewAuthMiddlewareProvider(userRepo *repository.UserRepository, revocations repository.RevocationStore, permissions PermissionChecker) *AuthMiddlewareProvider {
+	return &AuthMiddlewareProvider{userRepo: userRepo, revocations: revocations, permissions: permissions}
 }
 
 // AuthMiddleware 核心认证中间件
@@ -25,7 +27,7 @@ func (p *AuthMiddlewareProvider) AuthMiddleware(next http.Handler) http.Handler
 		// 1. 获取 Authorization 请求头
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			utils.SetCORSHeaders(w, "GET, POST, PUT, DELETE, OPTIONS")
+			utils.SetCORSHeaders(w, "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 			http.Error(w, "Unauthorized: No token provided", http.StatusUnauthorized)
 			return
 		}
@@ -43,22 +45,40 @@ func (p *AuthMiddlewareProvider) AuthMiddleware(next http.Handler) http.Handler
 		// 3. 解析并校验 Token
 		claims, err := utils.ParseToken(tokenStr)
 		if err != nil {
-			utils.SetCORSHeaders(w, "GET, POST, PUT, DELETE, OPTIONS")
+			utils.SetCORSHeaders(w, "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 			http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
 			return
 		}
 
-		// 4. 二次校验：检查数据库中用户状态和角色是否发生变更
-		newRole, changed, active := p.checkUserPermissionFromDB(claims.ID, claims.Role)
+		// 4. 撤销校验：已注销或被管理员强制下线的令牌直接拒绝
+		revoked, err := p.revocations.IsRevoked(claims.RegisteredClaims.ID, claims.ID, claims.IssuedAt.Time)
+		if err != nil {
+			utils.AuthLogger.Error("查询令牌撤销状态失败: %v", err)
+		}
+		if err != nil || revoked {
+			utils.SetCORSHeaders(w, "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			http.Error(w, "Unauthorized: Token revoked", http.StatusUnauthorized)
+			return
+		}
+
+		// 旧版令牌未携带组织，要求客户端刷新后重新获取
+		if claims.OrgID == 0 {
+			utils.SetCORSHeaders(w, "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		// 5. 二次校验：检查数据库中用户状态、组织成员关系和组织内角色是否发生变更
+		newRole, changed, active := p.checkUserPermissionFromDB(claims.ID, claims.OrgID, claims.Role)
 		if !active {
-			utils.SetCORSHeaders(w, "GET, POST, PUT, DELETE, OPTIONS")
+			utils.SetCORSHeaders(w, "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 			http.Error(w, "账号已被禁用或不存在", http.StatusForbidden)
 			return
 		}
 
-		// 5. 如果角色发生变更，自动下发新 Token (实现无缝角色切换)
+		// 6. 如果角色发生变更，自动下发新 Token (实现无缝角色切换)
 		if changed {
-			newToken, err := utils.GenerateToken(claims.ID, claims.Username, newRole)
+			newToken, err := utils.GenerateToken(claims.ID, claims.Username, newRole, claims.OrgID)
 			if err == nil {
 				w.Header().Set("New-Token", newToken)
 				w.Header().Set("Access-Control-Expose-Headers", "New-Token")
@@ -66,20 +86,23 @@ func (p *AuthMiddlewareProvider) AuthMiddleware(next http.Handler) http.Handler
 			}
 		}
 
-		// 6. 将用户信息注入 Context，供后续 Handler 使用
+		// 7. 将用户信息注入 Context，供后续 Handler 使用
 		ctx := context.WithValue(r.Context(), "userID", claims.ID)
 		ctx = context.WithValue(ctx, "role", claims.Role)
+		ctx = context.WithValue(ctx, "orgID", claims.OrgID)
 		ctx = context.WithValue(ctx, "username", claims.Username)
+		ctx = context.WithValue(ctx, "tokenID", claims.RegisteredClaims.ID)
+		ctx = context.WithValue(ctx, "tokenExpiresAt", claims.ExpiresAt.Time)
 
-		// 7. 继续执行下一个处理器
+		// 8. 继续执行下一个处理器
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// checkUserPermissionFromDB 校验用户在数据库中的实时状态
-// 返回值: 实时角色, 角色是否变更, 账号是否活跃
-func (p *AuthMiddlewareProvider) checkUserPermissionFromDB(id int64, oldRole string) (string, bool, bool) {
-	user, err := p.userRepo.GetByID(id)
+// checkUserPermissionFromDB 校验用户在数据库中的实时状态 (已被移出组织视为不活跃)
+// 返回值: 组织内实时角色, 角色是否变更, 账号是否活跃
+func (p *AuthMiddlewareProvider) checkUserPermissionFromDB(id, orgID int64, oldRole string) (string, bool, bool) {
+	user, err := p.userRepo.GetByIDInOrg(orgID, id)
 	if err != nil {
 		return oldRole, false, false
 	}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"GoWork_7/internal/utils"
+	"net/http"
+)
+
+// PermissionChecker 权限校验器
+type PermissionChecker interface {
+	HasPermission(role, permission string) bool
+}
+
+// RequirePermission 权限校验中间件，须放在 AuthMiddleware 之后
+// 用法: auth.AuthMiddleware(auth.RequirePermission("users:delete")(handler))
+func (p *AuthMiddlewareProvider) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value("role").(string)
+			if !p.permissions.HasPermission(role, permission) {
+				userID, _ := r.Context().Value("userID").(int64)
+				utils.AuthLogger.Info("用户 %d (角色 %s) 缺少权限 %s: %s %s", userID, role, permission, r.Method, r.URL.Path)
+				utils.ErrorResponse(w, http.StatusForbidden, "权限不足")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
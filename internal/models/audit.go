@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actor 操作者及请求来源 (由 Handler 从认证上下文与请求头中提取)
+type Actor struct {
+	UserID    int64
+	Username  string
+	Role      string
+	OrgID     int64
+	IP        string
+	UserAgent string
+}
+
+// AuditEvent 审计事件模型结构体
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	OrgID      int64           `json:"org_id,omitempty"`
+	ActorID    int64           `json:"actor_id,omitempty"`
+	ActorName  string          `json:"actor_name"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter 审计日志查询条件 (零值表示不限)
+type AuditFilter struct {
+	OrgID      int64
+	ActorID    int64
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+	Page       int
+	Limit      int
+}
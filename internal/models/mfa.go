@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// UserMFA 用户 TOTP 二次验证记录 (Enabled 为 false 时为待激活的登记)
+type UserMFA struct {
+	UserID       int64
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+	EnabledAt    *time.Time
+}
+
+// MFAStatus 二次验证状态
+type MFAStatus struct {
+	Enabled                bool `json:"enabled"`
+	Pending                bool `json:"pending"`  // 已登记但尚未激活
+	Required               bool `json:"required"` // 当前角色要求二次验证
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// MFAEnrollment 登记信息：供认证器 App 扫码或手动录入
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_png"` // data:image/png;base64,...
+}
+
+// MFAChallenge 密码校验通过后等待二次验证的挑战
+type MFAChallenge struct {
+	Token     string `json:"mfa_token"`
+	ExpiresIn int64  `json:"expires_in"`
+	// Enrollment 角色要求二次验证但用户尚未登记时返回，验证通过即完成激活
+	Enrollment *MFAEnrollment `json:"enrollment,omitempty"`
+}
+
+// MFACodeRequest 提交验证码 (TOTP 或恢复码) 请求结构体
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFAVerifyRequest 登录二次验证请求结构体
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
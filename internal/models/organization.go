@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Organization 组织 (租户) 模型结构体
+type Organization struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// OrgMembership 用户在某个组织中的成员身份
+type OrgMembership struct {
+	OrgID   int64  `json:"org_id"`
+	OrgName string `json:"org_name"`
+	Role    string `json:"role"`
+}
+
+// OrgRequest 创建/重命名组织请求结构体
+type OrgRequest struct {
+	Name string `json:"name"`
+}
+
+// OrgMemberRequest 添加组织成员或修改成员角色请求结构体
+type OrgMemberRequest struct {
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role"`
+}
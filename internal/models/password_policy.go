@@ -0,0 +1,19 @@
+package models
+
+// PasswordPolicyInfo 对外公开的密码策略 (供前端提示与预校验)
+type PasswordPolicyInfo struct {
+	MinLength     int  `json:"min_length"`
+	MaxLength     int  `json:"max_length"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	HistoryCount  int  `json:"history_count"` // 不能与最近 N 个密码相同，0 表示不限制
+	MaxAgeDays    int  `json:"max_age_days"`  // 密码最长有效天数，0 表示永不过期
+}
+
+// PasswordViolation 单条密码策略违规 (Code 供前端识别，Message 为可直接展示的中文说明)
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// Role 角色模型结构体
+type Role struct {
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	System      bool     `json:"system"`
+	MFARequired bool     `json:"mfa_required"` // 该角色的成员登录时必须通过二次验证
+	Permissions []string `json:"permissions"`
+}
+
+// Permission 权限模型结构体
+type Permission struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// RoleRequest 创建/修改角色请求结构体
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	MFARequired bool     `json:"mfa_required"`
+	Permissions []string `json:"permissions"`
+}
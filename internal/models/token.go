@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// RefreshToken 刷新令牌记录 (数据库中只保存令牌的 SHA-256 摘要)
+type RefreshToken struct {
+	ID         int64
+	UserID     int64
+	OrgID      int64
+	FamilyID   string
+	TokenHash  string
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy *int64
+	CreatedAt  time.Time
+}
+
+// TokenPair 登录或刷新后下发的令牌对
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// RefreshRequest 刷新令牌请求结构体
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// SwitchOrgRequest 切换组织请求结构体
+type SwitchOrgRequest struct {
+	OrgID        int64  `json:"org_id"`
+	RefreshToken string `json:"refresh_token"` // 可选：一并撤销旧组织会话的刷新令牌
+}
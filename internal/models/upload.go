@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// TusUpload 可续传上传 (tus 1.0)
+type TusUpload struct {
+	ID          string
+	UserID      int64
+	OrgID       int64
+	Length      int64  // 文件总字节数 (Upload-Length)
+	Offset      int64  // 已接收的字节数 (Upload-Offset)
+	Metadata    string // 创建时的 Upload-Metadata 原文
+	StorageKey  string // 合并后的文件 (完成后非空)
+	ExpiresAt   time.Time
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+}
+
+// Completed 是否已接收全部数据并合并
+func (u *TusUpload) Completed() bool {
+	return u.CompletedAt != nil
+}
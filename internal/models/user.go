@@ -1,25 +1,95 @@
 package models
 
+import "time"
+
+// 用户账号状态 (users.status)
+const (
+	UserStatusEnabled  = "enabled"
+	UserStatusDisabled = "disabled"
+	UserStatusPending  = "pending" // 自助注册后尚未验证邮箱
+)
+
 // User 用户模型结构体
 type User struct {
-	ID        int64  `json:"id"`
-	Username  string `json:"username"`
-	Password  string `json:"-"` // 关键：转 JSON 时隐藏密码
-	LastLogin string `json:"last_login"`
-	Role      string `json:"role"`
-	Enable    bool   `json:"enable"`
-	Avatar    string `json:"avatar,omitempty"`
+	ID                int64             `json:"id"`
+	Username          string            `json:"username"`
+	Email             string            `json:"email,omitempty"`
+	EmailVerified     bool              `json:"email_verified"`
+	Password          string            `json:"-"` // 关键：转 JSON 时隐藏密码
+	PasswordChangedAt time.Time         `json:"-"` // 密码最近一次修改时间 (用于密码最长有效期)
+	LastLogin         string            `json:"last_login"`
+	Role              string            `json:"role"`             // 用户在当前组织中的角色
+	OrgID             int64             `json:"org_id,omitempty"` // 当前组织ID
+	Enable            bool              `json:"enable"`
+	Status            string            `json:"status,omitempty"` // enabled / disabled / pending
+	Avatar            string            `json:"avatar,omitempty"`
+	AvatarThumbnails  map[string]string `json:"avatar_thumbnails,omitempty"` // 缩略图尺寸 -> 访问地址
+	CreatedAt         *time.Time        `json:"created_at,omitempty"`        // 注册时间 (仅用户列表返回)
+	DeletedAt         *time.Time        `json:"deleted_at,omitempty"`        // 移入回收站的时间 (仅回收站列表返回)
+	DeletedBy         int64             `json:"deleted_by,omitempty"`        // 执行删除的操作者ID
+}
+
+// UserListFilter 用户列表查询条件 (零值表示不限)
+type UserListFilter struct {
+	OrgID         int64
+	Keyword       string // 用户名或邮箱模糊匹配
+	Status        string // 1 启用, 2 待验证邮箱, 其余非空值为禁用
+	Role          string // 组织内角色
+	LastLoginFrom time.Time
+	LastLoginTo   time.Time
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	Sort          string // 排序字段：id (默认) / username / last_login / created_at / role
+	Desc          bool   // 是否倒序
+	Page          int
+	Limit         int
+	UseCursor     bool   // 使用游标分页 (忽略 Page，不统计总数)
+	Cursor        string // 上一页返回的 next_cursor，为空表示第一页
+}
+
+// ForgotPasswordRequest 申请重置密码请求结构体
+type ForgotPasswordRequest struct {
+	Username string `json:"username"` // 用户名或邮箱
+}
+
+// VerifyEmailRequest 凭验证令牌确认邮箱请求结构体
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ResendVerificationRequest 重新发送验证邮件请求结构体
+type ResendVerificationRequest struct {
+	Username string `json:"username"` // 用户名或邮箱
+}
+
+// ResetPasswordRequest 凭重置令牌设置新密码请求结构体
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ProfileUpdateRequest 本人修改个人资料请求结构体 (PATCH /api/users/me，仅允许以下字段)
+type ProfileUpdateRequest struct {
+	Email *string `json:"email"` // nil 表示不修改，空字符串表示解除绑定
+}
+
+// ChangePasswordRequest 本人修改密码请求结构体
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
 }
 
 // LoginRequest 登录请求结构体
 type LoginRequest struct {
-	Username string `json:"username"`
+	Username string `json:"username"` // 用户名或邮箱
 	Password string `json:"password"`
+	OrgID    int64  `json:"org_id,omitempty"` // 可选：登录后进入的组织，为空时进入加入最早的组织
 }
 
 // RegisterRequest 注册请求结构体
 type RegisterRequest struct {
 	Username string `json:"username"`
+	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
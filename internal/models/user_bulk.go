@@ -0,0 +1,46 @@
+package models
+
+// 批量操作类型
+const (
+	UserBulkEnable        = "enable"
+	UserBulkDisable       = "disable"
+	UserBulkDelete        = "delete"
+	UserBulkSetRole       = "set-role"
+	UserBulkResetPassword = "reset-password"
+)
+
+// UserBulkRequest 批量操作用户请求结构体 (ids 与 filter 二选一)
+type UserBulkRequest struct {
+	Action string          `json:"action"`
+	IDs    []int64         `json:"ids"`
+	Filter *UserBulkFilter `json:"filter"`
+	Role   string          `json:"role"` // set-role 的目标角色
+}
+
+// UserBulkFilter 按用户列表的筛选条件选择操作对象 (与 GET /api/users 的 keyword、status、role 相同)
+type UserBulkFilter struct {
+	Keyword string `json:"keyword"`
+	Status  string `json:"status"`
+	Role    string `json:"role"`
+}
+
+// UserBulkReport 批量操作结果报告
+type UserBulkReport struct {
+	Action    string           `json:"action"`
+	Total     int              `json:"total"`     // 操作对象数
+	Succeeded int              `json:"succeeded"` // 成功数 (含无需变更的用户)
+	Failed    int              `json:"failed"`    // 被拒绝或找不到的用户数
+	Results   []UserBulkResult `json:"results"`
+}
+
+// UserBulkResult 单个用户的操作结果
+type UserBulkResult struct {
+	ID                int64  `json:"id"`
+	Username          string `json:"username,omitempty"`
+	Success           bool   `json:"success"`
+	Unchanged         bool   `json:"unchanged,omitempty"`          // 已是目标状态，未做修改
+	Trashed           bool   `json:"trashed,omitempty"`            // delete：账号已移入回收站
+	GeneratedPassword string `json:"generated_password,omitempty"` // reset-password：新生成的密码，仅返回一次
+	Code              string `json:"code,omitempty"`               // 失败原因代码
+	Message           string `json:"message,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// UserImportReport 批量导入用户的结果报告
+type UserImportReport struct {
+	DryRun  bool            `json:"dry_run"` // 是否仅校验
+	Total   int             `json:"total"`   // 数据行数 (不含表头与空行)
+	Valid   int             `json:"valid"`   // 校验通过的行数
+	Invalid int             `json:"invalid"` // 校验失败的行数
+	Created int             `json:"created"` // 实际创建的用户数 (仅校验时为 0)
+	Rows    []UserImportRow `json:"rows"`
+}
+
+// UserImportRow 单行导入结果
+type UserImportRow struct {
+	Row               int               `json:"row"` // 文件中的行号 (表头为第 1 行)
+	Username          string            `json:"username"`
+	Email             string            `json:"email,omitempty"`
+	Role              string            `json:"role"`
+	Status            string            `json:"status"`
+	PasswordGenerated bool              `json:"password_generated"`           // 密码列为空，由系统生成
+	GeneratedPassword string            `json:"generated_password,omitempty"` // 生成的密码，仅在导入成功时返回一次
+	ID                int64             `json:"id,omitempty"`                 // 新用户ID (导入成功后)
+	Errors            []UserImportError `json:"errors,omitempty"`
+}
+
+// UserImportError 单行中的一个字段错误
+type UserImportError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"GoWork_7/internal/models"
+	"database/sql"
+)
+
+// AuditRepository 审计日志数据访问仓库
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository 创建审计日志仓库实例
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create 写入审计事件
+// 参数: e 审计事件 (OrgID/ActorID 为 0 时写入 NULL)
+// 返回: error 错误信息
+func (r *AuditRepository) Create(e *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_logs(org_id, actor_id, actor_name, action, target_type, target_id, before_data, after_data, ip, user_agent)
+		VALUES (?,?,?,?,?,?,?,?,?,?)`
+	_, err := r.db.Exec(query, nullID(e.OrgID), nullID(e.ActorID), e.ActorName, e.Action, e.TargetType, e.TargetID,
+		nullJSON(e.Before), nullJSON(e.After), e.IP, e.UserAgent)
+	return err
+}
+
+// Query 按条件分页查询审计事件，按时间倒序
+// 参数: f 查询条件
+// 返回: []models.AuditEvent 事件切片, int 总记录数, error 错误信息
+func (r *AuditRepository) Query(f models.AuditFilter) ([]models.AuditEvent, int, error) {
+	whereClause := ""
+	var args []interface{}
+
+	if f.OrgID != 0 {
+		whereClause += " AND org_id = ?"
+		args = append(args, f.OrgID)
+	}
+	if f.ActorID != 0 {
+		whereClause += " AND actor_id = ?"
+		args = append(args, f.ActorID)
+	}
+	if f.Action != "" {
+		whereClause += " AND action = ?"
+		args = append(args, f.Action)
+	}
+	if f.TargetType != "" {
+		whereClause += " AND target_type = ?"
+		args = append(args, f.TargetType)
+	}
+	if f.TargetID != "" {
+		whereClause += " AND target_id = ?"
+		args = append(args, f.TargetID)
+	}
+	if !f.From.IsZero() {
+		whereClause += " AND created_at >= ?"
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		whereClause += " AND created_at < ?"
+		args = append(args, f.To)
+	}
+	if whereClause != "" {
+		whereClause = "WHERE " + whereClause[5:]
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM audit_logs "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, COALESCE(org_id, 0), COALESCE(actor_id, 0), actor_name, action, target_type, target_id,
+			before_data, after_data, ip, user_agent, created_at
+		FROM audit_logs
+		` + whereClause + `
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`
+	rows, err := r.db.Query(query, append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var e models.AuditEvent
+		var before, after []byte
+		if err := rows.Scan(&e.ID, &e.OrgID, &e.ActorID, &e.ActorName, &e.Action, &e.TargetType, &e.TargetID,
+			&before, &after, &e.IP, &e.UserAgent, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		e.Before, e.After = before, after
+		events = append(events, e)
+	}
+	return events, total, rows.Err()
+}
+
+// nullID 将 0 转换为 NULL
+func nullID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// nullJSON 将空 JSON 转换为 NULL
+func nullJSON(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrAvatarUploadNotFound 临时头像不存在、已过期、已被认领或不是当前操作者上传的
+var ErrAvatarUploadNotFound = errors.New("AVATAR_UPLOAD_NOT_FOUND")
+
+// AvatarUploadRepository 临时头像上传记录数据访问仓库
+type AvatarUploadRepository struct {
+	db *sql.DB
+}
+
+// NewAvatarUploadRepository 创建临时头像上传记录仓库实例
+func NewAvatarUploadRepository(db *sql.DB) *AvatarUploadRepository {
+	return &AvatarUploadRepository{db: db}
+}
+
+// Create 登记一次临时上传 (新建用户前通过通用接口上传的头像)
+// 参数: orgID 上传者当前组织ID, uploadedBy 上传者ID, avatar 头像文件名
+func (r *AvatarUploadRepository) Create(orgID, uploadedBy int64, avatar string) error {
+	_, err := r.db.Exec("INSERT INTO avatar_uploads(avatar, org_id, uploaded_by) VALUES (?,?,?)", avatar, orgID, uploadedBy)
+	return err
+}
+
+// DeleteExpired 删除在指定时间之前上传且未被认领的记录
+// 返回: int64 删除的记录数, error 错误信息
+func (r *AvatarUploadRepository) DeleteExpired(cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM avatar_uploads WHERE created_at < ?", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Referenced 批量查询头像文件名是否仍被引用 (任一用户的头像，含回收站中的账号，或尚未认领的临时上传)
+// 参数: avatars 头像文件名
+// 返回: map[string]bool 仍被引用的文件名, error 错误信息
+func (r *AvatarUploadRepository) Referenced(avatars []string) (map[string]bool, error) {
+	const chunk = 500
+	referenced := make(map[string]bool)
+	for start := 0; start < len(avatars); start += chunk {
+		end := min(start+chunk, len(avatars))
+		batch := avatars[start:end]
+		args := make([]interface{}, 0, len(batch)*2)
+		for _, a := range batch {
+			args = append(args, a)
+		}
+		args = append(args, args...)
+		in := "IN (?" + strings.Repeat(",?", len(batch)-1) + ")"
+		rows, err := r.db.Query("SELECT avatar FROM users WHERE avatar "+in+" UNION SELECT avatar FROM avatar_uploads WHERE avatar "+in, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var avatar string
+			if err := rows.Scan(&avatar); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			referenced[avatar] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return referenced, nil
+}
+
+// claimAvatarUpload 在事务中认领一条临时上传记录 (删除该记录)，记录须由同一操作者在当前组织内于 notBefore 之后上传
+func claimAvatarUpload(tx *sql.Tx, orgID, uploadedBy int64, avatar string, notBefore time.Time) error {
+	result, err := tx.Exec("DELETE FROM avatar_uploads WHERE avatar = ? AND org_id = ? AND uploaded_by = ? AND created_at >= ? LIMIT 1",
+		avatar, orgID, uploadedBy, notBefore)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAvatarUploadNotFound
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrVerificationTokenNotFound 邮箱验证令牌不存在、已过期或已使用
+var ErrVerificationTokenNotFound = errors.New("VERIFICATION_TOKEN_NOT_FOUND")
+
+// EmailVerificationRepository 邮箱验证令牌数据访问仓库
+type EmailVerificationRepository struct {
+	db *sql.DB
+}
+
+// NewEmailVerificationRepository 创建邮箱验证令牌仓库实例
+func NewEmailVerificationRepository(db *sql.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+// Create 保存验证令牌，同时作废该用户此前未使用的令牌 (事务)
+// 参数: userID 用户ID, email 待验证的邮箱, tokenHash 令牌摘要, expiresAt 过期时间
+// 返回: error 错误信息
+func (r *EmailVerificationRepository) Create(userID int64, email, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE email_verifications SET used_at = NOW() WHERE user_id = ? AND used_at IS NULL", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO email_verifications(user_id, email, token_hash, expires_at) VALUES (?,?,?,?)",
+		userID, email, tokenHash, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountSince 统计用户在指定时间之后申请的验证邮件数量 (用于限制发送频率)
+// 参数: userID 用户ID, since 起始时间
+// 返回: int 次数, error 错误信息
+func (r *EmailVerificationRepository) CountSince(userID int64, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM email_verifications WHERE user_id = ? AND created_at >= ?", userID, since).Scan(&n)
+	return n, err
+}
+
+// Consume 使用验证令牌：条件更新保证同一令牌只能成功使用一次
+// 参数: tokenHash 令牌摘要
+// 返回: int64 令牌所属用户ID, string 签发时的邮箱, error 错误信息 (无效时为 ErrVerificationTokenNotFound)
+func (r *EmailVerificationRepository) Consume(tokenHash string) (int64, string, error) {
+	var id, userID int64
+	var email string
+	err := r.db.QueryRow("SELECT id, user_id, email FROM email_verifications WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		tokenHash, time.Now()).Scan(&id, &userID, &email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", ErrVerificationTokenNotFound
+		}
+		return 0, "", err
+	}
+
+	result, err := r.db.Exec("UPDATE email_verifications SET used_at = NOW() WHERE id = ? AND used_at IS NULL", id)
+	if err != nil {
+		return 0, "", err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return 0, "", err
+	} else if n == 0 {
+		// 并发请求已抢先使用
+		return 0, "", ErrVerificationTokenNotFound
+	}
+	return userID, email, nil
+}
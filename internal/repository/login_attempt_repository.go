@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// 登录失败计数的维度
+const (
+	AttemptScopeUser = "user"
+	AttemptScopeIP   = "ip"
+)
+
+// LoginAttemptRepository 登录失败计数数据访问仓库 (持久化，重启后仍然有效)
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepository 创建登录失败计数仓库实例
+func NewLoginAttemptRepository(db *sql.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// LockedUntil 查询锁定截止时间
+// 参数: scope 维度, key 用户名或 IP
+// 返回: time.Time 锁定截止时间 (未锁定时为零值), error 错误信息
+func (r *LoginAttemptRepository) LockedUntil(scope, key string) (time.Time, error) {
+	var until sql.NullTime
+	err := r.db.QueryRow("SELECT locked_until FROM login_attempts WHERE scope = ? AND key_value = ?", scope, key).Scan(&until)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return until.Time, nil
+}
+
+// RecordFailure 累加失败次数；上次失败 (或锁定结束) 早于 windowStart 时重新计数
+// 参数: scope 维度, key 用户名或 IP, now 当前时间, windowStart 计数窗口起点
+// 返回: int 累加后的失败次数, error 错误信息
+func (r *LoginAttemptRepository) RecordFailure(scope, key string, now, windowStart time.Time) (int, error) {
+	// MySQL 按书写顺序执行赋值，failures 须在 last_failure 之前计算
+	query := `
+		INSERT INTO login_attempts(scope, key_value, failures, last_failure) VALUES (?,?,1,?)
+		ON DUPLICATE KEY UPDATE
+			failures = IF(GREATEST(last_failure, COALESCE(locked_until, last_failure)) < ?, 1, failures + 1),
+			last_failure = VALUES(last_failure)`
+	if _, err := r.db.Exec(query, scope, key, now, windowStart); err != nil {
+		return 0, err
+	}
+
+	var failures int
+	err := r.db.QueryRow("SELECT failures FROM login_attempts WHERE scope = ? AND key_value = ?", scope, key).Scan(&failures)
+	return failures, err
+}
+
+// Lock 设置锁定截止时间
+// 参数: scope 维度, key 用户名或 IP, until 锁定截止时间
+// 返回: error 错误信息
+func (r *LoginAttemptRepository) Lock(scope, key string, until time.Time) error {
+	_, err := r.db.Exec("UPDATE login_attempts SET locked_until = ? WHERE scope = ? AND key_value = ?", until, scope, key)
+	return err
+}
+
+// Reset 清除失败计数与锁定
+// 参数: scope 维度, key 用户名或 IP
+// 返回: int64 影响行数, error 错误信息
+func (r *LoginAttemptRepository) Reset(scope, key string) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM login_attempts WHERE scope = ? AND key_value = ?", scope, key)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
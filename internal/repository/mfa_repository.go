@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"GoWork_7/internal/models"
+	"database/sql"
+	"errors"
+)
+
+// ErrMFANotFound 用户未登记二次验证
+var ErrMFANotFound = errors.New("MFA_NOT_FOUND")
+
+// MFARepository 二次验证数据访问仓库
+type MFARepository struct {
+	db *sql.DB
+}
+
+// NewMFARepository 创建二次验证仓库实例
+func NewMFARepository(db *sql.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// Get 获取用户的二次验证记录
+// 参数: userID 用户ID
+// 返回: *models.UserMFA 记录, error 错误信息 (未登记时为 ErrMFANotFound)
+func (r *MFARepository) Get(userID int64) (*models.UserMFA, error) {
+	m := &models.UserMFA{}
+	var enabledAt sql.NullTime
+	err := r.db.QueryRow("SELECT user_id, secret, enabled, last_used_step, enabled_at FROM user_mfa WHERE user_id = ?", userID).
+		Scan(&m.UserID, &m.Secret, &m.Enabled, &m.LastUsedStep, &enabledAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMFANotFound
+		}
+		return nil, err
+	}
+	if enabledAt.Valid {
+		m.EnabledAt = &enabledAt.Time
+	}
+	return m, nil
+}
+
+// SavePending 保存待激活的密钥 (已激活的记录不会被覆盖)
+// 参数: userID 用户ID, secret Base32 密钥
+// 返回: bool 是否保存成功 (false 表示已激活), error 错误信息
+func (r *MFARepository) SavePending(userID int64, secret string) (bool, error) {
+	query := `
+		INSERT INTO user_mfa(user_id, secret) VALUES (?,?)
+		ON DUPLICATE KEY UPDATE secret = IF(enabled, secret, VALUES(secret))`
+	if _, err := r.db.Exec(query, userID, secret); err != nil {
+		return false, err
+	}
+	m, err := r.Get(userID)
+	if err != nil {
+		return false, err
+	}
+	return !m.Enabled && m.Secret == secret, nil
+}
+
+// Enable 激活二次验证并替换恢复码 (事务)
+// 参数: userID 用户ID, step 本次使用的 TOTP 时间步, codeHashes 恢复码摘要
+// 返回: bool 是否激活成功 (false 表示记录不存在或已激活), error 错误信息
+func (r *MFARepository) Enable(userID, step int64, codeHashes []string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE user_mfa SET enabled = TRUE, enabled_at = NOW(), last_used_step = ? WHERE user_id = ? AND enabled = FALSE", step, userID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// UseStep 记录已使用的 TOTP 时间步，同一时间步或更早的验证码不能再次使用
+// 返回: bool 是否记录成功 (false 表示重放), error 错误信息
+func (r *MFARepository) UseStep(userID, step int64) (bool, error) {
+	result, err := r.db.Exec("UPDATE user_mfa SET last_used_step = ? WHERE user_id = ? AND enabled = TRUE AND last_used_step < ?", step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// UseRecoveryCode 使用一次性恢复码
+// 参数: userID 用户ID, codeHash 恢复码摘要
+// 返回: bool 恢复码是否有效且未被使用, error 错误信息
+func (r *MFARepository) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	result, err := r.db.Exec("UPDATE mfa_recovery_codes SET used_at = NOW() WHERE user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ReplaceRecoveryCodes 作废旧恢复码并保存新恢复码 (事务)
+// 参数: userID 用户ID, codeHashes 恢复码摘要
+// 返回: error 错误信息
+func (r *MFARepository) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountRecoveryCodes 统计剩余可用的恢复码数量
+// 参数: userID 用户ID
+// 返回: int 数量, error 错误信息
+func (r *MFARepository) CountRecoveryCodes(userID int64) (int, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = ? AND used_at IS NULL", userID).Scan(&n)
+	return n, err
+}
+
+// Delete 删除用户的二次验证登记与恢复码 (事务)
+// 参数: userID 用户ID
+// 返回: int64 影响行数, error 错误信息
+func (r *MFARepository) Delete(userID int64) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec("DELETE FROM user_mfa WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return affected, tx.Commit()
+}
+
+// replaceRecoveryCodes 在事务中删除旧恢复码并写入新恢复码
+func replaceRecoveryCodes(tx *sql.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec("INSERT INTO mfa_recovery_codes(user_id, code_hash) VALUES (?,?)", userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"GoWork_7/internal/models"
+	"database/sql"
+	"errors"
+)
+
+var (
+	// ErrOrgNotFound 组织不存在错误
+	ErrOrgNotFound = errors.New("ORG_NOT_FOUND")
+)
+
+// OrgRepository 组织 (租户) 及成员关系数据访问仓库
+type OrgRepository struct {
+	db *sql.DB
+}
+
+// NewOrgRepository 创建组织仓库实例
+func NewOrgRepository(db *sql.DB) *OrgRepository {
+	return &OrgRepository{db: db}
+}
+
+// List 获取全部组织及成员数
+// 返回: []models.Organization 组织切片, error 错误信息
+func (r *OrgRepository) List() ([]models.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.created_at, COUNT(m.user_id)
+		FROM organizations o LEFT JOIN org_members m ON m.org_id = o.id
+		GROUP BY o.id, o.name, o.created_at
+		ORDER BY o.id ASC`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var o models.Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedAt, &o.MemberCount); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// GetByID 根据ID获取组织
+// 参数: id 组织ID
+// 返回: *models.Organization 组织, error 错误信息
+func (r *OrgRepository) GetByID(id int64) (*models.Organization, error) {
+	return r.getOne("SELECT id, name, created_at FROM organizations WHERE id = ?", id)
+}
+
+// GetByName 根据名称获取组织
+// 参数: name 组织名称
+// 返回: *models.Organization 组织, error 错误信息
+func (r *OrgRepository) GetByName(name string) (*models.Organization, error) {
+	return r.getOne("SELECT id, name, created_at FROM organizations WHERE name = ?", name)
+}
+
+// Create 创建组织
+// 参数: name 组织名称
+// 返回: int64 新组织ID, error 错误信息
+func (r *OrgRepository) Create(name string) (int64, error) {
+	result, err := r.db.Exec("INSERT INTO organizations(name) VALUES (?)", name)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// Rename 修改组织名称
+// 参数: id 组织ID, name 新名称
+// 返回: error 错误信息
+func (r *OrgRepository) Rename(id int64, name string) error {
+	_, err := r.db.Exec("UPDATE organizations SET name = ? WHERE id = ?", name, id)
+	return err
+}
+
+// Delete 删除组织 (成员关系级联删除)
+// 参数: id 组织ID
+// 返回: int64 影响行数, error 错误信息
+func (r *OrgRepository) Delete(id int64) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM organizations WHERE id = ?", id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListByUser 获取用户加入的全部组织，按加入时间升序
+// 参数: userID 用户ID
+// 返回: []models.OrgMembership 成员身份切片, error 错误信息
+func (r *OrgRepository) ListByUser(userID int64) ([]models.OrgMembership, error) {
+	query := `
+		SELECT m.org_id, o.name, m.role
+		FROM org_members m JOIN organizations o ON o.id = m.org_id
+		WHERE m.user_id = ?
+		ORDER BY m.created_at ASC, m.org_id ASC`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []models.OrgMembership{}
+	for rows.Next() {
+		var m models.OrgMembership
+		if err := rows.Scan(&m.OrgID, &m.OrgName, &m.Role); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+// SetMember 将用户加入组织，已是成员时修改其角色
+// 参数: orgID 组织ID, userID 用户ID, role 组织内角色
+// 返回: error 错误信息
+func (r *OrgRepository) SetMember(orgID, userID int64, role string) error {
+	query := "INSERT INTO org_members(org_id, user_id, role) VALUES (?,?,?) ON DUPLICATE KEY UPDATE role = VALUES(role)"
+	_, err := r.db.Exec(query, orgID, userID, role)
+	return err
+}
+
+// RemoveMember 将用户移出组织 (不删除账号)
+// 参数: orgID 组织ID, userID 用户ID
+// 返回: int64 影响行数, error 错误信息
+func (r *OrgRepository) RemoveMember(orgID, userID int64) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM org_members WHERE org_id = ? AND user_id = ?", orgID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CountMemberships 统计用户加入的组织数
+// 参数: userID 用户ID
+// 返回: int 组织数, error 错误信息
+func (r *OrgRepository) CountMemberships(userID int64) (int, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM org_members WHERE user_id = ?", userID).Scan(&n)
+	return n, err
+}
+
+// CountMembers 统计组织成员数
+// 参数: orgID 组织ID
+// 返回: int 成员数, error 错误信息
+func (r *OrgRepository) CountMembers(orgID int64) (int, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM org_members WHERE org_id = ?", orgID).Scan(&n)
+	return n, err
+}
+
+// getOne 查询单个组织
+func (r *OrgRepository) getOne(query string, arg interface{}) (*models.Organization, error) {
+	o := &models.Organization{}
+	if err := r.db.QueryRow(query, arg).Scan(&o.ID, &o.Name, &o.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrgNotFound
+		}
+		return nil, err
+	}
+	return o, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+// PasswordHistoryRepository 历史密码哈希数据访问仓库
+type PasswordHistoryRepository struct {
+	db *sql.DB
+}
+
+// NewPasswordHistoryRepository 创建历史密码仓库实例
+func NewPasswordHistoryRepository(db *sql.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{db: db}
+}
+
+// Add 保存被替换的旧密码哈希，并只保留最近 keep 条 (事务)
+// 参数: userID 用户ID, passwordHash 旧密码哈希, keep 保留条数
+// 返回: error 错误信息
+func (r *PasswordHistoryRepository) Add(userID int64, passwordHash string, keep int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT INTO password_history(user_id, password_hash) VALUES (?,?)", userID, passwordHash); err != nil {
+		return err
+	}
+	// MySQL 不允许 IN 子查询直接使用 LIMIT，需再包一层派生表
+	query := `
+		DELETE FROM password_history
+		WHERE user_id = ? AND id NOT IN (
+			SELECT id FROM (SELECT id FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?) AS recent
+		)`
+	if _, err := tx.Exec(query, userID, userID, keep); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Recent 获取用户最近使用过的 n 个旧密码哈希 (新的在前)
+// 参数: userID 用户ID, n 条数
+// 返回: []string 密码哈希, error 错误信息
+func (r *PasswordHistoryRepository) Recent(userID int64, n int) ([]string, error) {
+	rows, err := r.db.Query("SELECT password_hash FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?", userID, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, h)
+	}
+	return hashes, rows.Err()
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrResetTokenNotFound 重置令牌不存在、已过期或已使用
+var ErrResetTokenNotFound = errors.New("RESET_TOKEN_NOT_FOUND")
+
+// PasswordResetRepository 密码重置令牌数据访问仓库
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+// NewPasswordResetRepository 创建密码重置令牌仓库实例
+func NewPasswordResetRepository(db *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// Create 保存重置令牌，同时作废该用户此前未使用的令牌 (事务)
+// 参数: userID 用户ID, tokenHash 令牌摘要, expiresAt 过期时间, ip 请求来源 IP
+// 返回: error 错误信息
+func (r *PasswordResetRepository) Create(userID int64, tokenHash string, expiresAt time.Time, ip string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE password_resets SET used_at = NOW() WHERE user_id = ? AND used_at IS NULL", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO password_resets(user_id, token_hash, expires_at, requested_ip) VALUES (?,?,?,?)",
+		userID, tokenHash, expiresAt, ip); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountSince 统计用户在指定时间之后申请的重置次数 (用于限制发送频率)
+// 参数: userID 用户ID, since 起始时间
+// 返回: int 次数, error 错误信息
+func (r *PasswordResetRepository) CountSince(userID int64, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM password_resets WHERE user_id = ? AND created_at >= ?", userID, since).Scan(&n)
+	return n, err
+}
+
+// Find 查询有效的重置令牌所属用户 (不消耗令牌，用于设置新密码前的校验)
+// 参数: tokenHash 令牌摘要
+// 返回: int64 令牌所属用户ID, error 错误信息 (无效时为 ErrResetTokenNotFound)
+func (r *PasswordResetRepository) Find(tokenHash string) (int64, error) {
+	var userID int64
+	err := r.db.QueryRow("SELECT user_id FROM password_resets WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		tokenHash, time.Now()).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrResetTokenNotFound
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// Consume 使用重置令牌：条件更新保证同一令牌只能成功使用一次
+// 参数: tokenHash 令牌摘要
+// 返回: int64 令牌所属用户ID, error 错误信息 (无效时为 ErrResetTokenNotFound)
+func (r *PasswordResetRepository) Consume(tokenHash string) (int64, error) {
+	var id, userID int64
+	err := r.db.QueryRow("SELECT id, user_id FROM password_resets WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		tokenHash, time.Now()).Scan(&id, &userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrResetTokenNotFound
+		}
+		return 0, err
+	}
+
+	result, err := r.db.Exec("UPDATE password_resets SET used_at = NOW() WHERE id = ? AND used_at IS NULL", id)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		// 并发请求已抢先使用
+		return 0, ErrResetTokenNotFound
+	}
+	return userID, nil
+}
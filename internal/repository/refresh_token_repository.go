@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"GoWork_7/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrRefreshTokenNotFound 刷新令牌不存在错误
+	ErrRefreshTokenNotFound = errors.New("REFRESH_TOKEN_NOT_FOUND")
+)
+
+// RefreshTokenRepository 刷新令牌数据访问仓库
+type RefreshTokenRepository struct {
+	db *sql.DB
+}
+
+// NewRefreshTokenRepository 创建刷新令牌仓库实例
+func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// Create 保存新的刷新令牌
+// 参数: t 刷新令牌记录 (TokenHash 为摘要)
+// 返回: int64 新记录ID, error 错误信息
+func (r *RefreshTokenRepository) Create(t *models.RefreshToken) (int64, error) {
+	query := "INSERT INTO refresh_tokens(user_id, org_id, family_id, token_hash, expires_at) VALUES (?,?,?,?,?)"
+	result, err := r.db.Exec(query, t.UserID, t.OrgID, t.FamilyID, t.TokenHash, t.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetByHash 根据令牌摘要获取刷新令牌
+// 参数: tokenHash 令牌摘要
+// 返回: *models.RefreshToken 刷新令牌, error 错误信息
+func (r *RefreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, org_id, family_id, token_hash, expires_at, revoked_at, replaced_by, created_at
+		FROM refresh_tokens WHERE token_hash = ?`
+	t := &models.RefreshToken{}
+	var revokedAt sql.NullTime
+	var replacedBy sql.NullInt64
+
+	err := r.db.QueryRow(query, tokenHash).Scan(&t.ID, &t.UserID, &t.OrgID, &t.FamilyID, &t.TokenHash,
+		&t.ExpiresAt, &revokedAt, &replacedBy, &t.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+	if replacedBy.Valid {
+		t.ReplacedBy = &replacedBy.Int64
+	}
+	return t, nil
+}
+
+// MarkRotated 将令牌标记为已轮换 (仅对尚未撤销的令牌生效)
+// 参数: id 旧令牌ID, replacedBy 新令牌ID
+// 返回: bool 是否标记成功 (false 表示令牌已被并发使用), error 错误信息
+func (r *RefreshTokenRepository) MarkRotated(id, replacedBy int64) (bool, error) {
+	query := "UPDATE refresh_tokens SET revoked_at = ?, replaced_by = ? WHERE id = ? AND revoked_at IS NULL"
+	result, err := r.db.Exec(query, time.Now(), replacedBy, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// RevokeFamily 撤销同一令牌家族中的所有令牌
+// 参数: familyID 令牌家族ID
+// 返回: int64 影响行数, error 错误信息
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) (int64, error) {
+	query := "UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL"
+	result, err := r.db.Exec(query, time.Now(), familyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RevokeByUser 撤销用户的全部刷新令牌
+// 参数: userID 用户ID
+// 返回: int64 影响行数, error 错误信息
+func (r *RefreshTokenRepository) RevokeByUser(userID int64) (int64, error) {
+	query := "UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL"
+	result, err := r.db.Exec(query, time.Now(), userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// RevocationStore 访问令牌撤销列表
+// 支持按 jti 撤销单个令牌，以及按用户撤销某一时间点之前签发的全部令牌
+type RevocationStore interface {
+	// RevokeToken 撤销单个令牌，expiresAt 之后记录可被清理
+	RevokeToken(jti string, expiresAt time.Time) error
+	// RevokeUser 撤销用户在 before 之前签发的全部令牌
+	// 令牌的签发时间 (iat) 只精确到秒，before 按秒截断，撤销所在的整秒内签发的令牌均视为已撤销
+	// (签发方需避开该秒，见 TokenService.AwaitRevocationSecond)
+	RevokeUser(userID int64, before time.Time) error
+	// IsRevoked 判断令牌是否已被撤销
+	IsRevoked(jti string, userID int64, issuedAt time.Time) (bool, error)
+}
+
+// MemoryRevocationStore 基于内存的撤销列表 (单实例部署使用，重启后失效)
+type MemoryRevocationStore struct {
+	mu        sync.RWMutex
+	tokens    map[string]time.Time
+	users     map[int64]time.Time
+	lastPrune time.Time
+}
+
+// NewMemoryRevocationStore 创建内存撤销列表实例
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens:    make(map[string]time.Time),
+		users:     make(map[int64]time.Time),
+		lastPrune: time.Now(),
+	}
+}
+
+// RevokeToken 撤销单个令牌
+func (s *MemoryRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[jti] = expiresAt
+	s.pruneLocked()
+	return nil
+}
+
+// RevokeUser 撤销用户的全部令牌
+func (s *MemoryRevocationStore) RevokeUser(userID int64, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID] = before.Truncate(time.Second)
+	return nil
+}
+
+// IsRevoked 判断令牌是否已被撤销
+func (s *MemoryRevocationStore) IsRevoked(jti string, userID int64, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.tokens[jti]; ok {
+		return true, nil
+	}
+	if before, ok := s.users[userID]; ok && !issuedAt.After(before) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// pruneLocked 定期清理已自然过期的令牌记录 (调用方需持有写锁)
+func (s *MemoryRevocationStore) pruneLocked() {
+	now := time.Now()
+	if now.Sub(s.lastPrune) < time.Minute {
+		return
+	}
+	for jti, exp := range s.tokens {
+		if now.After(exp) {
+			delete(s.tokens, jti)
+		}
+	}
+	s.lastPrune = now
+}
+
+// SQLRevocationStore 基于数据库的撤销列表 (多实例部署共享)
+type SQLRevocationStore struct {
+	db *sql.DB
+}
+
+// NewSQLRevocationStore 创建数据库撤销列表实例
+func NewSQLRevocationStore(db *sql.DB) *SQLRevocationStore {
+	return &SQLRevocationStore{db: db}
+}
+
+// RevokeToken 撤销单个令牌，并顺带清理已过期的记录
+func (s *SQLRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	query := "INSERT INTO revoked_tokens(jti, expires_at) VALUES (?,?) ON DUPLICATE KEY UPDATE expires_at = VALUES(expires_at)"
+	if _, err := s.db.Exec(query, jti, expiresAt); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("DELETE FROM revoked_tokens WHERE expires_at < ?", time.Now())
+	return err
+}
+
+// RevokeUser 撤销用户的全部令牌 (按秒截断后保存，避免 DATETIME 列四舍五入到下一秒)
+func (s *SQLRevocationStore) RevokeUser(userID int64, before time.Time) error {
+	query := "INSERT INTO user_token_revocations(user_id, revoked_before) VALUES (?,?) ON DUPLICATE KEY UPDATE revoked_before = VALUES(revoked_before)"
+	_, err := s.db.Exec(query, userID, before.Truncate(time.Second))
+	return err
+}
+
+// IsRevoked 判断令牌是否已被撤销
+func (s *SQLRevocationStore) IsRevoked(jti string, userID int64, issuedAt time.Time) (bool, error) {
+	var exists int
+	err := s.db.QueryRow("SELECT 1 FROM revoked_tokens WHERE jti = ?", jti).Scan(&exists)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	var before time.Time
+	err = s.db.QueryRow("SELECT revoked_before FROM user_token_revocations WHERE user_id = ?", userID).Scan(&before)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return !issuedAt.After(before), nil
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"GoWork_7/internal/models"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrRoleNotFound 角色不存在错误
+	ErrRoleNotFound = errors.New("ROLE_NOT_FOUND")
+	// ErrPermissionNotFound 权限不存在错误
+	ErrPermissionNotFound = errors.New("PERMISSION_NOT_FOUND")
+)
+
+// RoleRepository 角色与权限数据访问仓库
+type RoleRepository struct {
+	db *sql.DB
+}
+
+// NewRoleRepository 创建角色仓库实例
+func NewRoleRepository(db *sql.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// List 获取全部角色及其权限
+// 返回: []models.Role 角色切片, error 错误信息
+func (r *RoleRepository) List() ([]models.Role, error) {
+	rows, err := r.db.Query("SELECT id, name, description, is_system, mfa_required FROM roles ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.System, &role.MFARequired); err != nil {
+			return nil, err
+		}
+		role.Permissions = []string{}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	grants, err := r.PermissionsByRole()
+	if err != nil {
+		return nil, err
+	}
+	for i := range roles {
+		if perms, ok := grants[roles[i].Name]; ok {
+			roles[i].Permissions = perms
+		}
+	}
+	return roles, nil
+}
+
+// GetByName 根据名称获取角色 (不含权限)
+// 参数: name 角色名
+// 返回: *models.Role 角色对象, error 错误信息
+func (r *RoleRepository) GetByName(name string) (*models.Role, error) {
+	role := &models.Role{}
+	err := r.db.QueryRow("SELECT id, name, description, is_system, mfa_required FROM roles WHERE name = ?", name).
+		Scan(&role.ID, &role.Name, &role.Description, &role.System, &role.MFARequired)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// PermissionsByRole 获取 角色名 -> 权限列表 的映射
+// 返回: map[string][]string 映射, error 错误信息
+func (r *RoleRepository) PermissionsByRole() (map[string][]string, error) {
+	query := `
+		SELECT r.name, p.name
+		FROM role_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		JOIN permissions p ON p.id = rp.permission_id
+		ORDER BY p.name ASC`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := make(map[string][]string)
+	for rows.Next() {
+		var role, perm string
+		if err := rows.Scan(&role, &perm); err != nil {
+			return nil, err
+		}
+		grants[role] = append(grants[role], perm)
+	}
+	return grants, rows.Err()
+}
+
+// ListPermissions 获取全部权限
+// 返回: []models.Permission 权限切片, error 错误信息
+func (r *RoleRepository) ListPermissions() ([]models.Permission, error) {
+	rows, err := r.db.Query("SELECT id, name, description FROM permissions ORDER BY name ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perms []models.Permission
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		perms = append(perms, p)
+	}
+	return perms, rows.Err()
+}
+
+// Create 创建角色并授予权限 (事务)
+// 参数: name 角色名, description 描述, mfaRequired 是否强制二次验证, permissions 权限名列表
+// 返回: int64 新角色ID, error 错误信息
+func (r *RoleRepository) Create(name, description string, mfaRequired bool, permissions []string) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO roles(name, description, mfa_required) VALUES (?,?,?)", name, description, mfaRequired)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := grantPermissions(tx, id, permissions); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// Update 修改角色描述、二次验证要求并整体替换权限 (事务)
+// 参数: name 角色名, description 描述, mfaRequired 是否强制二次验证, permissions 权限名列表
+// 返回: error 错误信息
+func (r *RoleRepository) Update(name, description string, mfaRequired bool, permissions []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	if err := tx.QueryRow("SELECT id FROM roles WHERE name = ? FOR UPDATE", name).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+	if _, err := tx.Exec("UPDATE roles SET description = ?, mfa_required = ? WHERE id = ?", description, mfaRequired, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", id); err != nil {
+		return err
+	}
+	if err := grantPermissions(tx, id, permissions); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete 删除角色
+// 参数: name 角色名
+// 返回: int64 影响行数, error 错误信息
+func (r *RoleRepository) Delete(name string) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM roles WHERE name = ? AND is_system = FALSE", name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CountUsers 统计在任一组织中使用该角色的成员数
+// 参数: name 角色名
+// 返回: int 成员数, error 错误信息
+func (r *RoleRepository) CountUsers(name string) (int, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM org_members WHERE role = ?", name).Scan(&n)
+	return n, err
+}
+
+// grantPermissions 在事务中为角色授予权限，任一权限不存在时返回 ErrPermissionNotFound
+func grantPermissions(tx *sql.Tx, roleID int64, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(permissions)), ",")
+	args := []interface{}{roleID}
+	for _, p := range permissions {
+		args = append(args, p)
+	}
+	result, err := tx.Exec(`
+		INSERT IGNORE INTO role_permissions(role_id, permission_id)
+		SELECT ?, id FROM permissions WHERE name IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(affected) != len(permissions) {
+		return ErrPermissionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"GoWork_7/internal/models"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrTusUploadNotFound 上传不存在
+	ErrTusUploadNotFound = errors.New("TUS_UPLOAD_NOT_FOUND")
+	// ErrTusOffsetConflict 上传偏移量已被其它请求修改 (并发写入同一上传)
+	ErrTusOffsetConflict = errors.New("TUS_OFFSET_CONFLICT")
+	// ErrTusQuotaExceeded 创建后用户占用的空间将超出配额
+	ErrTusQuotaExceeded = errors.New("TUS_QUOTA_EXCEEDED")
+)
+
+// TusUploadPart 已接收的分片
+type TusUploadPart struct {
+	Offset     int64
+	Size       int64
+	StorageKey string
+}
+
+// TusUploadRepository 可续传上传数据访问仓库
+type TusUploadRepository struct {
+	db *sql.DB
+}
+
+// NewTusUploadRepository 创建可续传上传仓库实例
+func NewTusUploadRepository(db *sql.DB) *TusUploadRepository {
+	return &TusUploadRepository{db: db}
+}
+
+// CreateWithinQuota 在用户配额内创建上传记录
+// 锁定用户行后统计已占用空间 (已完成的上传及未过期的未完成上传，按声明的总长度计算)，避免并发创建超出配额
+// 参数: u 上传记录, quota 用户配额 (字节)
+// 返回: error 错误信息 (超出配额为 ErrTusQuotaExceeded)
+func (r *TusUploadRepository) CreateWithinQuota(u *models.TusUpload, quota int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	if err := tx.QueryRow("SELECT id FROM users WHERE id = ? FOR UPDATE", u.UserID).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	var used int64
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(upload_length), 0) FROM tus_uploads
+		WHERE user_id = ? AND (completed_at IS NOT NULL OR expires_at > ?)`, u.UserID, time.Now()).Scan(&used)
+	if err != nil {
+		return err
+	}
+	if used+u.Length > quota {
+		return ErrTusQuotaExceeded
+	}
+	_, err = tx.Exec("INSERT INTO tus_uploads(id, user_id, org_id, upload_length, metadata, expires_at) VALUES (?,?,?,?,?,?)",
+		u.ID, u.UserID, u.OrgID, u.Length, u.Metadata, u.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get 根据ID查询上传记录
+// 返回: *models.TusUpload 上传记录, error 错误信息 (不存在为 ErrTusUploadNotFound)
+func (r *TusUploadRepository) Get(id string) (*models.TusUpload, error) {
+	var u models.TusUpload
+	var key sql.NullString
+	var completedAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, user_id, org_id, upload_length, upload_offset, metadata, storage_key, expires_at, completed_at, created_at
+		FROM tus_uploads WHERE id = ?`, id).
+		Scan(&u.ID, &u.UserID, &u.OrgID, &u.Length, &u.Offset, &u.Metadata, &key, &u.ExpiresAt, &completedAt, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTusUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.StorageKey = key.String
+	if completedAt.Valid {
+		u.CompletedAt = &completedAt.Time
+	}
+	return &u, nil
+}
+
+// AppendPart 登记一个分片并推进偏移量 (仅当当前偏移量仍为 part.Offset 时成功)，同时顺延过期时间
+// 返回: error 错误信息 (偏移量已变化为 ErrTusOffsetConflict)
+func (r *TusUploadRepository) AppendPart(id string, part TusUploadPart, expiresAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE tus_uploads SET upload_offset = upload_offset + ?, expires_at = ?
+		WHERE id = ? AND upload_offset = ? AND completed_at IS NULL AND upload_offset + ? <= upload_length`,
+		part.Size, expiresAt, id, part.Offset, part.Size)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTusOffsetConflict
+	}
+	if _, err := tx.Exec("INSERT INTO tus_upload_parts(upload_id, part_offset, size, storage_key) VALUES (?,?,?,?)",
+		id, part.Offset, part.Size, part.StorageKey); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Parts 按偏移量顺序查询已接收的分片
+func (r *TusUploadRepository) Parts(id string) ([]TusUploadPart, error) {
+	rows, err := r.db.Query("SELECT part_offset, size, storage_key FROM tus_upload_parts WHERE upload_id = ? ORDER BY part_offset", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var parts []TusUploadPart
+	for rows.Next() {
+		var p TusUploadPart
+		if err := rows.Scan(&p.Offset, &p.Size, &p.StorageKey); err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	return parts, rows.Err()
+}
+
+// Complete 标记上传完成并删除分片记录
+// 参数: id 上传ID, storageKey 合并后的文件
+func (r *TusUploadRepository) Complete(id, storageKey string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE tus_uploads SET storage_key = ?, completed_at = NOW() WHERE id = ? AND completed_at IS NULL", storageKey, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTusUploadNotFound
+	}
+	if _, err := tx.Exec("DELETE FROM tus_upload_parts WHERE upload_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete 删除上传记录 (分片记录随外键级联删除)
+func (r *TusUploadRepository) Delete(id string) error {
+	_, err := r.db.Exec("DELETE FROM tus_uploads WHERE id = ?", id)
+	return err
+}
+
+// ExpiredIDs 查询在指定时间之前过期且未完成的上传
+// 参数: now 当前时间, limit 单次最多返回的条数
+func (r *TusUploadRepository) ExpiredIDs(now time.Time, limit int) ([]string, error) {
+	rows, err := r.db.Query("SELECT id FROM tus_uploads WHERE completed_at IS NULL AND expires_at < ? LIMIT ?", now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ExistingIDs 批量查询仍存在的上传ID
+// 返回: map[string]bool 存在的上传ID, error 错误信息
+func (r *TusUploadRepository) ExistingIDs(ids []string) (map[string]bool, error) {
+	const chunk = 500
+	existing := make(map[string]bool)
+	for start := 0; start < len(ids); start += chunk {
+		end := min(start+chunk, len(ids))
+		batch := ids[start:end]
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		rows, err := r.db.Query("SELECT id FROM tus_uploads WHERE id IN (?"+strings.Repeat(",?", len(batch)-1)+")", args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			existing[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
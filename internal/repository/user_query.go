@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"GoWork_7/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidUserSort 排序字段不在白名单中
+	ErrInvalidUserSort = errors.New("INVALID_USER_SORT")
+	// ErrInvalidCursor 分页游标无法解析，或与当前排序方式不一致
+	ErrInvalidCursor = errors.New("INVALID_CURSOR")
+)
+
+// userSortColumns 允许排序的字段及其 SQL 表达式 (白名单：请求中的字段名只用于查表，不会拼接进 SQL)
+// 从未登录的用户 last_login 与列表返回值一致按 1970-01-01 00:00:00 参与排序，保证游标比较时不出现 NULL
+var userSortColumns = map[string]string{
+	"id":         "u.id",
+	"username":   "u.username",
+	"last_login": "COALESCE(u.last_login, " + neverLoggedIn + ")",
+	"created_at": "u.created_at",
+	"role":       "m.role",
+}
+
+// neverLoggedIn 从未登录的用户参与排序的 last_login (DATETIME 字面量，与列类型一致按时间比较)
+const neverLoggedIn = "TIMESTAMP '1970-01-01 00:00:00'"
+
+// lastLoginLayout 接口返回的 last_login 及游标中时间值的格式 (本地时间)
+const lastLoginLayout = "2006-01-02 15:04:05"
+
+// userTimeSorts 按时间排序的字段：游标中的值解析为 time.Time 后再绑定
+var userTimeSorts = map[string]bool{"last_login": true, "created_at": true}
+
+// ValidUserSort 判断排序字段是否在白名单中 (空字符串表示默认按 id 排序)
+func ValidUserSort(sort string) bool {
+	if sort == "" {
+		return true
+	}
+	_, ok := userSortColumns[sort]
+	return ok
+}
+
+// userQuery 用户列表查询条件构造器：条件均为固定 SQL 片段，取值一律通过占位符传入
+type userQuery struct {
+	conds []string
+	args  []interface{}
+}
+
+// where 追加一个条件
+func (q *userQuery) where(cond string, args ...interface{}) {
+	q.conds = append(q.conds, cond)
+	q.args = append(q.args, args...)
+}
+
+// clause 生成 WHERE 子句
+func (q *userQuery) clause() string {
+	return "WHERE " + strings.Join(q.conds, " AND ")
+}
+
+// newUserQuery 按筛选条件构造查询 (组织、未删除、关键字、状态、角色、最后登录与注册时间范围)
+func newUserQuery(f models.UserListFilter) *userQuery {
+	q := &userQuery{}
+	q.where("m.org_id = ?", f.OrgID)
+	q.where("u.deleted_at IS NULL")
+
+	if f.Keyword != "" {
+		q.where("(u.username LIKE ? OR u.email LIKE ?)", "%"+f.Keyword+"%", "%"+strings.ToLower(f.Keyword)+"%")
+	}
+	if f.Status != "" {
+		// 1 启用, 2 待验证邮箱, 其余为禁用
+		statusValue := models.UserStatusDisabled
+		switch f.Status {
+		case "1":
+			statusValue = models.UserStatusEnabled
+		case "2":
+			statusValue = models.UserStatusPending
+		}
+		q.where("u.status = ?", statusValue)
+	}
+	if f.Role != "" {
+		q.where("m.role = ?", f.Role)
+	}
+	if !f.LastLoginFrom.IsZero() {
+		q.where("u.last_login >= ?", f.LastLoginFrom)
+	}
+	if !f.LastLoginTo.IsZero() {
+		q.where("u.last_login < ?", f.LastLoginTo)
+	}
+	if !f.CreatedFrom.IsZero() {
+		q.where("u.created_at >= ?", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		q.where("u.created_at < ?", f.CreatedTo)
+	}
+	return q
+}
+
+// userOrderBy 生成排序子句，以 id 作为次要排序保证顺序稳定
+func userOrderBy(f models.UserListFilter) (string, error) {
+	column, dir, err := sortColumn(f)
+	if err != nil {
+		return "", err
+	}
+	if column == "u.id" {
+		return " ORDER BY u.id " + dir, nil
+	}
+	return " ORDER BY " + column + " " + dir + ", u.id " + dir, nil
+}
+
+// after 追加游标条件：只取排在游标位置之后的行
+func (q *userQuery) after(f models.UserListFilter) error {
+	if f.Cursor == "" {
+		return nil
+	}
+	c, err := decodeUserCursor(f.Cursor)
+	if err != nil {
+		return err
+	}
+	sort := f.Sort
+	if sort == "" {
+		sort = "id"
+	}
+	if c.Sort != sort || c.Desc != f.Desc {
+		return ErrInvalidCursor
+	}
+
+	column, _, err := sortColumn(f)
+	if err != nil {
+		return err
+	}
+	op := ">"
+	if f.Desc {
+		op = "<"
+	}
+	if column == "u.id" {
+		q.where("u.id "+op+" ?", c.ID)
+		return nil
+	}
+	var value interface{} = c.Value
+	if userTimeSorts[sort] {
+		t, err := time.ParseInLocation(lastLoginLayout, c.Value, time.Local)
+		if err != nil {
+			return ErrInvalidCursor
+		}
+		value = t
+	}
+	q.where("("+column+" "+op+" ? OR ("+column+" = ? AND u.id "+op+" ?))", value, value, c.ID)
+	return nil
+}
+
+// sortColumn 从白名单中取排序表达式与方向
+func sortColumn(f models.UserListFilter) (string, string, error) {
+	sort := f.Sort
+	if sort == "" {
+		sort = "id"
+	}
+	column, ok := userSortColumns[sort]
+	if !ok {
+		return "", "", ErrInvalidUserSort
+	}
+	if f.Desc {
+		return column, "DESC", nil
+	}
+	return column, "ASC", nil
+}
+
+// userCursor 游标内容：排序方式及上一页最后一行的排序值与ID
+type userCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+// encodeUserCursor 以用户列表中的最后一行生成下一页游标
+func encodeUserCursor(f models.UserListFilter, last *models.User) string {
+	c := userCursor{Sort: f.Sort, Desc: f.Desc, ID: last.ID}
+	switch f.Sort {
+	case "", "id":
+		c.Sort = "id"
+	case "username":
+		c.Value = last.Username
+	case "last_login":
+		c.Value = last.LastLogin
+	case "created_at":
+		if last.CreatedAt != nil {
+			c.Value = last.CreatedAt.In(time.Local).Format(lastLoginLayout)
+		}
+	case "role":
+		c.Value = last.Role
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeUserCursor 解析游标
+func decodeUserCursor(s string) (*userCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c userCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	if _, ok := userSortColumns[c.Sort]; !ok {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
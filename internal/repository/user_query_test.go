@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"GoWork_7/internal/models"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUserCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 8, 30, 15, 0, time.Local)
+	last := &models.User{ID: 42, Username: "alice", LastLogin: "2024-05-06 07:08:09", Role: "admin", CreatedAt: &created}
+	lastLogin := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+
+	tests := []struct {
+		sort string
+		cond string
+		args []interface{}
+	}{
+		{"", "u.id > ?", []interface{}{int64(42)}},
+		{"id", "u.id > ?", []interface{}{int64(42)}},
+		{"username", "(u.username > ? OR (u.username = ? AND u.id > ?))", []interface{}{"alice", "alice", int64(42)}},
+		{"role", "(m.role > ? OR (m.role = ? AND u.id > ?))", []interface{}{"admin", "admin", int64(42)}},
+		{"last_login", "(" + userSortColumns["last_login"] + " > ? OR (" + userSortColumns["last_login"] + " = ? AND u.id > ?))",
+			[]interface{}{lastLogin, lastLogin, int64(42)}},
+		{"created_at", "(u.created_at > ? OR (u.created_at = ? AND u.id > ?))", []interface{}{created, created, int64(42)}},
+	}
+	for _, tt := range tests {
+		f := models.UserListFilter{Sort: tt.sort}
+		f.Cursor = encodeUserCursor(f, last)
+
+		q := &userQuery{}
+		if err := q.after(f); err != nil {
+			t.Fatalf("sort=%q: after 返回错误 %v", tt.sort, err)
+		}
+		if len(q.conds) != 1 || q.conds[0] != tt.cond {
+			t.Errorf("sort=%q: 条件 = %v, 期望 %q", tt.sort, q.conds, tt.cond)
+		}
+		if !reflect.DeepEqual(q.args, tt.args) {
+			t.Errorf("sort=%q: 参数 = %v, 期望 %v", tt.sort, q.args, tt.args)
+		}
+	}
+}
+
+func TestUserCursorDescending(t *testing.T) {
+	f := models.UserListFilter{Sort: "username", Desc: true}
+	f.Cursor = encodeUserCursor(f, &models.User{ID: 7, Username: "bob"})
+
+	q := &userQuery{}
+	if err := q.after(f); err != nil {
+		t.Fatalf("after 返回错误 %v", err)
+	}
+	if want := "(u.username < ? OR (u.username = ? AND u.id < ?))"; len(q.conds) != 1 || q.conds[0] != want {
+		t.Errorf("条件 = %v, 期望 %q", q.conds, want)
+	}
+}
+
+func TestUserCursorMismatch(t *testing.T) {
+	last := &models.User{ID: 7, Username: "bob", Role: "user"}
+	tests := []struct {
+		name     string
+		encodeAs models.UserListFilter
+		query    models.UserListFilter
+	}{
+		{"排序字段不同", models.UserListFilter{Sort: "username"}, models.UserListFilter{Sort: "role"}},
+		{"默认排序与其它字段", models.UserListFilter{}, models.UserListFilter{Sort: "username"}},
+		{"正序游标用于倒序", models.UserListFilter{Sort: "username"}, models.UserListFilter{Sort: "username", Desc: true}},
+		{"倒序游标用于正序", models.UserListFilter{Desc: true}, models.UserListFilter{}},
+	}
+	for _, tt := range tests {
+		f := tt.query
+		f.Cursor = encodeUserCursor(tt.encodeAs, last)
+		q := &userQuery{}
+		if err := q.after(f); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: 错误 = %v, 期望 ErrInvalidCursor", tt.name, err)
+		}
+		if len(q.conds) != 0 {
+			t.Errorf("%s: 不应追加条件 %v", tt.name, q.conds)
+		}
+	}
+}
+
+func TestUserCursorMalformed(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := map[string]models.UserListFilter{
+		"非 base64":      {Cursor: "!!!"},
+		"非 JSON":        {Cursor: raw("not json")},
+		"缺少 ID":         {Cursor: raw(`{"s":"id"}`)},
+		"未知排序字段":        {Cursor: raw(`{"s":"password","id":1}`)},
+		"时间值格式错误":       {Sort: "last_login", Cursor: raw(`{"s":"last_login","v":"yesterday","id":1}`)},
+		"时间值为 Unix 时间戳": {Sort: "created_at", Cursor: raw(`{"s":"created_at","v":"1700000000","id":1}`)},
+	}
+	for name, f := range tests {
+		q := &userQuery{}
+		if err := q.after(f); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: 错误 = %v, 期望 ErrInvalidCursor", name, err)
+		}
+	}
+}
@@ -4,11 +4,20 @@ import (
 	"GoWork_7/internal/models"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
+// mysqlDuplicateEntry MySQL 唯一约束冲突错误码
+const mysqlDuplicateEntry = 1062
+
 var (
 	// ErrUserNotFound 用户不存在错误
 	ErrUserNotFound = errors.New("USER_NOT_FOUND")
+	// ErrUserExists 用户名或邮箱已被占用 (违反唯一约束)
+	ErrUserExists = errors.New("USER_EXISTS")
 )
 
 // UserRepository 用户数据访问仓库
@@ -21,28 +30,195 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-// Create 创建新用户
-// 参数: username 用户名, password 密码
+// Create 创建新用户并加入指定组织
+// 参数: orgID 组织ID, user 用户对象 (Password 须为服务层生成的哈希, Email 为空表示未绑定, Status 为空时为 enabled, Role 为组织内角色)
 // 返回: int64 新用户ID, error 错误信息
-func (r *UserRepository) Create(username, password string) (int64, error) {
-	query := "INSERT INTO users(username,password) VALUES (?,?)"
-	result, err := r.db.Exec(query, username, password)
+func (r *UserRepository) Create(orgID int64, user *models.User) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertUser(tx, orgID, user)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// CreateWithAvatarUpload 创建新用户并在同一事务中认领一条临时头像上传作为该用户的头像 (user.Avatar)
+// 参数: orgID 组织ID, user 用户对象 (同 Create), uploadedBy 操作者ID (须为头像的上传者), notBefore 仅认领该时间之后上传的头像
+// 返回: int64 新用户ID, error 错误信息 (临时头像不可用时为 ErrAvatarUploadNotFound，用户不会被创建)
+func (r *UserRepository) CreateWithAvatarUpload(orgID int64, user *models.User, uploadedBy int64, notBefore time.Time) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := claimAvatarUpload(tx, orgID, uploadedBy, user.Avatar, notBefore); err != nil {
+		return 0, err
+	}
+	id, err := insertUser(tx, orgID, user)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// CreateBatch 在同一事务中批量创建用户并加入指定组织，任一失败则全部回滚
+// 参数: orgID 组织ID, users 用户对象 (要求同 Create；成功后回填 ID)
+// 返回: error 错误信息 (用户名或邮箱已被占用时为 ErrUserExists)
+func (r *UserRepository) CreateBatch(orgID int64, users []*models.User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, u := range users {
+		if u.ID, err = insertUser(tx, orgID, u); err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+				return ErrUserExists
+			}
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		for _, u := range users {
+			u.ID = 0
+		}
+		return err
+	}
+	return nil
+}
+
+// insertUser 在事务中插入用户及其组织成员关系
+func insertUser(tx *sql.Tx, orgID int64, user *models.User) (int64, error) {
+	status := user.Status
+	if status == "" {
+		status = models.UserStatusEnabled
+	}
+	result, err := tx.Exec("INSERT INTO users(username, email, password, password_changed_at, status, avatar) VALUES (?,?,?,NOW(),?,?)",
+		user.Username, nullableEmail(user.Email), user.Password, status, sql.NullString{String: user.Avatar, Valid: user.Avatar != ""})
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	if _, err := tx.Exec("INSERT INTO org_members(org_id, user_id, role) VALUES (?,?,?)", orgID, id, user.Role); err != nil {
+		return 0, err
+	}
+	return id, nil
 }
 
-// GetByUsernameAndPassword 根据用户名和密码获取用户
-// 参数: username 用户名, password 密码
+// UsernamesTaken 批量查询已被占用的用户名 (含回收站中的账号，不区分大小写)
+// 参数: usernames 待检查的用户名
+// 返回: map[string]bool 已被占用的用户名 (小写), error 错误信息
+func (r *UserRepository) UsernamesTaken(usernames []string) (map[string]bool, error) {
+	return r.takenValues("username", usernames)
+}
+
+// EmailsTaken 批量查询已被占用的邮箱 (含回收站中的账号)
+// 参数: emails 待检查的邮箱 (小写)
+// 返回: map[string]bool 已被占用的邮箱 (小写), error 错误信息
+func (r *UserRepository) EmailsTaken(emails []string) (map[string]bool, error) {
+	return r.takenValues("email", emails)
+}
+
+// takenValues 分批查询指定唯一列中已存在的值 (column 仅限内部传入的列名)
+func (r *UserRepository) takenValues(column string, values []string) (map[string]bool, error) {
+	const chunk = 500
+	taken := make(map[string]bool)
+	for start := 0; start < len(values); start += chunk {
+		end := min(start+chunk, len(values))
+		batch := values[start:end]
+		args := make([]interface{}, len(batch))
+		for i, v := range batch {
+			args[i] = v
+		}
+		query := "SELECT " + column + " FROM users WHERE " + column + " IN (?" + strings.Repeat(",?", len(batch)-1) + ")"
+		rows, err := r.db.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var v string
+			if err := rows.Scan(&v); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			taken[strings.ToLower(v)] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return taken, nil
+}
+
+// GetByUsername 根据用户名获取用户 (含密码哈希；不含组织角色，回收站中的用户视为不存在)
+// 参数: username 用户名
 // 返回: *models.User 用户对象, error 错误信息
-func (r *UserRepository) GetByUsernameAndPassword(username, password string) (*models.User, error) {
-	query := "SELECT id, username, password, role, status, avatar FROM users WHERE username = ? AND password = ?"
+func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
+	return r.getOne("SELECT "+userColumns+" FROM users WHERE username = ? AND deleted_at IS NULL", username)
+}
+
+// GetByLogin 根据用户名或邮箱获取用户 (含密码哈希，用于登录校验；不含组织角色，回收站中的用户视为不存在)
+// 历史账号的用户名与另一账号的邮箱相同时优先匹配邮箱 (新用户名不能包含 @)，避免形如邮箱的用户名抢占他人的邮箱登录
+// 参数: login 用户名或邮箱
+// 返回: *models.User 用户对象, error 错误信息
+func (r *UserRepository) GetByLogin(login string) (*models.User, error) {
+	email := strings.ToLower(login)
+	query := "SELECT " + userColumns + " FROM users WHERE (username = ? OR email = ?) AND deleted_at IS NULL ORDER BY email <=> ? DESC LIMIT 1"
+	return r.getOne(query, login, email, email)
+}
+
+// GetByID 根据用户ID获取用户 (不限组织，不含组织角色，回收站中的用户视为不存在)
+// 参数: id 用户ID
+// 返回: *models.User 用户对象, error 错误信息
+func (r *UserRepository) GetByID(id int64) (*models.User, error) {
+	return r.getOne("SELECT "+userColumns+" FROM users WHERE id = ? AND deleted_at IS NULL", id)
+}
+
+// UsernameByID 根据用户ID获取用户名 (不限组织，用于生成默认头像)
+// 参数: id 用户ID
+// 返回: string 用户名, error 错误信息 (用户不存在或已在回收站中为 ErrUserNotFound)
+func (r *UserRepository) UsernameByID(id int64) (string, error) {
+	var username string
+	err := r.db.QueryRow("SELECT username FROM users WHERE id = ? AND deleted_at IS NULL", id).Scan(&username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	return username, err
+}
+
+// EmailTaken 判断邮箱是否已被其他账号使用 (回收站中的账号仍占用其邮箱，以便恢复)
+// 参数: email 邮箱 (小写), excludeID 排除的用户ID (0 表示不排除)
+// 返回: bool 是否已被使用, error 错误信息
+func (r *UserRepository) EmailTaken(email string, excludeID int64) (bool, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM users WHERE email = ? AND id <> ?", email, excludeID).Scan(&n)
+	return n > 0, err
+}
+
+// GetByIDInOrg 获取指定组织内的用户 (含组织内角色)
+// 参数: orgID 组织ID, id 用户ID
+// 返回: *models.User 用户对象 (用户不属于该组织或已在回收站中时返回 ErrUserNotFound), error 错误信息
+func (r *UserRepository) GetByIDInOrg(orgID, id int64) (*models.User, error) {
+	query := `
+		SELECT u.id, u.username, u.email, u.email_verified_at, u.password, u.password_changed_at, m.role, m.org_id, u.status, u.avatar
+		FROM users u JOIN org_members m ON m.user_id = u.id
+		WHERE m.org_id = ? AND u.id = ? AND u.deleted_at IS NULL`
 	u := &models.User{}
-	var statusStr string
-	var avatar sql.NullString
+	var f userNullFields
 
-	err := r.db.QueryRow(query, username, password).Scan(&u.ID, &u.Username, &u.Password, &u.Role, &statusStr, &avatar)
+	err := r.db.QueryRow(query, orgID, id).Scan(&u.ID, &u.Username, &f.email, &f.verifiedAt, &u.Password, &f.passwordChangedAt, &u.Role, &u.OrgID, &u.Status, &f.avatar)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -50,142 +226,607 @@ func (r *UserRepository) GetByUsernameAndPassword(username, password string) (*m
 		return nil, err
 	}
 
-	r.mapUserStatus(u, statusStr, avatar)
+	r.mapUserStatus(u, f)
 	return u, nil
 }
 
-// GetByID 根据用户ID获取用户
-// 参数: id 用户ID
-// 返回: *models.User 用户对象, error 错误信息
-func (r *UserRepository) GetByID(id int64) (*models.User, error) {
-	query := "SELECT id, username, password, role, status, avatar FROM users WHERE id = ?"
+// Delete 将用户移出指定组织；该组织是用户最后一个组织时改为将账号移入回收站 (软删除，保留组织成员关系以便恢复)
+// 参数: orgID 组织ID, id 用户ID, deletedBy 操作者ID
+// 返回: int64 影响行数 (用户不属于该组织或已在回收站中时为 0), bool 是否移入回收站, error 错误信息
+func (r *UserRepository) Delete(orgID, id, deletedBy int64) (int64, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	affected, trashed, err := deleteInTx(tx, orgID, id, deletedBy)
+	if err != nil || affected == 0 {
+		return 0, false, err
+	}
+	return affected, trashed, tx.Commit()
+}
+
+// deleteInTx 在事务中执行 Delete 的移出组织/移入回收站逻辑
+func deleteInTx(tx *sql.Tx, orgID, id, deletedBy int64) (int64, bool, error) {
+	exists, err := lockMember(tx, orgID, id)
+	if err != nil || !exists {
+		return 0, false, err
+	}
+
+	var memberships int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM org_members WHERE user_id = ?", id).Scan(&memberships); err != nil {
+		return 0, false, err
+	}
+
+	var result sql.Result
+	trashed := memberships <= 1
+	if trashed {
+		result, err = tx.Exec("UPDATE users SET deleted_at = NOW(), deleted_by = ? WHERE id = ? AND deleted_at IS NULL", deletedBy, id)
+	} else {
+		result, err = tx.Exec("DELETE FROM org_members WHERE org_id = ? AND user_id = ?", orgID, id)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected, trashed && affected > 0, err
+}
+
+// lockMember 在事务中锁定组织成员关系，确认用户属于该组织且不在回收站中
+// 返回: bool 是否存在, error 错误信息
+func lockMember(tx *sql.Tx, orgID, id int64) (bool, error) {
+	var exists int
+	err := tx.QueryRow(`
+		SELECT 1 FROM org_members m JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = ? AND m.user_id = ? AND u.deleted_at IS NULL FOR UPDATE`, orgID, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// UserBulkChange 批量操作中对单个用户的变更 (仅非空字段生效)
+type UserBulkChange struct {
+	ID           int64
+	Status       string // 新状态
+	Role         string // 新的组织内角色
+	PasswordHash string // 新密码哈希 (须为服务层生成的哈希)
+	Delete       bool   // 移出组织，最后一个组织时移入回收站
+
+	Applied bool // 结果：是否已应用 (事务中发现用户已不在该组织内时为 false)
+	Trashed bool // 结果：是否已移入回收站
+}
+
+// BulkApply 在同一事务中对指定组织内的多个用户应用变更，任一语句失败时全部回滚
+// 参数: orgID 组织ID, deletedBy 操作者ID (用于软删除), changes 变更列表 (执行后回写 Applied/Trashed)
+// 返回: error 错误信息
+func (r *UserRepository) BulkApply(orgID, deletedBy int64, changes []*UserBulkChange) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, c := range changes {
+		c.Applied, c.Trashed = false, false
+		if c.Delete {
+			affected, trashed, err := deleteInTx(tx, orgID, c.ID, deletedBy)
+			if err != nil {
+				return err
+			}
+			c.Applied, c.Trashed = affected > 0, trashed
+			continue
+		}
+
+		exists, err := lockMember(tx, orgID, c.ID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		if c.Status != "" {
+			if _, err := tx.Exec("UPDATE users SET status = ? WHERE id = ?", c.Status, c.ID); err != nil {
+				return err
+			}
+		}
+		if c.Role != "" {
+			if _, err := tx.Exec("UPDATE org_members SET role = ? WHERE org_id = ? AND user_id = ?", c.Role, orgID, c.ID); err != nil {
+				return err
+			}
+		}
+		if c.PasswordHash != "" {
+			if _, err := tx.Exec("UPDATE users SET password = ?, password_changed_at = NOW() WHERE id = ?", c.PasswordHash, c.ID); err != nil {
+				return err
+			}
+		}
+		c.Applied = true
+	}
+	return tx.Commit()
+}
+
+// GetManyInOrg 批量获取指定组织内的用户 (含组织内角色，不含回收站中的用户)
+// 参数: orgID 组织ID, ids 用户ID列表
+// 返回: map[int64]*models.User 按ID索引的用户, error 错误信息
+func (r *UserRepository) GetManyInOrg(orgID int64, ids []int64) (map[int64]*models.User, error) {
+	users := make(map[int64]*models.User, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+	args := []interface{}{orgID}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := `
+		SELECT u.id, u.username, u.email, u.email_verified_at, u.password, u.password_changed_at, m.role, m.org_id, u.status, u.avatar
+		FROM users u JOIN org_members m ON m.user_id = u.id
+		WHERE m.org_id = ? AND u.deleted_at IS NULL AND u.id IN (?` + strings.Repeat(",?", len(ids)-1) + ")"
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u := &models.User{}
+		var f userNullFields
+		if err := rows.Scan(&u.ID, &u.Username, &f.email, &f.verifiedAt, &u.Password, &f.passwordChangedAt, &u.Role, &u.OrgID, &u.Status, &f.avatar); err != nil {
+			return nil, err
+		}
+		r.mapUserStatus(u, f)
+		users[u.ID] = u
+	}
+	return users, rows.Err()
+}
+
+// SharedUsers 找出同时属于多个组织的用户
+// 参数: ids 用户ID列表
+// 返回: map[int64]bool 属于多个组织的用户ID集合, error 错误信息
+func (r *UserRepository) SharedUsers(ids []int64) (map[int64]bool, error) {
+	shared := make(map[int64]bool)
+	if len(ids) == 0 {
+		return shared, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := "SELECT user_id FROM org_members WHERE user_id IN (?" + strings.Repeat(",?", len(ids)-1) + ") GROUP BY user_id HAVING COUNT(*) > 1"
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		shared[id] = true
+	}
+	return shared, rows.Err()
+}
+
+// ListIDs 按与用户列表相同的筛选条件获取组织内的用户ID (按ID升序，忽略排序与分页参数)
+// 参数: f 筛选条件, limit 最多返回条数
+// 返回: []int64 用户ID列表, error 错误信息
+func (r *UserRepository) ListIDs(f models.UserListFilter, limit int) ([]int64, error) {
+	q := newUserQuery(f)
+	query := "SELECT u.id FROM users u JOIN org_members m ON m.user_id = u.id " + q.clause() + " ORDER BY u.id ASC LIMIT ?"
+	rows, err := r.db.Query(query, append(q.args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// FetchDeleted 分页获取指定组织回收站中的用户 (按删除时间倒序)
+// 参数: orgID 组织ID, page 页码, limit 每页条数
+// 返回: []models.User 用户切片, int 总记录数, error 错误信息
+func (r *UserRepository) FetchDeleted(orgID int64, page, limit int) ([]models.User, int, error) {
+	var total int
+	countQuery := "SELECT COUNT(*) FROM users u JOIN org_members m ON m.user_id = u.id WHERE m.org_id = ? AND u.deleted_at IS NOT NULL"
+	if err := r.db.QueryRow(countQuery, orgID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT u.id, u.username, u.email, u.email_verified_at, m.role, u.status, u.avatar, u.deleted_at, COALESCE(u.deleted_by, 0)
+		FROM users u JOIN org_members m ON m.user_id = u.id
+		WHERE m.org_id = ? AND u.deleted_at IS NOT NULL
+		ORDER BY u.deleted_at DESC, u.id DESC
+		LIMIT ? OFFSET ?`
+	rows, err := r.db.Query(query, orgID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		var f userNullFields
+		var deletedAt time.Time
+		if err := rows.Scan(&u.ID, &u.Username, &f.email, &f.verifiedAt, &u.Role, &u.Status, &f.avatar, &deletedAt, &u.DeletedBy); err != nil {
+			return nil, 0, err
+		}
+		r.mapUserStatus(&u, f)
+		u.OrgID = orgID
+		u.DeletedAt = &deletedAt
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+// GetDeletedInOrg 获取指定组织回收站中的用户
+// 参数: orgID 组织ID, id 用户ID
+// 返回: *models.User 用户对象 (不在该组织的回收站中时返回 ErrUserNotFound), error 错误信息
+func (r *UserRepository) GetDeletedInOrg(orgID, id int64) (*models.User, error) {
+	query := `
+		SELECT u.id, u.username, u.email, u.email_verified_at, m.role, u.status, u.avatar, u.deleted_at, COALESCE(u.deleted_by, 0)
+		FROM users u JOIN org_members m ON m.user_id = u.id
+		WHERE m.org_id = ? AND u.id = ? AND u.deleted_at IS NOT NULL`
 	u := &models.User{}
-	var statusStr string
-	var avatar sql.NullString
+	var f userNullFields
+	var deletedAt time.Time
 
-	err := r.db.QueryRow(query, id).Scan(&u.ID, &u.Username, &u.Password, &u.Role, &statusStr, &avatar)
+	err := r.db.QueryRow(query, orgID, id).Scan(&u.ID, &u.Username, &f.email, &f.verifiedAt, &u.Role, &u.Status, &f.avatar, &deletedAt, &u.DeletedBy)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
-
-	r.mapUserStatus(u, statusStr, avatar)
+	r.mapUserStatus(u, f)
+	u.OrgID = orgID
+	u.DeletedAt = &deletedAt
 	return u, nil
 }
 
-// Delete 根据用户ID删除用户
-// 参数: id 用户ID
-// 返回: int64 影响行数, error 错误信息
-func (r *UserRepository) Delete(id int64) (int64, error) {
-	query := "DELETE FROM users WHERE id = ?"
-	result, err := r.db.Exec(query, id)
+// Restore 将指定组织回收站中的用户恢复为正常账号
+// 参数: orgID 组织ID, id 用户ID
+// 返回: int64 影响行数 (不在该组织的回收站中时为 0), error 错误信息
+func (r *UserRepository) Restore(orgID, id int64) (int64, error) {
+	query := `
+		UPDATE users u JOIN org_members m ON m.user_id = u.id
+		SET u.deleted_at = NULL, u.deleted_by = NULL
+		WHERE m.org_id = ? AND u.id = ? AND u.deleted_at IS NOT NULL`
+	result, err := r.db.Exec(query, orgID, id)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected()
 }
 
-// FetchWithPagination 分页获取用户列表
-// 参数: page 页码, limit 每页条数, keyword 关键词, status 状态筛选
-// 返回: []models.User 用户切片, int 总记录数, error 错误信息
-func (r *UserRepository) FetchWithPagination(page, limit int, keyword, status string) ([]models.User, int, error) {
-	whereClause := ""
-	var args []interface{}
+// Purge 彻底删除指定组织回收站中的用户 (关联的令牌、二次验证、历史密码等随外键级联删除)
+// 参数: orgID 组织ID, id 用户ID
+// 返回: int64 影响行数 (不在该组织的回收站中时为 0), error 错误信息
+func (r *UserRepository) Purge(orgID, id int64) (int64, error) {
+	query := `
+		DELETE u FROM users u JOIN org_members m ON m.user_id = u.id
+		WHERE m.org_id = ? AND u.id = ? AND u.deleted_at IS NOT NULL`
+	result, err := r.db.Exec(query, orgID, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
 
-	if keyword != "" {
-		whereClause += " AND username LIKE ?"
-		args = append(args, "%"+keyword+"%")
+// PurgeDeletedBefore 彻底删除在指定时间之前移入回收站的全部用户 (不限组织)
+// 参数: cutoff 截止时间
+// 返回: []models.User 被删除的用户 (含 ID、用户名及所属组织，用于审计), error 错误信息
+func (r *UserRepository) PurgeDeletedBefore(cutoff time.Time) ([]models.User, error) {
+	query := `
+		SELECT u.id, u.username, COALESCE(MIN(m.org_id), 0)
+		FROM users u LEFT JOIN org_members m ON m.user_id = u.id
+		WHERE u.deleted_at IS NOT NULL AND u.deleted_at < ?
+		GROUP BY u.id, u.username`
+	rows, err := r.db.Query(query, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	var expired []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.OrgID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		expired = append(expired, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	if status != "" {
-		statusValue := "disabled"
-		if status == "1" {
-			statusValue = "enabled"
+	// 逐个删除并再次确认仍在回收站中，避免与并发的恢复操作冲突
+	purged := make([]models.User, 0, len(expired))
+	for _, u := range expired {
+		result, err := r.db.Exec("DELETE FROM users WHERE id = ? AND deleted_at IS NOT NULL AND deleted_at < ?", u.ID, cutoff)
+		if err != nil {
+			return purged, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			purged = append(purged, u)
 		}
-		whereClause += " AND status = ?"
-		args = append(args, statusValue)
 	}
+	return purged, nil
+}
 
-	if whereClause != "" {
-		whereClause = "WHERE " + whereClause[5:]
+// FetchWithPagination 分页获取指定组织内的用户列表 (不含回收站中的用户)
+// 默认按页码分页并统计总数；f.UseCursor 为 true 时按排序字段做游标分页，不统计总数
+// 参数: f 筛选、排序与分页条件 (排序字段须在白名单中)
+// 返回: []models.User 用户切片, int 总记录数 (游标分页时为 -1), string 下一页游标 (没有更多数据时为空), error 错误信息
+func (r *UserRepository) FetchWithPagination(f models.UserListFilter) ([]models.User, int, string, error) {
+	q := newUserQuery(f)
+	orderBy, err := userOrderBy(f)
+	if err != nil {
+		return nil, 0, "", err
 	}
 
-	var total int
-	countQuery := "SELECT COUNT(*) FROM users " + whereClause
-	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
-		return nil, 0, err
+	total := -1
+	if f.UseCursor {
+		if err := q.after(f); err != nil {
+			return nil, 0, "", err
+		}
+	} else {
+		countQuery := "SELECT COUNT(*) FROM users u JOIN org_members m ON m.user_id = u.id " + q.clause()
+		if err := r.db.QueryRow(countQuery, q.args...).Scan(&total); err != nil {
+			return nil, 0, "", err
+		}
 	}
 
-	offset := (page - 1) * limit
+	// 多取一行用于判断是否还有下一页
 	query := `
-		SELECT id, username, role, COALESCE(last_login, '1970-01-01 00:00:00'), status, avatar 
-		FROM users 
-		` + whereClause + `
-		ORDER BY id ASC 
+		SELECT u.id, u.username, u.email, u.email_verified_at, m.role, COALESCE(u.last_login, ` + neverLoggedIn + `), u.status, u.avatar, u.created_at
+		FROM users u JOIN org_members m ON m.user_id = u.id
+		` + q.clause() + orderBy + `
 		LIMIT ? OFFSET ?`
-
-	queryArgs := append(args, limit, offset)
-	rows, err := r.db.Query(query, queryArgs...)
+	offset := 0
+	if !f.UseCursor {
+		offset = (f.Page - 1) * f.Limit
+	}
+	rows, err := r.db.Query(query, append(q.args, f.Limit+1, offset)...)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, "", err
 	}
 	defer rows.Close()
 
 	var users []models.User
 	for rows.Next() {
 		var u models.User
-		var statusStr string
-		var avatar sql.NullString
-		if err := rows.Scan(&u.ID, &u.Username, &u.Role, &u.LastLogin, &statusStr, &avatar); err != nil {
-			continue
+		var nf userNullFields
+		var lastLogin, createdAt time.Time
+		if err := rows.Scan(&u.ID, &u.Username, &nf.email, &nf.verifiedAt, &u.Role, &lastLogin, &u.Status, &nf.avatar, &createdAt); err != nil {
+			return nil, 0, "", err
 		}
-		r.mapUserStatus(&u, statusStr, avatar)
+		r.mapUserStatus(&u, nf)
+		u.LastLogin = lastLogin.In(time.Local).Format(lastLoginLayout)
+		u.OrgID = f.OrgID
+		u.CreatedAt = &createdAt
 		users = append(users, u)
 	}
-	return users, total, nil
+	if err := rows.Err(); err != nil {
+		return nil, 0, "", err
+	}
+
+	var next string
+	if len(users) > f.Limit {
+		users = users[:f.Limit]
+		next = encodeUserCursor(f, &users[len(users)-1])
+	}
+	return users, total, next, nil
 }
 
-// Update 更新用户信息
-// 参数: user 用户对象
+// StreamUsers 按与用户列表相同的筛选与排序条件逐行读取组织内的全部用户 (不分页)
+// 结果集由数据库游标逐行读取，不在内存中缓存；fn 返回错误时停止读取并返回该错误
+// 参数: f 筛选与排序条件 (忽略分页参数), fn 逐行回调
 // 返回: error 错误信息
-func (r *UserRepository) Update(user *models.User) error {
-	var status string
+func (r *UserRepository) StreamUsers(f models.UserListFilter, fn func(u *models.User) error) error {
+	q := newUserQuery(f)
+	orderBy, err := userOrderBy(f)
+	if err != nil {
+		return err
+	}
+	query := `
+		SELECT u.id, u.username, u.email, u.email_verified_at, m.role, u.last_login, u.status
+		FROM users u JOIN org_members m ON m.user_id = u.id
+		` + q.clause() + orderBy
+
+	rows, err := r.db.Query(query, q.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u models.User
+		var nf userNullFields
+		var lastLogin sql.NullTime
+		if err := rows.Scan(&u.ID, &u.Username, &nf.email, &nf.verifiedAt, &u.Role, &lastLogin, &u.Status); err != nil {
+			return err
+		}
+		r.mapUserStatus(&u, nf)
+		if lastLogin.Valid {
+			u.LastLogin = lastLogin.Time.In(time.Local).Format(lastLoginLayout)
+		}
+		u.OrgID = f.OrgID
+		if err := fn(&u); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Update 更新指定组织内的用户信息及其组织内角色；邮箱变更时清除验证状态
+// 参数: orgID 组织ID, user 用户对象 (Password 非空时须为服务层生成的哈希; Enable 为 false 且 Status 为 pending 时保持待验证)
+// 返回: error 错误信息 (用户不属于该组织时返回 ErrUserNotFound, 用户名或邮箱已被占用时为 ErrUserExists)
+func (r *UserRepository) Update(orgID int64, user *models.User) error {
+	status := models.UserStatusDisabled
 	if user.Enable {
-		status = "enabled"
-	} else {
-		status = "disabled"
+		status = models.UserStatusEnabled
+	} else if user.Status == models.UserStatusPending {
+		status = models.UserStatusPending
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow(`
+		SELECT 1 FROM org_members m JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = ? AND m.user_id = ? AND u.deleted_at IS NULL FOR UPDATE`, orgID, user.ID).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
 	}
 
 	var query string
 	var args []interface{}
 
+	// MySQL 按顺序执行赋值：须在覆盖 email 之前比较新旧邮箱
+	email := nullableEmail(user.Email)
 	if user.Password != "" {
-		query = "UPDATE users SET username=?, password=?, role=?, status=?, avatar=? WHERE id=?"
-		args = []interface{}{user.Username, user.Password, user.Role, status, user.Avatar, user.ID}
+		query = "UPDATE users SET username=?, email_verified_at=IF(email <=> ?, email_verified_at, NULL), email=?, password=?, password_changed_at=NOW(), status=?, avatar=? WHERE id=?"
+		args = []interface{}{user.Username, email, email, user.Password, status, user.Avatar, user.ID}
 	} else {
-		query = "UPDATE users SET username=?, role=?, status=?, avatar=? WHERE id=?"
-		args = []interface{}{user.Username, user.Role, status, user.Avatar, user.ID}
+		query = "UPDATE users SET username=?, email_verified_at=IF(email <=> ?, email_verified_at, NULL), email=?, status=?, avatar=? WHERE id=?"
+		args = []interface{}{user.Username, email, email, status, user.Avatar, user.ID}
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return ErrUserExists
+		}
+		return err
 	}
+	if _, err := tx.Exec("UPDATE org_members SET role = ? WHERE org_id = ? AND user_id = ?", user.Role, orgID, user.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	_, err := r.db.Exec(query, args...)
+// UpdateAvatar 仅更新用户头像
+// 参数: uid 用户ID, avatar 头像文件名
+// 返回: error 错误信息
+func (r *UserRepository) UpdateAvatar(uid int64, avatar string) error {
+	_, err := r.db.Exec("UPDATE users SET avatar = ? WHERE id = ?", avatar, uid)
 	return err
 }
 
+// UpdatePassword 仅更新用户密码哈希，不改变密码修改时间 (用于同一密码的哈希升级)
+// 参数: uid 用户ID, passwordHash 密码哈希
+// 返回: error 错误信息
+func (r *UserRepository) UpdatePassword(uid int64, passwordHash string) error {
+	query := "UPDATE users SET password = ? WHERE id = ?"
+	_, err := r.db.Exec(query, passwordHash, uid)
+	return err
+}
+
+// ChangePassword 设置新密码并记录修改时间
+// 参数: uid 用户ID, passwordHash 新密码哈希
+// 返回: error 错误信息
+func (r *UserRepository) ChangePassword(uid int64, passwordHash string) error {
+	query := "UPDATE users SET password = ?, password_changed_at = NOW() WHERE id = ?"
+	_, err := r.db.Exec(query, passwordHash, uid)
+	return err
+}
+
+// UpdateEmail 仅更新用户邮箱；邮箱变更时清除验证状态
+// 参数: uid 用户ID, email 新邮箱 (小写，空字符串表示解除绑定)
+// 返回: error 错误信息
+func (r *UserRepository) UpdateEmail(uid int64, email string) error {
+	e := nullableEmail(email)
+	_, err := r.db.Exec("UPDATE users SET email_verified_at = IF(email <=> ?, email_verified_at, NULL), email = ? WHERE id = ?", e, e, uid)
+	return err
+}
+
+// MarkEmailVerified 确认邮箱已验证，待验证的账号同时激活
+// 参数: uid 用户ID, email 令牌签发时的邮箱 (账号邮箱已变更时不生效)
+// 返回: bool 是否更新成功, error 错误信息
+func (r *UserRepository) MarkEmailVerified(uid int64, email string) (bool, error) {
+	query := "UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), status = IF(status = ?, ?, status) WHERE id = ? AND email = ?"
+	result, err := r.db.Exec(query, models.UserStatusPending, models.UserStatusEnabled, uid, email)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return n > 0, err
+	}
+
+	// 影响行数只统计实际变更的行：已验证过的账号需再确认邮箱仍然匹配
+	var n int
+	err = r.db.QueryRow("SELECT COUNT(*) FROM users WHERE id = ? AND email = ?", uid, email).Scan(&n)
+	return n > 0, err
+}
+
 // UpdateLoginTime 更新最后登录时间
 // 参数: uid 用户ID
 // 返回: error 错误信息
 func (r *UserRepository) UpdateLoginTime(uid int64) error {
-	query := "UPDATE users SET last_login = CURRENT_TIMESTAMP WHERE id = ?"
-	_, err := r.db.Exec(query, uid)
+	query := "UPDATE users SET last_login = ? WHERE id = ?"
+	_, err := r.db.Exec(query, time.Now(), uid)
 	return err
 }
 
-// mapUserStatus 映射用户状态及头像
-func (r *UserRepository) mapUserStatus(u *models.User, statusStr string, avatar sql.NullString) {
-	u.Enable = (statusStr == "enabled")
-	if avatar.Valid {
-		u.Avatar = avatar.String
+// userColumns 按 userNullFields 顺序读取用户基本信息的列 (不含组织角色)
+const userColumns = "id, username, email, email_verified_at, password, password_changed_at, status, avatar"
+
+// userNullFields 用户表中可为空的列
+type userNullFields struct {
+	email             sql.NullString
+	verifiedAt        sql.NullTime
+	passwordChangedAt sql.NullTime
+	avatar            sql.NullString
+}
+
+// getOne 按 userColumns 查询单个用户
+func (r *UserRepository) getOne(query string, args ...interface{}) (*models.User, error) {
+	u := &models.User{}
+	var f userNullFields
+
+	err := r.db.QueryRow(query, args...).Scan(&u.ID, &u.Username, &f.email, &f.verifiedAt, &u.Password, &f.passwordChangedAt, &u.Status, &f.avatar)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	r.mapUserStatus(u, f)
+	return u, nil
+}
+
+// mapUserStatus 映射用户状态、邮箱及头像
+func (r *UserRepository) mapUserStatus(u *models.User, f userNullFields) {
+	u.Enable = (u.Status == models.UserStatusEnabled)
+	u.Email = f.email.String
+	u.EmailVerified = f.verifiedAt.Valid
+	u.PasswordChangedAt = f.passwordChangedAt.Time
+	if f.avatar.Valid {
+		u.Avatar = f.avatar.String
 	}
 }
+
+// nullableEmail 空邮箱存为 NULL (唯一索引允许多个 NULL)
+func nullableEmail(email string) sql.NullString {
+	return sql.NullString{String: email, Valid: email != ""}
+}
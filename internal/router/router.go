@@ -1,16 +1,20 @@
 package router
 
 import (
+	"GoWork_7/internal/config"
 	"GoWork_7/internal/database"
 	"GoWork_7/internal/handlers"
+	"GoWork_7/internal/mailer"
 	"GoWork_7/internal/middleware"
 	"GoWork_7/internal/repository"
 	"GoWork_7/internal/service"
+	"GoWork_7/internal/storage"
 	"GoWork_7/internal/utils"
 	"fmt"
 	"html/template"
 	"net/http"
 	"strings"
+	"time"
 )
 
 func welcome3(w http.ResponseWriter, r *http.Request) {
@@ -28,50 +32,283 @@ func welcome3(w http.ResponseWriter, r *http.Request) {
 	t.Execute(w, nil)
 }
 
-func SetupRouter() *http.ServeMux {
+// newRevocationStore 根据配置选择令牌撤销列表实现
+// memory 为默认实现；sql 使用数据库实现，供多实例部署共享
+func newRevocationStore(kind string) repository.RevocationStore {
+	if kind == "sql" {
+		return repository.NewSQLRevocationStore(database.DB)
+	}
+	return repository.NewMemoryRevocationStore()
+}
+
+// newMailer 根据配置选择邮件发送实现
+// log 为默认实现 (仅写日志)；file 将邮件保存为 .eml 文件；smtp 通过邮件服务器发送
+func newMailer(cfg config.MailConfig) mailer.Mailer {
+	switch cfg.Driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			TLS:      cfg.SMTP.TLS,
+		}, cfg.From)
+	case "file":
+		return mailer.NewFileMailer(cfg.Dir, cfg.From)
+	}
+	return mailer.NewLogMailer(cfg.From)
+}
+
+// newStorage 根据配置选择上传文件存储后端
+// local 为默认实现 (本地磁盘)；s3 使用 S3 兼容对象存储，供多实例部署共享
+func newStorage(cfg config.UploadConfig) (storage.Storage, error) {
+	if cfg.Driver == "s3" {
+		return storage.NewS3Storage(storage.S3Config{
+			Endpoint:      cfg.S3.Endpoint,
+			Region:        cfg.S3.Region,
+			Bucket:        cfg.S3.Bucket,
+			AccessKey:     cfg.S3.AccessKey,
+			SecretKey:     cfg.S3.SecretKey,
+			UseSSL:        cfg.S3.UseSSL,
+			PathStyle:     cfg.S3.PathStyle,
+			PublicURL:     cfg.S3.PublicURL,
+			PresignExpiry: time.Duration(cfg.S3.PresignExpirySeconds) * time.Second,
+		})
+	}
+	return storage.NewLocalStorage(cfg.Dir, cfg.BaseURL), nil
+}
+
+// newPasswordPolicy 根据配置创建密码策略服务
+// 黑名单文件在配置校验时已确认存在；读取失败时仅使用内置的常见弱密码并记录日志
+func newPasswordPolicy(cfg config.PasswordConfig, historyRepo *repository.PasswordHistoryRepository, hasher service.PasswordHasher) *service.PasswordPolicyService {
+	blocklist, err := service.LoadPasswordBlocklist(cfg.BlocklistFile)
+	if err != nil {
+		utils.SystemLogger.Error("读取密码黑名单 %s 失败，仅使用内置黑名单: %v", cfg.BlocklistFile, err)
+	}
+	return service.NewPasswordPolicyService(service.PasswordPolicy{
+		MinLength:     cfg.MinLength,
+		MaxLength:     cfg.MaxLength,
+		RequireUpper:  cfg.RequireUpper,
+		RequireLower:  cfg.RequireLower,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+		HistoryCount:  cfg.History,
+		MaxAge:        time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+	}, blocklist, historyRepo, hasher)
+}
+
+// SetupRouter 根据配置初始化依赖并注册路由
+func SetupRouter(cfg *config.Config) (*http.ServeMux, error) {
 	mux := http.NewServeMux()
 
+	uploadStorage, err := newStorage(cfg.Upload)
+	if err != nil {
+		return nil, fmt.Errorf("初始化上传存储失败: %w", err)
+	}
+	defaultAvatarSecret := []byte(cfg.Upload.DefaultAvatarSecret)
+	if len(defaultAvatarSecret) == 0 {
+		secret, err := utils.RandomToken(32)
+		if err != nil {
+			return nil, fmt.Errorf("生成默认头像密钥失败: %w", err)
+		}
+		defaultAvatarSecret = []byte(secret)
+		utils.SystemLogger.Info("未配置 upload.default_avatar_secret，默认头像使用进程内临时密钥 (重启后地址变化，多实例部署时须配置)")
+	}
+	defaultAvatarService := service.NewDefaultAvatarService(repository.NewUserRepository(database.DB), cfg.Upload.DefaultAvatarStyle, defaultAvatarSecret)
+	avatarUploadTTL := time.Duration(cfg.Upload.TempTTLMinutes) * time.Minute
+	avatarService := service.NewAvatarService(uploadStorage, repository.NewAvatarUploadRepository(database.DB), avatarUploadTTL, defaultAvatarService)
+	go avatarService.RunJanitor(time.Duration(cfg.Upload.JanitorIntervalMinutes) * time.Minute)
+
 	// 初始化依赖
 	userRepo := repository.NewUserRepository(database.DB)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(database.DB)
+	roleRepo := repository.NewRoleRepository(database.DB)
+	orgRepo := repository.NewOrgRepository(database.DB)
+	auditRepo := repository.NewAuditRepository(database.DB)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(database.DB)
+	mfaRepo := repository.NewMFARepository(database.DB)
+	passwordResetRepo := repository.NewPasswordResetRepository(database.DB)
+	emailVerificationRepo := repository.NewEmailVerificationRepository(database.DB)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(database.DB)
+	passwordHasher := service.DefaultPasswordHasher
+
+	revocationStore := newRevocationStore(cfg.JWT.RevocationStore)
+
+	auditService := service.NewAuditService(auditRepo)
 
-	loginService := service.NewLoginService(userRepo)
-	loginHandler := handlers.NewLoginHandler(loginService)
+	passwordPolicyService := newPasswordPolicy(cfg.Password, passwordHistoryRepo, passwordHasher)
+	passwordPolicyHandler := handlers.NewPasswordPolicyHandler(passwordPolicyService)
 
-	registerService := service.NewRegisterService(userRepo)
+	rbacService := service.NewRBACService(roleRepo, auditService)
+	roleHandler := handlers.NewRoleHandler(rbacService)
+	orgService := service.NewOrgService(orgRepo, userRepo, rbacService, auditService, int64(cfg.Org.DefaultID))
+
+	lockoutService := service.NewLockoutService(userRepo, loginAttemptRepo, auditService, service.LockoutPolicy{
+		MaxFailures:     cfg.Lockout.MaxFailures,
+		IPMaxFailures:   cfg.Lockout.IPMaxFailures,
+		LockDuration:    time.Duration(cfg.Lockout.LockSeconds) * time.Second,
+		MaxLockDuration: time.Duration(cfg.Lockout.MaxLockSeconds) * time.Second,
+		Window:          time.Duration(cfg.Lockout.WindowSeconds) * time.Second,
+	})
+
+	tokenService := service.NewTokenService(userRepo, refreshTokenRepo, revocationStore, auditService)
+
+	mailSender := newMailer(cfg.Mail)
+	passwordResetService := service.NewPasswordResetService(userRepo, passwordResetRepo, passwordHasher, passwordPolicyService, tokenService, lockoutService,
+		mailSender, auditService, cfg.Server.PublicURL)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetService)
+
+	mfaService := service.NewMFAService(mfaRepo, userRepo, rbacService, tokenService, passwordResetService, lockoutService, revocationStore, auditService, cfg.MFA.Issuer)
+	mfaHandler := handlers.NewMFAHandler(mfaService)
+
+	loginService := service.NewLoginService(userRepo, orgRepo, passwordHasher, tokenService, lockoutService, mfaService, passwordResetService, auditService)
+	authHandler := handlers.NewAuthHandler(tokenService, loginService, rbacService, orgService, lockoutService)
+	loginHandler := handlers.NewLoginHandler(loginService, rbacService, orgService, mfaService)
+
+	emailVerificationService := service.NewEmailVerificationService(userRepo, emailVerificationRepo, mailSender, auditService, cfg.Server.PublicURL)
+	emailVerificationHandler := handlers.NewEmailVerificationHandler(emailVerificationService)
+
+	registerService := service.NewRegisterService(userRepo, passwordHasher, passwordPolicyService, emailVerificationService, auditService,
+		cfg.RBAC.DefaultRole, int64(cfg.Org.DefaultID))
 	registerHandler := handlers.NewRegisterHandler(registerService)
 
-	userService := service.NewUserService(userRepo)
-	userHandler := handlers.NewUserHandler(userService)
+	userService := service.NewUserService(userRepo, passwordHasher, passwordPolicyService, emailVerificationService, rbacService, tokenService, auditService,
+		cfg.RBAC.DefaultRole, avatarUploadTTL)
+	userHandler := handlers.NewUserHandler(userService, rbacService, orgService, avatarService, cfg.Trash.RetentionDays)
+	if cfg.Trash.RetentionDays > 0 {
+		go userService.RunTrashPurger(time.Duration(cfg.Trash.RetentionDays)*24*time.Hour, time.Duration(cfg.Trash.PurgeIntervalMinutes)*time.Minute)
+	}
+	userImportService := service.NewUserImportService(userRepo, passwordHasher, passwordPolicyService, rbacService, emailVerificationService,
+		auditService, cfg.RBAC.DefaultRole)
+	userImportHandler := handlers.NewUserImportHandler(userImportService)
+	userExportHandler := handlers.NewUserExportHandler(userService)
+	userBulkService := service.NewUserBulkService(userRepo, passwordHasher, passwordPolicyService, rbacService, tokenService, auditService)
+	userBulkHandler := handlers.NewUserBulkHandler(userBulkService)
+	orgHandler := handlers.NewOrgHandler(orgService, userService)
+	auditHandler := handlers.NewAuditHandler(auditService, rbacService)
+
+	profileService := service.NewProfileService(userRepo, passwordHasher, passwordPolicyService, emailVerificationService, lockoutService,
+		tokenService, auditService)
+	profileHandler := handlers.NewProfileHandler(profileService, rbacService, avatarService)
+
+	uploadHandler := handlers.NewUploadHandler(userService, rbacService, orgService, avatarService)
 
-	uploadHandler := handlers.NewUploadHandler(userService)
+	tusService := service.NewTusService(uploadStorage, repository.NewTusUploadRepository(database.DB), service.TusPolicy{
+		MaxSize: int64(cfg.Upload.Tus.MaxSizeMB) << 20,
+		Quota:   int64(cfg.Upload.Tus.QuotaMB) << 20,
+		Expiry:  time.Duration(cfg.Upload.Tus.ExpiryHours) * time.Hour,
+	})
+	tusHandler := handlers.NewTusHandler(tusService)
+	defaultAvatarHandler := handlers.NewDefaultAvatarHandler(defaultAvatarService)
+	go tusService.RunJanitor(time.Duration(cfg.Upload.JanitorIntervalMinutes) * time.Minute)
 
-	authMiddleware := middleware.NewAuthMiddlewareProvider(userRepo)
+	authMiddleware := middleware.NewAuthMiddlewareProvider(userRepo, revocationStore, rbacService)
+
+	// authed 仅要求登录；protected 额外要求指定权限
+	authed := func(h http.HandlerFunc) http.Handler {
+		return authMiddleware.AuthMiddleware(h)
+	}
+	protected := func(permission string, h http.HandlerFunc) http.Handler {
+		return authMiddleware.AuthMiddleware(authMiddleware.RequirePermission(permission)(h))
+	}
 
 	// 1. 静态资源
 	mux.Handle("/html/", http.StripPrefix("/html/", http.FileServer(http.Dir("view/html"))))
 	mux.Handle("/js/", http.StripPrefix("/js/", http.FileServer(http.Dir("view/js"))))
-	mux.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir("view/images"))))
+	// 本地存储的上传文件；使用对象存储时由存储服务直接提供访问
+	if local, ok := uploadStorage.(*storage.LocalStorage); ok {
+		mux.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir(local.Dir()))))
+		// 可续传上传的文件不公开访问
+		mux.Handle("/images/uploads/", http.NotFoundHandler())
+	}
+	// 未上传头像的用户的默认头像 (与存储后端无关，按用户名实时生成)
+	mux.HandleFunc("GET /images/default/{file}", defaultAvatarHandler.Serve)
 
 	// 2. 基础页面路由
 	mux.HandleFunc("/", welcome3)
 
+	// 公钥集合 (供其它服务校验本服务签发的令牌)
+	mux.HandleFunc("GET /.well-known/jwks.json", authHandler.JWKS)
+
 	// 3. 认证相关接口 (Restful: /api/auth/...)
 	mux.HandleFunc("POST /api/auth/login", loginHandler.Login)
 	mux.HandleFunc("POST /api/auth/register", registerHandler.Register)
+	mux.HandleFunc("POST /api/auth/refresh", authHandler.Refresh)
+	mux.HandleFunc("POST /api/auth/forgot-password", passwordResetHandler.ForgotPassword)
+	mux.HandleFunc("POST /api/auth/reset-password", passwordResetHandler.ResetPassword)
+	mux.HandleFunc("GET /api/auth/password-policy", passwordPolicyHandler.Get)
+	mux.HandleFunc("POST /api/auth/verify-email", emailVerificationHandler.VerifyEmail)
+	mux.HandleFunc("POST /api/auth/resend-verification", emailVerificationHandler.ResendVerification)
+	mux.Handle("POST /api/auth/logout", authed(authHandler.Logout))
+	mux.Handle("GET /api/auth/orgs", authed(authHandler.Orgs))
+	mux.Handle("POST /api/auth/switch-org", authed(authHandler.SwitchOrg))
 
-	// 4. 用户资源接口 (Restful: /api/users)
+	// 二次验证：登录返回 mfa_token 后在 verify 接口换取正式令牌；其余接口管理自己的 TOTP 登记
+	mux.HandleFunc("POST /api/auth/mfa/verify", loginHandler.VerifyMFA)
+	mux.Handle("GET /api/auth/mfa", authed(mfaHandler.Status))
+	mux.Handle("POST /api/auth/mfa/enroll", authed(mfaHandler.Enroll))
+	mux.Handle("POST /api/auth/mfa/activate", authed(mfaHandler.Activate))
+	mux.Handle("POST /api/auth/mfa/recovery-codes", authed(mfaHandler.RegenerateRecoveryCodes))
+	mux.Handle("DELETE /api/auth/mfa", authed(mfaHandler.Disable))
+
+	// 4. 用户资源接口 (Restful: /api/users，均限定在令牌中的当前组织内)
+	// 本人资料 (仅允许修改白名单字段；修改密码需校验当前密码)
+	mux.Handle("GET /api/users/me", authed(profileHandler.Get))
+	mux.Handle("PATCH /api/users/me", authed(profileHandler.Update))
+	mux.Handle("POST /api/users/me/password", authed(profileHandler.ChangePassword))
 	// 获取用户列表
-	mux.Handle("GET /api/users", authMiddleware.AuthMiddleware(http.HandlerFunc(userHandler.GetAllUsers)))
+	mux.Handle("GET /api/users", protected(service.PermUsersRead, userHandler.GetAllUsers))
 	// 新增用户
-	mux.Handle("POST /api/users", authMiddleware.AuthMiddleware(http.HandlerFunc(userHandler.NewUser)))
-	// 修改用户 (使用路径参数 {id})
-	mux.Handle("PUT /api/users/{id}", authMiddleware.AuthMiddleware(http.HandlerFunc(userHandler.PutUser)))
-	// 删除用户 (使用路径参数 {id})
-	mux.Handle("DELETE /api/users/{id}", authMiddleware.AuthMiddleware(http.HandlerFunc(userHandler.DeleteUser)))
+	mux.Handle("POST /api/users", protected(service.PermUsersCreate, userHandler.NewUser))
+	// 批量导入用户 (CSV/XLSX，默认仅校验)
+	mux.Handle("POST /api/users/import", protected(service.PermUsersCreate, userImportHandler.Import))
+	// 导出用户 (CSV/XLSX/JSON，不分页)
+	mux.Handle("GET /api/users/export", protected(service.PermUsersExport, userExportHandler.Export))
+	// 批量操作用户 (按操作类型需 users:update 或 users:delete 权限，在 Service 中校验)
+	mux.Handle("POST /api/users/bulk", authed(userBulkHandler.Bulk))
+	// 修改用户 (使用路径参数 {id}；修改他人、角色和状态需 users:update 权限，在 Handler 中校验)
+	mux.Handle("PUT /api/users/{id}", authed(userHandler.PutUser))
+	// 删除用户 (使用路径参数 {id}；账号移入回收站，可在保留期内恢复)
+	mux.Handle("DELETE /api/users/{id}", protected(service.PermUsersDelete, userHandler.DeleteUser))
+	// 回收站：查看、恢复与彻底删除
+	mux.Handle("GET /api/users/trash", protected(service.PermUsersDelete, userHandler.ListDeletedUsers))
+	mux.Handle("POST /api/users/{id}/restore", protected(service.PermUsersDelete, userHandler.RestoreUser))
+	mux.Handle("DELETE /api/users/{id}/purge", protected(service.PermUsersDelete, userHandler.PurgeUser))
+	// 强制下线用户的全部会话
+	mux.Handle("DELETE /api/users/{id}/sessions", protected(service.PermSessionsRevoke, authHandler.RevokeUserSessions))
+	// 解除登录失败锁定
+	mux.Handle("DELETE /api/users/{id}/lockout", protected(service.PermUsersUpdate, authHandler.UnlockUser))
+	// 重置用户的二次验证 (用户丢失认证设备时使用)
+	mux.Handle("DELETE /api/users/{id}/mfa", protected(service.PermUsersUpdate, mfaHandler.ResetUser))
 	// 上传头像 (通用接口，支持新建用户时的临时上传)
-	mux.Handle("POST /api/uploads/avatar", authMiddleware.AuthMiddleware(http.HandlerFunc(uploadHandler.UploadAvatar)))
+	mux.Handle("POST /api/uploads/avatar", protected(service.PermUsersCreate, uploadHandler.UploadAvatar))
 	// 上传头像 (特定用户接口)
-	mux.Handle("POST /api/users/{id}/avatar", authMiddleware.AuthMiddleware(http.HandlerFunc(uploadHandler.UploadAvatar)))
+	mux.Handle("POST /api/users/{id}/avatar", authed(uploadHandler.UploadAvatar))
+	// 可续传上传 (tus 1.0)：OPTIONS 用于查询服务端能力及跨域预检，无需登录
+	mux.HandleFunc("OPTIONS /api/uploads/", tusHandler.Options)
+	mux.Handle("POST /api/uploads/{$}", authed(tusHandler.Create))
+	mux.Handle("HEAD /api/uploads/{id}", authed(tusHandler.Head))
+	mux.Handle("PATCH /api/uploads/{id}", authed(tusHandler.Patch))
+	mux.Handle("DELETE /api/uploads/{id}", authed(tusHandler.Delete))
+
+	// 5. 角色与权限管理接口 (Restful: /api/roles)
+	mux.Handle("GET /api/roles", protected(service.PermRolesManage, roleHandler.ListRoles))
+	mux.Handle("POST /api/roles", protected(service.PermRolesManage, roleHandler.CreateRole))
+	mux.Handle("PUT /api/roles/{name}", protected(service.PermRolesManage, roleHandler.UpdateRole))
+	mux.Handle("DELETE /api/roles/{name}", protected(service.PermRolesManage, roleHandler.DeleteRole))
+	mux.Handle("GET /api/permissions", protected(service.PermRolesManage, roleHandler.ListPermissions))
+
+	// 6. 组织管理接口 (Restful: /api/orgs，平台管理员跨组织管理)
+	mux.Handle("GET /api/orgs", protected(service.PermOrgsManage, orgHandler.ListOrgs))
+	mux.Handle("POST /api/orgs", protected(service.PermOrgsManage, orgHandler.CreateOrg))
+	mux.Handle("PUT /api/orgs/{id}", protected(service.PermOrgsManage, orgHandler.RenameOrg))
+	mux.Handle("DELETE /api/orgs/{id}", protected(service.PermOrgsManage, orgHandler.DeleteOrg))
+	mux.Handle("GET /api/orgs/{id}/members", protected(service.PermOrgsManage, orgHandler.ListMembers))
+	mux.Handle("POST /api/orgs/{id}/members", protected(service.PermOrgsManage, orgHandler.SetMember))
+	mux.Handle("DELETE /api/orgs/{id}/members/{userID}", protected(service.PermOrgsManage, orgHandler.RemoveMember))
+
+	// 7. 审计日志接口
+	mux.Handle("GET /api/audit", protected(service.PermAuditRead, auditHandler.ListEvents))
 
-	return mux
+	return mux, nil
 }
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// 审计动作
+const (
+	AuditUserRegister         = "user.register"
+	AuditUserCreate           = "user.create"
+	AuditUserUpdate           = "user.update"
+	AuditUserDelete           = "user.delete"
+	AuditUserRestore          = "user.restore"
+	AuditUserPurge            = "user.purge"
+	AuditUserExport           = "user.export"
+	AuditUserAvatar           = "user.avatar"
+	AuditUserUnlock           = "user.unlock"
+	AuditLogin                = "auth.login"
+	AuditLoginFailed          = "auth.login_failed"
+	AuditMFAChallenge         = "auth.mfa_challenge"
+	AuditPasswordResetRequest = "auth.password_reset_request"
+	AuditPasswordReset        = "auth.password_reset"
+	AuditEmailVerifyRequest   = "auth.email_verify_request"
+	AuditEmailVerify          = "auth.email_verify"
+	AuditPasswordChange       = "auth.password_change"
+	AuditProfileUpdate        = "user.profile_update"
+	AuditMFAEnable            = "mfa.enable"
+	AuditMFADisable           = "mfa.disable"
+	AuditMFAReset             = "mfa.reset"
+	AuditMFARecoveryCodes     = "mfa.recovery_codes"
+	AuditSessionsRevoke       = "sessions.revoke"
+	AuditRoleCreate           = "role.create"
+	AuditRoleUpdate           = "role.update"
+	AuditRoleDelete           = "role.delete"
+	AuditOrgCreate            = "org.create"
+	AuditOrgUpdate            = "org.update"
+	AuditOrgDelete            = "org.delete"
+	AuditOrgMemberSet         = "org.member_set"
+	AuditOrgMemberRemove      = "org.member_remove"
+)
+
+// 审计目标类型
+const (
+	AuditTargetUser = "user"
+	AuditTargetRole = "role"
+	AuditTargetOrg  = "org"
+)
+
+// AuditService 审计日志服务
+type AuditService struct {
+	auditRepo *repository.AuditRepository
+}
+
+// NewAuditService 创建审计日志服务实例
+func NewAuditService(auditRepo *repository.AuditRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// Record 写入审计事件
+// before/after 为变更前后的快照 (结构体或 map，可为 nil)，两者都存在时只保存发生变化的字段
+// 写入失败只记录错误日志，不影响业务操作
+func (s *AuditService) Record(actor models.Actor, action, targetType, targetID string, before, after interface{}) {
+	b, a := diffSnapshots(before, after)
+	e := &models.AuditEvent{
+		OrgID:      actor.OrgID,
+		ActorID:    actor.UserID,
+		ActorName:  actor.Username,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     b,
+		After:      a,
+		IP:         actor.IP,
+		UserAgent:  truncate(actor.UserAgent, 255),
+	}
+	if err := s.auditRepo.Create(e); err != nil {
+		utils.SystemLogger.Error("写入审计日志失败 (%s %s/%s): %v", action, targetType, targetID, err)
+	}
+}
+
+// Query 按条件分页查询审计事件
+func (s *AuditService) Query(f models.AuditFilter) ([]models.AuditEvent, int, error) {
+	return s.auditRepo.Query(f)
+}
+
+// diffSnapshots 序列化快照，两者都存在时仅保留值不同的字段
+func diffSnapshots(before, after interface{}) (json.RawMessage, json.RawMessage) {
+	bm, am := toMap(before), toMap(after)
+	if bm != nil && am != nil {
+		for k, v := range bm {
+			if av, ok := am[k]; ok && reflect.DeepEqual(v, av) {
+				delete(bm, k)
+				delete(am, k)
+			}
+		}
+	}
+	return marshalMap(bm), marshalMap(am)
+}
+
+// toMap 通过 JSON 将快照转换为 map，nil 返回 nil
+func toMap(v interface{}) map[string]interface{} {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// marshalMap 序列化 map，空 map 返回 nil
+func marshalMap(m map[string]interface{}) json.RawMessage {
+	if len(m) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// truncate 按字节截断字符串，保证不超过列长度
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/storage"
+	"GoWork_7/internal/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // 注册 GIF 解码器 (取第一帧)
+	"image/jpeg"
+	_ "image/png" // 注册 PNG 解码器
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // 注册 WebP 解码器
+)
+
+// AvatarSizes 头像输出尺寸 (正方形边长，像素)；第一个为主图，其余为缩略图
+var AvatarSizes = []int{256, 64, 32}
+
+const (
+	// avatarMaxPixels 允许解码的最大像素数，防止解压炸弹耗尽内存
+	avatarMaxPixels = 40_000_000
+	// avatarMaxSide 允许的最大边长
+	avatarMaxSide = 12000
+	// avatarJPEGQuality 重新编码的 JPEG 质量
+	avatarJPEGQuality = 85
+)
+
+var (
+	// ErrAvatarInvalid 文件不是支持的图片格式 (按内容检测并完整解码)
+	ErrAvatarInvalid = errors.New("AVATAR_INVALID")
+	// ErrAvatarTooLarge 图片尺寸超出上限
+	ErrAvatarTooLarge = errors.New("AVATAR_TOO_LARGE")
+)
+
+// avatarTypes 按内容检测允许的图片类型 -> image 包中的格式名
+var avatarTypes = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// avatarPrefix 头像在存储中的目录
+const avatarPrefix = "avatars/"
+
+var (
+	// avatarSizedPattern 各尺寸头像文件：avatars/{内容哈希}_{尺寸}.jpg，以及旧版的 {ID}_{用户名}_{尺寸}.jpg、temp_{时间戳}_{尺寸}.jpg
+	avatarSizedPattern = regexp.MustCompile(`^(avatars/[0-9a-f]{64}|temp_\d+|\d+_[^/]+)_(\d+)\.jpg$`)
+	// avatarLegacyPattern 旧版未经处理的头像：{ID}_{用户名}.{扩展名}、temp_{时间戳}.{扩展名}
+	avatarLegacyPattern = regexp.MustCompile(`^(temp_\d+|\d+_[^/]+)\.(jpg|jpeg|png|gif)$`)
+)
+
+// AvatarService 头像处理服务：校验图片内容，裁剪为正方形并重新编码为 JPEG (去除 EXIF/GPS 等元数据)，生成固定尺寸的缩略图
+// 文件按内容哈希命名，相同图片只保存一份；不再被引用的文件由 RunJanitor 清理
+type AvatarService struct {
+	store      storage.Storage
+	uploadRepo *repository.AvatarUploadRepository
+	uploadTTL  time.Duration
+	defaults   *DefaultAvatarService
+}
+
+// NewAvatarService 创建头像处理服务实例
+// 参数: store 头像存储后端 (本地磁盘或 S3 兼容对象存储), uploadTTL 临时上传的有效期 (超过后不能再用于新建用户，文件由清理任务删除),
+// defaults 未上传头像的用户的默认头像
+func NewAvatarService(store storage.Storage, uploadRepo *repository.AvatarUploadRepository, uploadTTL time.Duration,
+	defaults *DefaultAvatarService) *AvatarService {
+	return &AvatarService{store: store, uploadRepo: uploadRepo, uploadTTL: uploadTTL, defaults: defaults}
+}
+
+// DefaultURL 用户默认头像的站内地址
+func (s *AvatarService) DefaultURL(userID int64) string {
+	return s.defaults.URL(userID)
+}
+
+// Save 处理上传的图片并按 AvatarSizes 写入全部尺寸，文件名为 avatars/{主图 SHA-256}_{尺寸}.jpg
+// 参数: data 上传的原始文件内容
+// 返回: string 主图文件名 (保存到 users.avatar), error 错误信息 (非图片为 ErrAvatarInvalid, 尺寸超限为 ErrAvatarTooLarge)
+func (s *AvatarService) Save(ctx context.Context, data []byte) (string, error) {
+	images, err := ProcessAvatar(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(images[AvatarSizes[0]])
+	base := avatarPrefix + hex.EncodeToString(sum[:])
+	// 先写缩略图，主图最后写入：主图存在即表示各尺寸均已就绪
+	for i := len(AvatarSizes) - 1; i >= 0; i-- {
+		size := AvatarSizes[i]
+		img := images[size]
+		if err := s.store.Put(ctx, avatarFileName(base, size), bytes.NewReader(img), int64(len(img)), "image/jpeg"); err != nil {
+			return "", err
+		}
+	}
+	return avatarFileName(base, AvatarSizes[0]), nil
+}
+
+// SaveTemp 保存新建用户前上传的头像并登记为操作者的临时上传，创建用户时凭返回的文件名认领
+// 返回: string 主图文件名, error 错误信息 (同 Save)
+func (s *AvatarService) SaveTemp(ctx context.Context, actor models.Actor, data []byte) (string, error) {
+	avatar, err := s.Save(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	if err := s.uploadRepo.Create(actor.OrgID, actor.UserID, avatar); err != nil {
+		return "", err
+	}
+	return avatar, nil
+}
+
+// UploadTTL 临时上传的有效期
+func (s *AvatarService) UploadTTL() time.Duration {
+	return s.uploadTTL
+}
+
+// RunJanitor 按固定间隔清理头像文件，阻塞运行，应在独立 goroutine 中调用
+// 参数: interval 检查间隔
+func (s *AvatarService) RunJanitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.Clean(context.Background())
+		if err != nil {
+			utils.UserLogger.Error("清理头像文件失败: %v", err)
+		} else if n > 0 {
+			utils.UserLogger.Info("已删除 %d 个不再被引用的头像文件", n)
+		}
+		<-ticker.C
+	}
+}
+
+// Clean 删除过期的临时上传记录，以及不再被任何用户 (含回收站中的账号) 或临时上传引用的头像文件
+// 只处理头像命名规则内的文件，且只删除写入时间早于临时上传有效期的文件，避免误删刚写入、尚未落库的头像
+// 返回: int 删除的文件数, error 错误信息
+func (s *AvatarService) Clean(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-s.uploadTTL)
+	if _, err := s.uploadRepo.DeleteExpired(cutoff); err != nil {
+		return 0, err
+	}
+
+	// 各尺寸文件归属于其主图；旧版未处理的头像归属于自身
+	owners := make(map[string]string)
+	err := s.store.List(ctx, "", func(obj storage.Object) error {
+		if !obj.ModTime.Before(cutoff) {
+			return nil
+		}
+		if m := avatarSizedPattern.FindStringSubmatch(obj.Key); m != nil {
+			owners[obj.Key] = avatarFileName(m[1], AvatarSizes[0])
+		} else if avatarLegacyPattern.MatchString(obj.Key) {
+			owners[obj.Key] = obj.Key
+		}
+		return nil
+	})
+	if err != nil || len(owners) == 0 {
+		return 0, err
+	}
+
+	names := make([]string, 0, len(owners)*2)
+	for key, owner := range owners {
+		names = append(names, key)
+		if owner != key {
+			names = append(names, owner)
+		}
+	}
+	referenced, err := s.uploadRepo.Referenced(names)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for key, owner := range owners {
+		if referenced[key] || referenced[owner] {
+			continue
+		}
+		if err := s.store.Delete(ctx, key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// URLs 获取头像及各尺寸缩略图的访问地址 (由存储后端生成公开地址或预签名地址)
+// 参数: avatar users.avatar 中保存的文件名
+// 返回: string 头像地址 (未设置头像时为空), map[string]string 缩略图边长 -> 地址
+func (s *AvatarService) URLs(ctx context.Context, avatar string) (string, map[string]string) {
+	if avatar == "" {
+		return "", nil
+	}
+	mainURL, err := s.store.URL(ctx, avatar)
+	if err != nil {
+		utils.UserLogger.Error("生成头像 %s 的访问地址失败: %v", avatar, err)
+		return "", nil
+	}
+	thumbs := make(map[string]string, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		key := AvatarThumbnail(avatar, size)
+		if key == avatar {
+			thumbs[strconv.Itoa(size)] = mainURL
+			continue
+		}
+		u, err := s.store.URL(ctx, key)
+		if err != nil {
+			utils.UserLogger.Error("生成头像 %s 的访问地址失败: %v", key, err)
+			continue
+		}
+		thumbs[strconv.Itoa(size)] = u
+	}
+	return mainURL, thumbs
+}
+
+// ProcessAvatar 校验并处理头像图片
+// 按文件内容 (而非客户端声明的 Content-Type) 判断格式并完整解码，按 EXIF 方向校正后居中裁剪为正方形，
+// 缩放为 AvatarSizes 中的各尺寸并重新编码为 JPEG；重新编码后原图中的 EXIF、GPS 等元数据全部丢弃
+// 返回: map[int][]byte 尺寸 -> JPEG 内容, error 错误信息
+func ProcessAvatar(data []byte) (map[int][]byte, error) {
+	format, ok := avatarTypes[http.DetectContentType(data)]
+	if !ok {
+		return nil, ErrAvatarInvalid
+	}
+	cfg, cfgFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfgFormat != format {
+		return nil, ErrAvatarInvalid
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrAvatarInvalid
+	}
+	if cfg.Width > avatarMaxSide || cfg.Height > avatarMaxSide || cfg.Width*cfg.Height > avatarMaxPixels {
+		return nil, ErrAvatarTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarInvalid
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	// 居中裁剪为正方形 (裁剪区域在任意旋转/翻转下不变，可先裁剪缩放再校正方向)
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+
+	out := make(map[int][]byte, len(AvatarSizes))
+	var largest image.Image
+	for _, size := range AvatarSizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		// 透明区域铺白色背景 (JPEG 不支持透明)
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		var img image.Image = dst
+		if largest == nil {
+			draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+			img = orient(dst, orientation)
+			largest = img
+		} else {
+			// 缩略图由已校正方向的主图缩小得到
+			draw.CatmullRom.Scale(dst, dst.Bounds(), largest, largest.Bounds(), draw.Src, nil)
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: avatarJPEGQuality}); err != nil {
+			return nil, err
+		}
+		out[size] = buf.Bytes()
+	}
+	return out, nil
+}
+
+// AvatarThumbnail 获取头像指定尺寸的文件名
+// 由本服务生成的头像 (主图以 _256.jpg 结尾) 返回对应尺寸的缩略图；旧版未处理的头像没有缩略图，返回原文件名
+func AvatarThumbnail(avatar string, size int) string {
+	suffix := fmt.Sprintf("_%d.jpg", AvatarSizes[0])
+	if !strings.HasSuffix(avatar, suffix) {
+		return avatar
+	}
+	return avatarFileName(strings.TrimSuffix(avatar, suffix), size)
+}
+
+// avatarFileName 生成指定尺寸的头像文件名
+func avatarFileName(base string, size int) string {
+	return fmt.Sprintf("%s_%d.jpg", base, size)
+}
+
+// orient 按 EXIF 方向值 (1-8) 旋转/翻转图片，使其按正常方向显示
+func orient(img *image.RGBA, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿左上-右下对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转 90°
+				dx, dy = h-1-y, x
+			case 7: // 沿右上-左下对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转 90°
+				dx, dy = y, w-1-x
+			}
+			dst.SetRGBA(dx, dy, img.RGBAAt(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation 读取 JPEG 中 EXIF 的方向标记 (0x0112)，没有或无法解析时返回 1
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // 图像数据开始/结束，之后不会再有 EXIF
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		seg := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation 在 EXIF 的 TIFF 结构中查找 IFD0 的方向标记
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			v := int(order.Uint16(tiff[entry+8:]))
+			if v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"GoWork_7/internal/repository"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// 默认头像样式
+const (
+	// DefaultAvatarIdenticon 由用户名哈希生成的 5x5 对称图案
+	DefaultAvatarIdenticon = "identicon"
+	// DefaultAvatarInitials 用户名首字母
+	DefaultAvatarInitials = "initials"
+)
+
+// ErrDefaultAvatarFormat 不支持的默认头像格式 (仅支持 svg 与 png)
+var ErrDefaultAvatarFormat = errors.New("DEFAULT_AVATAR_FORMAT")
+
+// defaultAvatarFont PNG 首字母头像使用的字体 (Go Bold，仅含拉丁、希腊、西里尔字母等)
+var defaultAvatarFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(gobold.TTF)
+})
+
+// DefaultAvatar 生成的默认头像
+type DefaultAvatar struct {
+	Data        []byte
+	ContentType string
+	// ETag 图片内容的 SHA-256，用户名不变时在各实例、各次重启间保持不变
+	ETag string
+}
+
+// DefaultAvatarService 为未上传头像的用户生成默认头像，同一用户名总是生成相同的图片
+// 图案与配色只取决于用户名的哈希；服务端密钥仅用于访问地址的签名，使地址无法按用户ID枚举
+type DefaultAvatarService struct {
+	userRepo *repository.UserRepository
+	style    string
+	secret   []byte
+}
+
+// NewDefaultAvatarService 创建默认头像服务实例
+// 参数: style 头像样式 (DefaultAvatarIdenticon 或 DefaultAvatarInitials), secret 地址签名密钥 (多实例部署时须相同)
+func NewDefaultAvatarService(userRepo *repository.UserRepository, style string, secret []byte) *DefaultAvatarService {
+	return &DefaultAvatarService{userRepo: userRepo, style: style, secret: secret}
+}
+
+// URL 用户默认头像的站内地址 (SVG，可任意缩放)，地址中带有签名，只能通过已登录的接口获得
+func (s *DefaultAvatarService) URL(userID int64) string {
+	return fmt.Sprintf("/images/default/%d-%s.svg", userID, s.signature(userID))
+}
+
+// Render 生成用户的默认头像
+// 参数: userID 用户ID, sig 地址中的签名, format 图片格式 svg 或 png
+// 返回: *DefaultAvatar 图片数据及 ETag, error 错误信息 (格式不支持为 ErrDefaultAvatarFormat,
+// 签名错误、用户不存在或已在回收站中为 repository.ErrUserNotFound)
+func (s *DefaultAvatarService) Render(userID int64, sig, format string) (*DefaultAvatar, error) {
+	if format != "svg" && format != "png" {
+		return nil, ErrDefaultAvatarFormat
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signature(userID))) {
+		return nil, repository.ErrUserNotFound
+	}
+	username, err := s.userRepo.UsernameByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	avatar, err := s.render(username, format)
+	if err != nil {
+		return nil, err
+	}
+	tag := sha256.Sum256(avatar.Data)
+	avatar.ETag = `"` + hex.EncodeToString(tag[:16]) + `"`
+	return avatar, nil
+}
+
+// render 按用户名生成图片 (图案与配色取自用户名的 SHA-256，与服务端密钥无关)
+func (s *DefaultAvatarService) render(username, format string) (*DefaultAvatar, error) {
+	sum := sha256.Sum256([]byte(username))
+	hue := float64(binary.BigEndian.Uint16(sum[:2])) / 65536 * 360
+	size := AvatarSizes[0]
+	if format == "svg" {
+		if s.style == DefaultAvatarInitials {
+			return &DefaultAvatar{ContentType: "image/svg+xml", Data: initialsSVG(avatarInitials(username), hslColor(hue, 0.5, 0.45), size)}, nil
+		}
+		return &DefaultAvatar{ContentType: "image/svg+xml", Data: identiconSVG(sum, hslColor(hue, 0.55, 0.5), size)}, nil
+	}
+
+	var img image.Image
+	if s.style == DefaultAvatarInitials {
+		var err error
+		img, err = initialsImage(avatarInitials(username), hslColor(hue, 0.5, 0.45), size)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// 字体中没有对应字形 (如中文用户名) 时 PNG 退回 identicon
+	if img == nil {
+		img = identiconImage(sum, hslColor(hue, 0.55, 0.5), size)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return &DefaultAvatar{ContentType: "image/png", Data: buf.Bytes()}, nil
+}
+
+// identiconBackground identicon 的背景色
+var identiconBackground = color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+
+// identiconCells 由哈希决定 5x5 网格中哪些格子着色 (左三列取自哈希，右两列为镜像)
+func identiconCells(sum [32]byte) [5][5]bool {
+	var cells [5][5]bool
+	for row := 0; row < 5; row++ {
+		for col := 0; col < 3; col++ {
+			on := sum[2+row*3+col]&1 == 1
+			cells[row][col] = on
+			cells[row][4-col] = on
+		}
+	}
+	return cells
+}
+
+// identiconSVG 生成 SVG 格式的 identicon：12x12 画布，四周留 1 个单位边距，每格 2x2
+func identiconSVG(sum [32]byte, fg color.RGBA, size int) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 12 12" width="%d" height="%d" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(&b, `<rect width="12" height="12" fill="%s"/>`, hexColor(identiconBackground))
+	for row, cols := range identiconCells(sum) {
+		for col, on := range cols {
+			if on {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="2" height="2" fill="%s"/>`, 1+col*2, 1+row*2, hexColor(fg))
+			}
+		}
+	}
+	b.WriteString(`</svg>`)
+	return []byte(b.String())
+}
+
+// identiconImage 生成与 identiconSVG 相同图案的位图
+func identiconImage(sum [32]byte, fg color.RGBA, size int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(identiconBackground), image.Point{}, draw.Src)
+	fill := image.NewUniform(fg)
+	for row, cols := range identiconCells(sum) {
+		for col, on := range cols {
+			if on {
+				r := image.Rect((1+col*2)*size/12, (1+row*2)*size/12, (3+col*2)*size/12, (3+row*2)*size/12)
+				draw.Draw(img, r, fill, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return img
+}
+
+// avatarInitials 取用户名的首字母：按 . _ - 及空白分词后取第一个和最后一个词的首字符，否则取第一个字符
+func avatarInitials(username string) string {
+	words := strings.FieldsFunc(username, func(r rune) bool {
+		return r == '.' || r == '_' || r == '-' || unicode.IsSpace(r)
+	})
+	if len(words) == 0 {
+		return "?"
+	}
+	initials := []rune{[]rune(words[0])[0]}
+	if len(words) > 1 {
+		initials = append(initials, []rune(words[len(words)-1])[0])
+	}
+	return strings.ToUpper(string(initials))
+}
+
+// initialsSVG 生成 SVG 格式的首字母头像 (文字由浏览器使用系统字体渲染，支持任意语言)
+func initialsSVG(text string, bg color.RGBA, size int) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d">`, size, size, size, size)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`, size, size, hexColor(bg))
+	fmt.Fprintf(&b, `<text x="50%%" y="50%%" dy=".35em" text-anchor="middle" fill="#ffffff" font-size="%d" font-weight="bold" `+
+		`font-family="-apple-system, 'Segoe UI', 'PingFang SC', 'Microsoft YaHei', sans-serif">%s</text>`, size*2/5, html.EscapeString(text))
+	b.WriteString(`</svg>`)
+	return []byte(b.String())
+}
+
+// initialsImage 生成位图格式的首字母头像
+// 返回: image.Image 头像 (字体中缺少某个字符的字形时为 nil), error 错误信息
+func initialsImage(text string, bg color.RGBA, size int) (image.Image, error) {
+	f, err := defaultAvatarFont()
+	if err != nil {
+		return nil, err
+	}
+	var buf sfnt.Buffer
+	for _, r := range text {
+		if idx, err := f.GlyphIndex(&buf, r); err != nil || idx == 0 {
+			return nil, nil
+		}
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: float64(size * 2 / 5), DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(color.White), Face: face}
+	// 水平居中；按大写字母高度垂直居中
+	d.Dot = fixed.Point26_6{
+		X: (fixed.I(size) - d.MeasureString(text)) / 2,
+		Y: (fixed.I(size) + face.Metrics().CapHeight) / 2,
+	}
+	d.DrawString(text)
+	return img, nil
+}
+
+// signature 默认头像地址中的签名
+func (s *DefaultAvatarService) signature(userID int64) string {
+	sum := s.mac("url", strconv.FormatInt(userID, 10))
+	return hex.EncodeToString(sum[:8])
+}
+
+// mac 以地址签名密钥计算各部分 (以 \x00 分隔) 的 HMAC-SHA256
+func (s *DefaultAvatarService) mac(parts ...string) [32]byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(strings.Join(parts, "\x00")))
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// hslColor HSL 转 RGB (h 取 0-360，s、l 取 0-1)
+func hslColor(h, s, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	hp := h / 60
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g = c, x
+	case hp < 2:
+		r, g = x, c
+	case hp < 3:
+		g, b = c, x
+	case hp < 4:
+		g, b = x, c
+	case hp < 5:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := l - c/2
+	return color.RGBA{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+		A: 0xff,
+	}
+}
+
+// hexColor 颜色的 #rrggbb 表示
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
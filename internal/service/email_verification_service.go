@@ -0,0 +1,176 @@
+package service
+
+import (
+	"GoWork_7/internal/mailer"
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailVerificationTTL 邮箱验证令牌有效期
+const EmailVerificationTTL = 24 * time.Hour
+
+// 同一用户每小时最多发送的验证邮件数量
+const (
+	emailVerificationLimit       = 5
+	emailVerificationLimitWindow = time.Hour
+)
+
+// maxEmailLength 邮箱最大长度 (RFC 5321 路径长度限制，与 users.email 列宽一致)
+const maxEmailLength = 254
+
+var (
+	// ErrInvalidEmail 邮箱格式错误
+	ErrInvalidEmail = errors.New("INVALID_EMAIL")
+	// ErrEmailExists 邮箱已被其他账号使用
+	ErrEmailExists = errors.New("EMAIL_EXISTS")
+	// ErrVerificationTokenInvalid 验证令牌无效、过期、已使用或邮箱已变更
+	ErrVerificationTokenInvalid = errors.New("VERIFICATION_TOKEN_INVALID")
+)
+
+// NormalizeEmail 校验并规范化邮箱地址 (去空白、转小写；不接受带显示名的地址)
+// 参数: email 原始输入
+// 返回: string 规范化后的邮箱, error 格式错误时为 ErrInvalidEmail
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" || len(email) > maxEmailLength {
+		return "", ErrInvalidEmail
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+	return strings.ToLower(email), nil
+}
+
+// EmailVerificationService 邮箱验证服务：签发一次性验证令牌并通过邮件发送，凭令牌确认邮箱并激活待验证账号
+type EmailVerificationService struct {
+	userRepo   *repository.UserRepository
+	verifyRepo *repository.EmailVerificationRepository
+	mailer     mailer.Mailer
+	audit      *AuditService
+	publicURL  string
+}
+
+// NewEmailVerificationService 创建邮箱验证服务实例
+// 参数: publicURL 对外访问地址，用于生成邮件中的验证链接
+func NewEmailVerificationService(userRepo *repository.UserRepository, verifyRepo *repository.EmailVerificationRepository,
+	m mailer.Mailer, audit *AuditService, publicURL string) *EmailVerificationService {
+	return &EmailVerificationService{userRepo: userRepo, verifyRepo: verifyRepo, mailer: m, audit: audit, publicURL: publicURL}
+}
+
+// Send 向用户当前邮箱发送验证链接 (未绑定或已验证时忽略，超过频率限制时忽略并记录日志)
+// 参数: actor 请求来源, user 用户对象 (须含 Email 与 EmailVerified)
+// 返回: error 仅在数据库异常时返回
+func (s *EmailVerificationService) Send(actor models.Actor, user *models.User) error {
+	if user.Email == "" || user.EmailVerified {
+		return nil
+	}
+
+	n, err := s.verifyRepo.CountSince(user.ID, time.Now().Add(-emailVerificationLimitWindow))
+	if err != nil {
+		return err
+	}
+	if n >= emailVerificationLimit {
+		utils.AuthLogger.Info("用户 %d 的验证邮件发送过于频繁，已忽略", user.ID)
+		return nil
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyRepo.Create(user.ID, user.Email, utils.HashToken(token), time.Now().Add(EmailVerificationTTL)); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, AuditEmailVerifyRequest, AuditTargetUser, strconv.FormatInt(user.ID, 10), nil,
+		map[string]interface{}{"email": user.Email})
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "验证邮箱",
+		Body: fmt.Sprintf("%s，您好：\n\n请在 %d 小时内打开以下链接验证您的邮箱地址：\n\n%s\n\n"+
+			"该链接只能使用一次。如果您没有注册或修改过账号邮箱，请忽略本邮件。\n",
+			user.Username, int(EmailVerificationTTL.Hours()), s.verifyLink(token)),
+	}
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			utils.SystemLogger.Error("发送验证邮件给用户 %d 失败: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// Resend 重新发送验证邮件
+// 无论账号是否存在、是否需要验证都返回 nil，避免通过响应内容枚举用户名或邮箱
+// 参数: actor 请求来源, login 用户名或邮箱
+// 返回: error 仅在数据库异常时返回
+func (s *EmailVerificationService) Resend(actor models.Actor, login string) error {
+	user, err := s.userRepo.GetByLogin(login)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.Status == models.UserStatusDisabled {
+		return nil
+	}
+	actor.UserID, actor.Username = user.ID, user.Username
+	return s.Send(actor, user)
+}
+
+// Verify 使用验证令牌确认邮箱；待验证的账号同时激活
+// 参数: actor 请求来源, token 邮件中的验证令牌
+// 返回: *models.User 验证后的用户, error 错误信息 (令牌无效时为 ErrVerificationTokenInvalid)
+func (s *EmailVerificationService) Verify(actor models.Actor, token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrVerificationTokenInvalid
+	}
+	userID, email, err := s.verifyRepo.Consume(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrVerificationTokenNotFound) {
+			return nil, ErrVerificationTokenInvalid
+		}
+		return nil, err
+	}
+	before, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrVerificationTokenInvalid
+		}
+		return nil, err
+	}
+
+	// 令牌签发后邮箱已被修改：旧地址的链接不再有效
+	ok, err := s.userRepo.MarkEmailVerified(userID, email)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrVerificationTokenInvalid
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	actor.UserID, actor.Username = user.ID, user.Username
+	s.audit.Record(actor, AuditEmailVerify, AuditTargetUser, strconv.FormatInt(user.ID, 10),
+		map[string]interface{}{"email": before.Email, "email_verified": before.EmailVerified, "status": before.Status},
+		map[string]interface{}{"email": user.Email, "email_verified": user.EmailVerified, "status": user.Status})
+	return user, nil
+}
+
+// verifyLink 生成验证页面链接
+func (s *EmailVerificationService) verifyLink(token string) string {
+	return s.publicURL + "/html/verify-email.html?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAccountLocked 登录失败次数过多，账号或来源 IP 被临时锁定
+var ErrAccountLocked = errors.New("ACCOUNT_LOCKED")
+
+// LockedError 锁定详情 (errors.Is(err, ErrAccountLocked) 为真)
+type LockedError struct {
+	// Scope 锁定维度：user 或 ip
+	Scope string
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s: %s until %s", ErrAccountLocked.Error(), e.Scope, e.Until.Format(time.RFC3339))
+}
+
+// Unwrap 使 LockedError 可与 ErrAccountLocked 比较
+func (e *LockedError) Unwrap() error {
+	return ErrAccountLocked
+}
+
+// RetryAfter 距离解锁的剩余秒数 (至少 1 秒)
+func (e *LockedError) RetryAfter() int {
+	seconds := int(time.Until(e.Until).Seconds() + 0.999)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// LockoutPolicy 登录失败锁定策略；阈值为 0 表示关闭对应维度
+type LockoutPolicy struct {
+	MaxFailures     int
+	IPMaxFailures   int
+	LockDuration    time.Duration
+	MaxLockDuration time.Duration
+	Window          time.Duration
+}
+
+// LockoutService 登录失败计数与渐进式锁定服务
+// 达到阈值后锁定 LockDuration，此后每多失败一次锁定时长翻倍，直至 MaxLockDuration
+type LockoutService struct {
+	userRepo    *repository.UserRepository
+	attemptRepo *repository.LoginAttemptRepository
+	audit       *AuditService
+	policy      LockoutPolicy
+}
+
+// NewLockoutService 创建登录锁定服务实例
+func NewLockoutService(userRepo *repository.UserRepository, attemptRepo *repository.LoginAttemptRepository, audit *AuditService, policy LockoutPolicy) *LockoutService {
+	return &LockoutService{userRepo: userRepo, attemptRepo: attemptRepo, audit: audit, policy: policy}
+}
+
+// Check 登录前检查账号与来源 IP 是否处于锁定中 (锁定期间不再校验密码)
+// 返回: *LockedError 锁定中时返回, 查询失败时放行并记录日志
+func (s *LockoutService) Check(username, ip string) error {
+	now := time.Now()
+	for _, k := range s.keys(username, ip) {
+		until, err := s.attemptRepo.LockedUntil(k.scope, k.value)
+		if err != nil {
+			utils.AuthLogger.Error("查询登录锁定状态失败 (%s %s): %v", k.scope, k.value, err)
+			continue
+		}
+		if until.After(now) {
+			return &LockedError{Scope: k.scope, Until: until}
+		}
+	}
+	return nil
+}
+
+// RecordFailure 记录一次凭据错误 (用户名不存在同样计数，避免通过锁定行为枚举用户名)
+// 返回: *LockedError 本次失败触发锁定时返回, 否则为 nil
+func (s *LockoutService) RecordFailure(username, ip string) error {
+	now := time.Now()
+	var locked *LockedError
+	for _, k := range s.keys(username, ip) {
+		failures, err := s.attemptRepo.RecordFailure(k.scope, k.value, now, now.Add(-s.policy.Window))
+		if err != nil {
+			utils.AuthLogger.Error("记录登录失败次数失败 (%s %s): %v", k.scope, k.value, err)
+			continue
+		}
+		if failures < k.threshold {
+			continue
+		}
+
+		until := now.Add(s.lockDuration(failures - k.threshold))
+		if err := s.attemptRepo.Lock(k.scope, k.value, until); err != nil {
+			utils.AuthLogger.Error("设置登录锁定失败 (%s %s): %v", k.scope, k.value, err)
+			continue
+		}
+		utils.AuthLogger.Info("登录失败 %d 次，%s %s 锁定至 %s", failures, k.scope, k.value, until.Format(time.DateTime))
+		if locked == nil || until.After(locked.Until) {
+			locked = &LockedError{Scope: k.scope, Until: until}
+		}
+	}
+	if locked != nil {
+		return locked
+	}
+	return nil
+}
+
+// RecordSuccess 登录成功后清除账号的失败计数 (IP 计数保留至窗口过期，防止用自己的账号洗白)
+func (s *LockoutService) RecordSuccess(username string) {
+	if s.policy.MaxFailures <= 0 {
+		return
+	}
+	if _, err := s.attemptRepo.Reset(repository.AttemptScopeUser, normalizeLoginName(username)); err != nil {
+		utils.AuthLogger.Error("清除用户 %s 的登录失败计数失败: %v", username, err)
+	}
+}
+
+// Unlock 管理员解除当前组织内指定用户的登录锁定并清零失败计数
+// 返回: bool 账号此前是否存在失败记录, error 错误信息
+func (s *LockoutService) Unlock(actor models.Actor, userID int64) (bool, error) {
+	user, err := s.userRepo.GetByIDInOrg(actor.OrgID, userID)
+	if err != nil {
+		return false, err
+	}
+	key := normalizeLoginName(user.Username)
+	until, err := s.attemptRepo.LockedUntil(repository.AttemptScopeUser, key)
+	if err != nil {
+		return false, err
+	}
+	affected, err := s.attemptRepo.Reset(repository.AttemptScopeUser, key)
+	if err != nil {
+		return false, err
+	}
+	if affected > 0 {
+		before := map[string]interface{}{"locked": until.After(time.Now())}
+		if !until.IsZero() {
+			before["locked_until"] = until.Format(time.DateTime)
+		}
+		s.audit.Record(actor, AuditUserUnlock, AuditTargetUser, strconv.FormatInt(user.ID, 10), before,
+			map[string]interface{}{"locked": false})
+	}
+	return affected > 0, nil
+}
+
+// lockDuration 计算第 extra 次超限失败对应的锁定时长 (指数增长，封顶 MaxLockDuration)
+func (s *LockoutService) lockDuration(extra int) time.Duration {
+	d := s.policy.LockDuration
+	for i := 0; i < extra && d < s.policy.MaxLockDuration; i++ {
+		d *= 2
+	}
+	if d > s.policy.MaxLockDuration {
+		d = s.policy.MaxLockDuration
+	}
+	return d
+}
+
+// lockoutKey 单个计数维度
+type lockoutKey struct {
+	scope     string
+	value     string
+	threshold int
+}
+
+// keys 返回本次登录需要检查的计数维度 (跳过已关闭的维度与空值)
+func (s *LockoutService) keys(username, ip string) []lockoutKey {
+	var keys []lockoutKey
+	if name := normalizeLoginName(username); s.policy.MaxFailures > 0 && name != "" {
+		keys = append(keys, lockoutKey{repository.AttemptScopeUser, name, s.policy.MaxFailures})
+	}
+	if s.policy.IPMaxFailures > 0 && ip != "" {
+		keys = append(keys, lockoutKey{repository.AttemptScopeIP, ip, s.policy.IPMaxFailures})
+	}
+	return keys
+}
+
+// normalizeLoginName 统一用户名的计数键 (去空白、转小写、截断至列宽)
+func normalizeLoginName(username string) string {
+	name := strings.ToLower(strings.TrimSpace(username))
+	if len(name) > 100 {
+		name = strings.ToValidUTF8(name[:100], "")
+	}
+	return name
+}
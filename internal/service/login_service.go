@@ -5,43 +5,229 @@ import (
 	"GoWork_7/internal/repository"
 	"GoWork_7/internal/utils"
 	"errors"
+	"strconv"
+	"sync"
 )
 
+// ErrAccountPending 账号尚未验证邮箱
+var ErrAccountPending = errors.New("ACCOUNT_PENDING")
+
 // LoginService 登录业务服务
 type LoginService struct {
-	userRepo *repository.UserRepository
+	userRepo     *repository.UserRepository
+	orgRepo      *repository.OrgRepository
+	hasher       PasswordHasher
+	tokenService *TokenService
+	lockout      *LockoutService
+	mfa          *MFAService
+	resets       *PasswordResetService
+	audit        *AuditService
+	// dummyHash 账号不存在时用于校验的哈希，使耗时与密码错误一致
+	dummyHash func() string
 }
 
 // NewLoginService 创建登录服务实例
-func NewLoginService(userRepo *repository.UserRepository) *LoginService {
-	return &LoginService{userRepo: userRepo}
+func NewLoginService(userRepo *repository.UserRepository, orgRepo *repository.OrgRepository, hasher PasswordHasher, tokenService *TokenService, lockout *LockoutService,
+	mfa *MFAService, resets *PasswordResetService, audit *AuditService) *LoginService {
+	return &LoginService{
+		userRepo:     userRepo,
+		orgRepo:      orgRepo,
+		hasher:       hasher,
+		tokenService: tokenService,
+		lockout:      lockout,
+		mfa:          mfa,
+		resets:       resets,
+		audit:        audit,
+		dummyHash: sync.OnceValue(func() string {
+			hash, err := hasher.Hash("dummy-password-for-timing")
+			if err != nil {
+				utils.AuthLogger.Error("生成占位密码哈希失败: %v", err)
+			}
+			return hash
+		}),
+	}
 }
 
-// Login 处理登录业务逻辑
-// 参数: username 用户名, password 密码
-// 返回: *models.User 用户对象, string JWT令牌, error 错误信息
-func (s *LoginService) Login(username, password string) (*models.User, string, error) {
-	// 1. 获取用户信息
-	user, err := s.userRepo.GetByUsernameAndPassword(username, password)
+// Login 处理登录业务逻辑，成功与失败均写入审计日志；凭据错误计入失败次数，超过阈值后临时锁定
+// 需要二次验证时不签发令牌，而是返回 mfa_pending 挑战，由 MFAService.Verify 完成登录；密码已过期时同样不签发令牌
+// 参数: actor 请求来源 (IP/UserAgent), login 用户名或邮箱, password 密码, orgID 进入的组织 (0 表示加入最早的组织)
+// 返回: *models.User 用户对象 (含组织内角色), *models.TokenPair 访问令牌与刷新令牌, *models.MFAChallenge 二次验证挑战 (与令牌二选一), error 错误信息 (锁定时为 *LockedError, 密码过期时为 *PasswordExpiredError)
+func (s *LoginService) Login(actor models.Actor, login, password string, orgID int64) (*models.User, *models.TokenPair, *models.MFAChallenge, error) {
+	user, err := s.userRepo.GetByLogin(login)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, nil, nil, err
+	}
+
+	// 失败计数以账号的用户名为键，交替使用用户名与邮箱不能绕过锁定；账号不存在时以输入值计数
+	key := login
+	if user != nil {
+		key = user.Username
+	}
+
+	// 锁定期间直接拒绝，不校验密码
+	if err := s.lockout.Check(key, actor.IP); err != nil {
+		s.recordFailure(actor, login, user, err)
+		return nil, nil, nil, err
+	}
+
+	var tokens *models.TokenPair
+	var challenge *models.MFAChallenge
+	if user == nil {
+		// 账号不存在时同样执行一次哈希校验，避免通过响应耗时枚举用户名
+		_, _ = s.hasher.Verify(s.dummyHash(), password)
+		err = repository.ErrUserNotFound
+	} else {
+		tokens, challenge, err = s.authenticate(actor, user, password, orgID)
+	}
 	if err != nil {
-		return nil, "", err
+		s.recordFailure(actor, login, user, err)
+
+		// 仅凭据错误计入失败次数 (账号禁用、组织不匹配等不计)
+		credentialErr := errors.Is(err, ErrPasswordMismatch) || errors.Is(err, repository.ErrUserNotFound)
+		if credentialErr {
+			if lockErr := s.lockout.RecordFailure(key, actor.IP); lockErr != nil {
+				return nil, nil, nil, lockErr
+			}
+		}
+
+		// 密码错误与用户不存在对外返回同一错误，避免枚举用户名
+		if errors.Is(err, ErrPasswordMismatch) {
+			return nil, nil, nil, repository.ErrUserNotFound
+		}
+		return nil, nil, nil, err
+	}
+
+	actor.UserID, actor.Username, actor.Role, actor.OrgID = user.ID, user.Username, user.Role, user.OrgID
+	if challenge != nil {
+		// 失败计数在二次验证通过后才清零
+		s.audit.Record(actor, AuditMFAChallenge, AuditTargetUser, strconv.FormatInt(user.ID, 10), nil,
+			map[string]interface{}{"enrollment": challenge.Enrollment != nil})
+		return user, nil, challenge, nil
 	}
 
-	// 2. 检查账号是否启用
+	s.lockout.RecordSuccess(key)
+	s.audit.Record(actor, AuditLogin, AuditTargetUser, strconv.FormatInt(user.ID, 10), nil, nil)
+	return user, tokens, nil, nil
+}
+
+// recordFailure 写入登录失败审计日志
+func (s *LoginService) recordFailure(actor models.Actor, login string, user *models.User, err error) {
+	actor.Username = login
+	targetID := ""
+	if user != nil {
+		actor.UserID = user.ID
+		targetID = strconv.FormatInt(user.ID, 10)
+	}
+	s.audit.Record(actor, AuditLoginFailed, AuditTargetUser, targetID, nil, map[string]interface{}{"reason": err.Error()})
+}
+
+// authenticate 校验凭据并签发令牌 (需要二次验证时返回挑战)
+func (s *LoginService) authenticate(actor models.Actor, user *models.User, password string, orgID int64) (*models.TokenPair, *models.MFAChallenge, error) {
+	// 1. 在服务层校验密码哈希
+	needsRehash, err := s.hasher.Verify(user.Password, password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 2. 检查账号是否启用 (密码正确后才区分待验证与禁用，避免泄露账号状态)
+	if user.Status == models.UserStatusPending {
+		return nil, nil, ErrAccountPending
+	}
 	if !user.Enable {
-		return nil, "", errors.New("ACCOUNT_DISABLED")
+		return nil, nil, errors.New("ACCOUNT_DISABLED")
+	}
+
+	// 3. 历史明文或过期参数的哈希：登录成功后透明升级
+	if needsRehash {
+		if hash, err := s.hasher.Hash(password); err == nil {
+			if err := s.userRepo.UpdatePassword(user.ID, hash); err != nil {
+				utils.AuthLogger.Error("用户 %d 密码哈希升级失败: %v", user.ID, err)
+			}
+		}
+	}
+
+	// 4. 确定登录后进入的组织及组织内角色
+	if err := s.selectOrg(user, orgID); err != nil {
+		return nil, nil, err
+	}
+
+	// 5. 已启用二次验证或角色要求二次验证：签发 mfa_pending 令牌，暂不签发正式令牌
+	challenge, err := s.mfa.Challenge(user, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge != nil {
+		return nil, challenge, nil
+	}
+
+	// 6. 密码已超过最长有效期：只返回一次性重置令牌，修改密码后才能登录
+	if err := s.resets.CheckExpired(actor, user); err != nil {
+		return nil, nil, err
 	}
 
-	// 3. 更新登录时间
+	// 7. 更新登录时间
 	_ = s.userRepo.UpdateLoginTime(user.ID)
 
-	// 4. 生成 JWT Token
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Role)
+	// 8. 签发访问令牌与刷新令牌
+	tokens, err := s.tokenService.IssueTokens(user)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return user, token, nil
+	return tokens, nil, nil
+}
+
+// SwitchOrg 切换到用户所属的另一个组织
+// 与登录相同按目标组织内的角色判断是否需要二次验证：需要时不签发令牌，而是返回 mfa_pending 挑战，
+// 由 MFAService.Verify 验证后签发目标组织的令牌并撤销 current 会话 (避免从无需二次验证的组织切换进要求二次验证的组织)
+// 密码已过期时与登录相同不签发令牌
+// 参数: actor 当前用户, orgID 目标组织ID, current 当前会话 (二次验证通过后撤销)
+// 返回: *models.User 用户对象 (含目标组织内角色), *models.TokenPair 新令牌对, *models.MFAChallenge 二次验证挑战 (与令牌二选一),
+// error 错误信息 (密码过期时为 *PasswordExpiredError)
+func (s *LoginService) SwitchOrg(actor models.Actor, orgID int64, current *utils.SessionRef) (*models.User, *models.TokenPair, *models.MFAChallenge, error) {
+	user, err := s.userRepo.GetByIDInOrg(orgID, actor.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, nil, nil, ErrNotOrgMember
+		}
+		return nil, nil, nil, err
+	}
+	if !user.Enable {
+		return nil, nil, nil, errors.New("ACCOUNT_DISABLED")
+	}
+	challenge, err := s.mfa.Challenge(user, current)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if challenge != nil {
+		return user, nil, challenge, nil
+	}
+	if err := s.resets.CheckExpired(actor, user); err != nil {
+		return nil, nil, nil, err
+	}
+	pair, err := s.tokenService.IssueTokens(user)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return user, pair, nil, nil
+}
+
+// selectOrg 根据成员关系设置用户的当前组织与角色
+func (s *LoginService) selectOrg(user *models.User, orgID int64) error {
+	memberships, err := s.orgRepo.ListByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if len(memberships) == 0 {
+		return ErrNoOrganization
+	}
+	for _, m := range memberships {
+		if orgID == 0 || m.OrgID == orgID {
+			user.OrgID, user.Role = m.OrgID, m.Role
+			return nil
+		}
+	}
+	return ErrNotOrgMember
 }
 
 // GetUserByID 根据ID获取用户信息 (保留在登录服务中供相关逻辑使用)
@@ -0,0 +1,511 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"bytes"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// TOTP 参数 (RFC 6238 默认值，与主流认证器 App 兼容)
+const (
+	totpPeriod        = 30
+	totpDigits        = otp.DigitsSix
+	recoveryCodeCount = 10
+	mfaQRCodeSize     = 200
+	mfaMethodTOTP     = "totp"
+	mfaMethodRecovery = "recovery_code"
+)
+
+var (
+	// ErrMFAInvalidCode 验证码或恢复码错误
+	ErrMFAInvalidCode = errors.New("MFA_INVALID_CODE")
+	// ErrMFATokenInvalid 二次验证令牌无效、过期或已使用
+	ErrMFATokenInvalid = errors.New("MFA_TOKEN_INVALID")
+	// ErrMFANotEnabled 未启用二次验证
+	ErrMFANotEnabled = errors.New("MFA_NOT_ENABLED")
+	// ErrMFAAlreadyEnabled 已启用二次验证
+	ErrMFAAlreadyEnabled = errors.New("MFA_ALREADY_ENABLED")
+	// ErrMFANotEnrolled 尚未登记 (需先获取密钥)
+	ErrMFANotEnrolled = errors.New("MFA_NOT_ENROLLED")
+	// ErrMFARequiredByRole 当前角色要求二次验证，不能关闭
+	ErrMFARequiredByRole = errors.New("MFA_REQUIRED_BY_ROLE")
+	// ErrMFAResetForbidden 不能重置角色权限高于自己的用户
+	ErrMFAResetForbidden = errors.New("MFA_RESET_FORBIDDEN")
+	// ErrMFAResetSelf 不能通过管理接口重置自己的二次验证 (须验证码关闭)
+	ErrMFAResetSelf = errors.New("MFA_RESET_SELF")
+)
+
+// secretEncoding otpauth URI 使用的无填充 Base32 编码
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAService TOTP 二次验证服务：登记、激活、登录验证与恢复码
+type MFAService struct {
+	mfaRepo      *repository.MFARepository
+	userRepo     *repository.UserRepository
+	rbac         *RBACService
+	tokenService *TokenService
+	resets       *PasswordResetService
+	lockout      *LockoutService
+	revocations  repository.RevocationStore
+	audit        *AuditService
+	issuer       string
+}
+
+// NewMFAService 创建二次验证服务实例
+// 参数: issuer 认证器 App 中显示的签发方名称
+func NewMFAService(mfaRepo *repository.MFARepository, userRepo *repository.UserRepository, rbac *RBACService, tokenService *TokenService,
+	resets *PasswordResetService, lockout *LockoutService, revocations repository.RevocationStore, audit *AuditService, issuer string) *MFAService {
+	return &MFAService{
+		mfaRepo:      mfaRepo,
+		userRepo:     userRepo,
+		rbac:         rbac,
+		tokenService: tokenService,
+		resets:       resets,
+		lockout:      lockout,
+		revocations:  revocations,
+		audit:        audit,
+		issuer:       issuer,
+	}
+}
+
+// Challenge 密码校验通过后判断是否需要二次验证
+// 已启用二次验证，或角色要求二次验证时签发 mfa_pending 令牌；后者若尚未启用则一并返回登记信息
+// 返回: *models.MFAChallenge 挑战 (无需二次验证时为 nil), error 错误信息
+// 参数: user 密码校验通过 (或切换组织) 的用户, replaces 切换组织时验证通过后需撤销的旧会话 (登录时为 nil)
+func (s *MFAService) Challenge(user *models.User, replaces *utils.SessionRef) (*models.MFAChallenge, error) {
+	rec, err := s.mfaRepo.Get(user.ID)
+	if err != nil && !errors.Is(err, repository.ErrMFANotFound) {
+		return nil, err
+	}
+	enabled := rec != nil && rec.Enabled
+	if !enabled {
+		required, err := s.rbac.MFARequired(user.Role)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+	}
+
+	if err := s.tokenService.AwaitRevocationSecond(user.ID); err != nil {
+		return nil, err
+	}
+	token, err := utils.GenerateMFAToken(user.ID, user.Username, user.OrgID, replaces)
+	if err != nil {
+		return nil, err
+	}
+	challenge := &models.MFAChallenge{Token: token, ExpiresIn: int64(utils.MFATokenTTL.Seconds())}
+	if !enabled {
+		// 沿用已有的待激活密钥，避免用户每次登录都需重新扫码
+		secret := ""
+		if rec != nil {
+			secret = rec.Secret
+		}
+		if challenge.Enrollment, err = s.enroll(user.ID, user.Username, secret); err != nil {
+			return nil, err
+		}
+	}
+	return challenge, nil
+}
+
+// Verify 校验 mfa_pending 令牌与验证码，通过后签发正式令牌
+// 强制登记场景下验证通过即激活二次验证并返回恢复码；验证码错误计入登录失败次数
+// 密码已过期时不签发令牌，返回 *PasswordExpiredError (激活时恢复码仍一并返回)
+// 参数: actor 请求来源, mfaToken 登录返回的 mfa_token, code TOTP 验证码或恢复码
+// 返回: *models.User 用户对象, *models.TokenPair 令牌对, []string 新生成的恢复码 (仅激活时), error 错误信息
+func (s *MFAService) Verify(actor models.Actor, mfaToken, code string) (*models.User, *models.TokenPair, []string, error) {
+	claims, err := utils.ParseMFAToken(mfaToken)
+	if err != nil {
+		return nil, nil, nil, ErrMFATokenInvalid
+	}
+	revoked, err := s.revocations.IsRevoked(claims.RegisteredClaims.ID, claims.ID, claims.IssuedAt.Time)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if revoked {
+		return nil, nil, nil, ErrMFATokenInvalid
+	}
+
+	actor.UserID, actor.Username, actor.OrgID = claims.ID, claims.Username, claims.OrgID
+	targetID := strconv.FormatInt(claims.ID, 10)
+	fail := func(err error) error {
+		s.audit.Record(actor, AuditLoginFailed, AuditTargetUser, targetID, nil, map[string]interface{}{"reason": err.Error(), "stage": "mfa"})
+		return err
+	}
+
+	if err := s.lockout.Check(claims.Username, actor.IP); err != nil {
+		return nil, nil, nil, fail(err)
+	}
+
+	user, err := s.userRepo.GetByIDInOrg(claims.OrgID, claims.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, nil, nil, fail(ErrMFATokenInvalid)
+		}
+		return nil, nil, nil, err
+	}
+	if !user.Enable {
+		return nil, nil, nil, fail(errors.New("ACCOUNT_DISABLED"))
+	}
+	rec, err := s.mfaRepo.Get(user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			// 登录过程中被管理员重置
+			return nil, nil, nil, fail(ErrMFATokenInvalid)
+		}
+		return nil, nil, nil, err
+	}
+
+	var method string
+	var recoveryCodes []string
+	if rec.Enabled {
+		method, err = s.checkCode(rec, code)
+	} else {
+		method = mfaMethodTOTP
+		recoveryCodes, err = s.activate(rec, code)
+	}
+	if err != nil {
+		if errors.Is(err, ErrMFAInvalidCode) {
+			fail(err)
+			if lockErr := s.lockout.RecordFailure(claims.Username, actor.IP); lockErr != nil {
+				return nil, nil, nil, lockErr
+			}
+		}
+		return nil, nil, nil, err
+	}
+
+	// mfa_pending 令牌一次性使用
+	if err := s.revocations.RevokeToken(claims.RegisteredClaims.ID, claims.ExpiresAt.Time); err != nil {
+		utils.AuthLogger.Error("作废二次验证令牌失败: %v", err)
+	}
+	s.lockout.RecordSuccess(user.Username)
+	if err := s.resets.CheckExpired(actor, user); err != nil {
+		return user, nil, recoveryCodes, fail(err)
+	}
+	// 切换组织：验证通过后旧组织的会话作废
+	if claims.Replaces != nil {
+		if err := s.tokenService.RevokeSession(claims.Replaces); err != nil {
+			utils.AuthLogger.Error("用户 %d 切换组织后撤销旧会话失败: %v", user.ID, err)
+		}
+	}
+	_ = s.userRepo.UpdateLoginTime(user.ID)
+
+	tokens, err := s.tokenService.IssueTokens(user)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	actor.Role = user.Role
+	after := map[string]interface{}{"mfa": method}
+	if recoveryCodes != nil {
+		after["mfa_enabled"] = true
+	}
+	s.audit.Record(actor, AuditLogin, AuditTargetUser, targetID, nil, after)
+	return user, tokens, recoveryCodes, nil
+}
+
+// Status 查询当前用户的二次验证状态
+func (s *MFAService) Status(userID int64, role string) (*models.MFAStatus, error) {
+	status := &models.MFAStatus{}
+	required, err := s.rbac.MFARequired(role)
+	if err != nil {
+		return nil, err
+	}
+	status.Required = required
+
+	rec, err := s.mfaRepo.Get(userID)
+	if errors.Is(err, repository.ErrMFANotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.Enabled, status.Pending = rec.Enabled, !rec.Enabled
+	if rec.Enabled {
+		if status.RecoveryCodesRemaining, err = s.mfaRepo.CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Enroll 为当前用户生成新的待激活密钥 (覆盖未激活的旧密钥)
+func (s *MFAService) Enroll(actor models.Actor) (*models.MFAEnrollment, error) {
+	rec, err := s.mfaRepo.Get(actor.UserID)
+	if err != nil && !errors.Is(err, repository.ErrMFANotFound) {
+		return nil, err
+	}
+	if rec != nil && rec.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return s.enroll(actor.UserID, actor.Username, "")
+}
+
+// Activate 使用认证器 App 生成的验证码激活二次验证
+// 返回: []string 恢复码明文 (仅此一次返回), error 错误信息
+func (s *MFAService) Activate(actor models.Actor, code string) ([]string, error) {
+	rec, err := s.mfaRepo.Get(actor.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if rec.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	codes, err := s.activate(rec, code)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(actor, AuditMFAEnable, AuditTargetUser, strconv.FormatInt(actor.UserID, 10),
+		map[string]interface{}{"mfa_enabled": false}, map[string]interface{}{"mfa_enabled": true})
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码 (旧恢复码全部作废)
+func (s *MFAService) RegenerateRecoveryCodes(actor models.Actor, code string) ([]string, error) {
+	rec, err := s.enabledRecord(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyOwnCode(actor, rec, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(actor.UserID, hashes); err != nil {
+		return nil, err
+	}
+	s.audit.Record(actor, AuditMFARecoveryCodes, AuditTargetUser, strconv.FormatInt(actor.UserID, 10), nil,
+		map[string]interface{}{"recovery_codes": len(codes)})
+	return codes, nil
+}
+
+// Disable 校验验证码后关闭当前用户的二次验证 (角色要求二次验证时不可关闭)
+func (s *MFAService) Disable(actor models.Actor, code string) error {
+	rec, err := s.enabledRecord(actor.UserID)
+	if err != nil {
+		return err
+	}
+	if required, err := s.rbac.MFARequired(actor.Role); err != nil {
+		return err
+	} else if required {
+		return ErrMFARequiredByRole
+	}
+	if err := s.verifyOwnCode(actor, rec, code); err != nil {
+		return err
+	}
+	if _, err := s.mfaRepo.Delete(actor.UserID); err != nil {
+		return err
+	}
+	s.audit.Record(actor, AuditMFADisable, AuditTargetUser, strconv.FormatInt(actor.UserID, 10),
+		map[string]interface{}{"mfa_enabled": true}, map[string]interface{}{"mfa_enabled": false})
+	return nil
+}
+
+// Reset 管理员重置当前组织内用户的二次验证 (用户丢失设备与恢复码时使用)
+// 只能重置角色权限不高于自己的用户；不能重置自己 (须通过 DELETE /api/auth/mfa 验证码关闭)
+// 返回: bool 用户此前是否登记过二次验证, error 错误信息 (重置自己为 ErrMFAResetSelf, 权限不足为 ErrMFAResetForbidden)
+func (s *MFAService) Reset(actor models.Actor, userID int64) (bool, error) {
+	target, err := s.userRepo.GetByIDInOrg(actor.OrgID, userID)
+	if err != nil {
+		return false, err
+	}
+	if userID == actor.UserID {
+		return false, ErrMFAResetSelf
+	}
+	if !s.rbac.CanAssign(actor.Role, target.Role) {
+		return false, ErrMFAResetForbidden
+	}
+	affected, err := s.mfaRepo.Delete(userID)
+	if err != nil {
+		return false, err
+	}
+	if affected > 0 {
+		s.audit.Record(actor, AuditMFAReset, AuditTargetUser, strconv.FormatInt(userID, 10),
+			map[string]interface{}{"mfa_enabled": true}, map[string]interface{}{"mfa_enabled": false})
+	}
+	return affected > 0, nil
+}
+
+// verifyOwnCode 校验已登录用户提交的验证码；错误计入登录失败次数，防止借用会话暴力猜测
+func (s *MFAService) verifyOwnCode(actor models.Actor, rec *models.UserMFA, code string) error {
+	if err := s.lockout.Check(actor.Username, actor.IP); err != nil {
+		return err
+	}
+	if _, err := s.checkCode(rec, code); err != nil {
+		if errors.Is(err, ErrMFAInvalidCode) {
+			if lockErr := s.lockout.RecordFailure(actor.Username, actor.IP); lockErr != nil {
+				return lockErr
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// enabledRecord 获取已启用的二次验证记录
+func (s *MFAService) enabledRecord(userID int64) (*models.UserMFA, error) {
+	rec, err := s.mfaRepo.Get(userID)
+	if errors.Is(err, repository.ErrMFANotFound) || (err == nil && !rec.Enabled) {
+		return nil, ErrMFANotEnabled
+	}
+	return rec, err
+}
+
+// enroll 保存待激活密钥并生成 otpauth URI 与二维码
+// 参数: secret 沿用的 Base32 密钥，为空时随机生成
+func (s *MFAService) enroll(userID int64, username, secret string) (*models.MFAEnrollment, error) {
+	opts := totp.GenerateOpts{Issuer: s.issuer, AccountName: username, Period: totpPeriod, Digits: totpDigits, Algorithm: otp.AlgorithmSHA1}
+	if secret != "" {
+		raw, err := secretEncoding.DecodeString(secret)
+		if err != nil {
+			return nil, err
+		}
+		opts.Secret = raw
+	}
+	key, err := totp.Generate(opts)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.mfaRepo.SavePending(userID, key.Secret())
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	img, err := key.Image(mfaQRCodeSize, mfaQRCodeSize)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return &models.MFAEnrollment{
+		Secret:     key.Secret(),
+		OtpauthURI: key.URL(),
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// activate 校验待激活密钥的验证码，通过后启用二次验证并生成恢复码
+func (s *MFAService) activate(rec *models.UserMFA, code string) ([]string, error) {
+	step, ok := matchTOTP(rec.Secret, code, rec.LastUsedStep)
+	if !ok {
+		return nil, ErrMFAInvalidCode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.mfaRepo.Enable(rec.UserID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return codes, nil
+}
+
+// checkCode 校验已启用用户的 TOTP 验证码或恢复码 (两者均一次性使用)
+// 返回: string 验证方式, error 错误信息
+func (s *MFAService) checkCode(rec *models.UserMFA, code string) (string, error) {
+	code = normalizeMFACode(code)
+	if len(code) == totpDigits.Length() {
+		step, ok := matchTOTP(rec.Secret, code, rec.LastUsedStep)
+		if !ok {
+			return "", ErrMFAInvalidCode
+		}
+		// 条件更新防止并发请求重放同一验证码
+		used, err := s.mfaRepo.UseStep(rec.UserID, step)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return "", ErrMFAInvalidCode
+		}
+		return mfaMethodTOTP, nil
+	}
+
+	if code == "" {
+		return "", ErrMFAInvalidCode
+	}
+	used, err := s.mfaRepo.UseRecoveryCode(rec.UserID, utils.HashToken(code))
+	if err != nil {
+		return "", err
+	}
+	if !used {
+		return "", ErrMFAInvalidCode
+	}
+	return mfaMethodRecovery, nil
+}
+
+// matchTOTP 在前后各一个时间步的容差内校验验证码，跳过已使用过的时间步
+// 返回: int64 匹配的时间步, bool 是否匹配
+func matchTOTP(secret, code string, lastUsedStep int64) (int64, bool) {
+	return matchTOTPAt(secret, code, lastUsedStep, time.Now())
+}
+
+// matchTOTPAt 以 now 为当前时间校验验证码 (见 matchTOTP)
+func matchTOTPAt(secret, code string, lastUsedStep int64, now time.Time) (int64, bool) {
+	code = normalizeMFACode(code)
+	if len(code) != totpDigits.Length() {
+		return 0, false
+	}
+	opts := totp.ValidateOpts{Period: totpPeriod, Digits: totpDigits, Algorithm: otp.AlgorithmSHA1}
+	for _, skew := range []int64{0, -1, 1} {
+		t := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		step := t.Unix() / totpPeriod
+		if step <= lastUsedStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(secret, t, opts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes 生成恢复码明文 (xxxxx-xxxxx) 及其摘要
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.RandomHex(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, utils.HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeMFACode 去除空白与连字符并转小写，便于用户按显示格式输入
+func normalizeMFACode(code string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret RFC 6238 附录 B 的 SHA1 种子 "12345678901234567890" 的 Base32 编码
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestMatchTOTPRFC6238Vectors(t *testing.T) {
+	// 附录 B 给出 8 位验证码，6 位验证码取其后 6 位
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, v := range vectors {
+		step, ok := matchTOTPAt(rfc6238Secret, v.code, 0, time.Unix(v.unix, 0))
+		if !ok {
+			t.Errorf("T=%d: 验证码 %s 未通过", v.unix, v.code)
+			continue
+		}
+		if want := v.unix / totpPeriod; step != want {
+			t.Errorf("T=%d: 时间步 = %d, 期望 %d", v.unix, step, want)
+		}
+	}
+}
+
+func TestMatchTOTPNormalizesInput(t *testing.T) {
+	if _, ok := matchTOTPAt(rfc6238Secret, " 287 082 ", 0, time.Unix(59, 0)); !ok {
+		t.Error("带空格的验证码未通过")
+	}
+	for _, code := range []string{"", "28708", "2870820", "94287082", "abcdef"} {
+		if _, ok := matchTOTPAt(rfc6238Secret, code, 0, time.Unix(59, 0)); ok {
+			t.Errorf("错误的验证码 %q 通过了校验", code)
+		}
+	}
+}
+
+func TestMatchTOTPSkew(t *testing.T) {
+	// 287082 属于时间步 1 ([30, 60))，359152 属于时间步 2 ([60, 90))
+	tests := []struct {
+		name string
+		code string
+		unix int64
+		step int64
+		ok   bool
+	}{
+		{"当前时间步", "287082", 45, 1, true},
+		{"慢一个时间步", "287082", 89, 1, true},
+		{"快一个时间步", "287082", 29, 1, true},
+		{"慢两个时间步", "287082", 90, 0, false},
+		{"快两个时间步", "359152", 15, 0, false},
+	}
+	for _, tt := range tests {
+		step, ok := matchTOTPAt(rfc6238Secret, tt.code, -1, time.Unix(tt.unix, 0))
+		if ok != tt.ok || step != tt.step {
+			t.Errorf("%s: (%d, %v), 期望 (%d, %v)", tt.name, step, ok, tt.step, tt.ok)
+		}
+	}
+}
+
+func TestMatchTOTPRejectsReplay(t *testing.T) {
+	now := time.Unix(59, 0)
+	step, ok := matchTOTPAt(rfc6238Secret, "287082", 0, now)
+	if !ok {
+		t.Fatal("首次使用未通过")
+	}
+	if _, ok := matchTOTPAt(rfc6238Secret, "287082", step, now); ok {
+		t.Error("同一时间步的验证码被重复使用")
+	}
+	// 下一个时间步的验证码在容差内仍可使用
+	if _, ok := matchTOTPAt(rfc6238Secret, "359152", step, now); !ok {
+		t.Error("下一个时间步的验证码未通过")
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrNoOrganization 用户未加入任何组织
+	ErrNoOrganization = errors.New("NO_ORGANIZATION")
+	// ErrNotOrgMember 用户不是目标组织的成员
+	ErrNotOrgMember = errors.New("NOT_ORG_MEMBER")
+	// ErrOrgExists 组织名称已存在
+	ErrOrgExists = errors.New("ORG_EXISTS")
+	// ErrInvalidOrgName 组织名称不合法
+	ErrInvalidOrgName = errors.New("INVALID_ORG_NAME")
+	// ErrOrgNotEmpty 组织仍有成员
+	ErrOrgNotEmpty = errors.New("ORG_NOT_EMPTY")
+	// ErrDefaultOrg 默认组织不可删除
+	ErrDefaultOrg = errors.New("DEFAULT_ORG")
+)
+
+// OrgService 组织 (租户) 管理服务
+type OrgService struct {
+	orgRepo      *repository.OrgRepository
+	userRepo     *repository.UserRepository
+	rbacService  *RBACService
+	audit        *AuditService
+	defaultOrgID int64
+}
+
+// NewOrgService 创建组织服务实例
+// 参数: defaultOrgID 自助注册用户加入的默认组织 (来自配置，不可删除)
+func NewOrgService(orgRepo *repository.OrgRepository, userRepo *repository.UserRepository, rbacService *RBACService, audit *AuditService, defaultOrgID int64) *OrgService {
+	return &OrgService{orgRepo: orgRepo, userRepo: userRepo, rbacService: rbacService, audit: audit, defaultOrgID: defaultOrgID}
+}
+
+// ListOrgs 获取全部组织
+func (s *OrgService) ListOrgs() ([]models.Organization, error) {
+	return s.orgRepo.List()
+}
+
+// GetOrg 根据ID获取组织
+func (s *OrgService) GetOrg(id int64) (*models.Organization, error) {
+	return s.orgRepo.GetByID(id)
+}
+
+// CreateOrg 创建组织
+func (s *OrgService) CreateOrg(actor models.Actor, name string) (int64, error) {
+	name, err := s.checkName(name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := s.orgRepo.Create(name)
+	if err != nil {
+		return 0, err
+	}
+	actor.OrgID = id
+	s.audit.Record(actor, AuditOrgCreate, AuditTargetOrg, strconv.FormatInt(id, 10), nil, map[string]interface{}{"name": name})
+	return id, nil
+}
+
+// RenameOrg 修改组织名称
+func (s *OrgService) RenameOrg(actor models.Actor, id int64, name string) error {
+	before, err := s.orgRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	name, err = s.checkName(name)
+	if err != nil {
+		return err
+	}
+	if err := s.orgRepo.Rename(id, name); err != nil {
+		return err
+	}
+	actor.OrgID = id
+	s.audit.Record(actor, AuditOrgUpdate, AuditTargetOrg, strconv.FormatInt(id, 10),
+		map[string]interface{}{"name": before.Name}, map[string]interface{}{"name": name})
+	return nil
+}
+
+// DeleteOrg 删除组织 (默认组织与仍有成员的组织不可删除)
+func (s *OrgService) DeleteOrg(actor models.Actor, id int64) error {
+	if id == s.defaultOrgID {
+		return ErrDefaultOrg
+	}
+	org, err := s.orgRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	n, err := s.orgRepo.CountMembers(id)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrOrgNotEmpty
+	}
+	if _, err := s.orgRepo.Delete(id); err != nil {
+		return err
+	}
+	s.audit.Record(actor, AuditOrgDelete, AuditTargetOrg, strconv.FormatInt(id, 10), map[string]interface{}{"name": org.Name}, nil)
+	return nil
+}
+
+// Memberships 获取用户加入的全部组织
+func (s *OrgService) Memberships(userID int64) ([]models.OrgMembership, error) {
+	return s.orgRepo.ListByUser(userID)
+}
+
+// SetMember 将已有用户加入组织或修改其组织内角色
+func (s *OrgService) SetMember(actor models.Actor, orgID int64, req models.OrgMemberRequest) error {
+	if _, err := s.orgRepo.GetByID(orgID); err != nil {
+		return err
+	}
+	if _, err := s.userRepo.GetByID(req.UserID); err != nil {
+		return err
+	}
+	if exists, err := s.rbacService.RoleExists(req.Role); err != nil {
+		return err
+	} else if !exists {
+		return repository.ErrRoleNotFound
+	}
+
+	var before interface{}
+	if current, err := s.userRepo.GetByIDInOrg(orgID, req.UserID); err == nil {
+		before = map[string]interface{}{"role": current.Role}
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return err
+	}
+	if err := s.orgRepo.SetMember(orgID, req.UserID, req.Role); err != nil {
+		return err
+	}
+
+	// 成员变更记录在目标组织下，便于该组织管理员查阅
+	actor.OrgID = orgID
+	s.audit.Record(actor, AuditOrgMemberSet, AuditTargetUser, strconv.FormatInt(req.UserID, 10), before,
+		map[string]interface{}{"role": req.Role})
+	return nil
+}
+
+// RemoveMember 将用户移出组织 (账号保留)
+func (s *OrgService) RemoveMember(actor models.Actor, orgID, userID int64) error {
+	current, err := s.userRepo.GetByIDInOrg(orgID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrNotOrgMember
+		}
+		return err
+	}
+	affected, err := s.orgRepo.RemoveMember(orgID, userID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotOrgMember
+	}
+
+	actor.OrgID = orgID
+	s.audit.Record(actor, AuditOrgMemberRemove, AuditTargetUser, strconv.FormatInt(userID, 10),
+		map[string]interface{}{"role": current.Role}, nil)
+	return nil
+}
+
+// IsShared 用户是否同时属于多个组织 (其账号信息变更会影响其它组织)
+func (s *OrgService) IsShared(userID int64) (bool, error) {
+	n, err := s.orgRepo.CountMemberships(userID)
+	return n > 1, err
+}
+
+// checkName 校验组织名称并检查是否重名
+func (s *OrgService) checkName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return "", ErrInvalidOrgName
+	}
+	if _, err := s.orgRepo.GetByName(name); err == nil {
+		return "", ErrOrgExists
+	} else if !errors.Is(err, repository.ErrOrgNotFound) {
+		return "", err
+	}
+	return name, nil
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrPasswordMismatch 密码校验不通过
+	ErrPasswordMismatch = errors.New("PASSWORD_MISMATCH")
+	// ErrUnsupportedHash 无法识别的密码哈希格式
+	ErrUnsupportedHash = errors.New("UNSUPPORTED_HASH")
+)
+
+// PasswordHasher 密码哈希器接口
+// 负责生成密码哈希、校验明文密码，并判断已存储的哈希是否需要升级
+type PasswordHasher interface {
+	// Hash 生成密码哈希
+	Hash(password string) (string, error)
+	// Verify 校验明文密码与存储的哈希是否匹配
+	// 返回: needsRehash 是否需要使用当前参数重新哈希, error 不匹配时为 ErrPasswordMismatch
+	Verify(stored, password string) (needsRehash bool, err error)
+}
+
+// DefaultPasswordHasher 默认密码哈希器 (bcrypt)
+var DefaultPasswordHasher PasswordHasher = NewBcryptHasher(bcrypt.DefaultCost)
+
+// BcryptHasher 基于 bcrypt 的密码哈希器
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher 创建 bcrypt 哈希器实例
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+// Hash 生成 bcrypt 哈希
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Verify 校验密码，兼容 argon2id 哈希与历史明文密码
+func (h *BcryptHasher) Verify(stored, password string) (bool, error) {
+	if !isBcryptHash(stored) {
+		// 非 bcrypt 格式：校验通过后一律升级为当前算法
+		if err := verifyForeign(stored, password); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, ErrPasswordMismatch
+		}
+		return false, err
+	}
+
+	cost, err := bcrypt.Cost([]byte(stored))
+	if err != nil {
+		return false, err
+	}
+	return cost != h.cost, nil
+}
+
+// Argon2idHasher 基于 argon2id 的密码哈希器
+type Argon2idHasher struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	keyLen  uint32
+	saltLen int
+}
+
+// NewArgon2idHasher 创建 argon2id 哈希器实例
+// 参数: time 迭代次数, memory 内存占用(KiB), threads 并行度
+func NewArgon2idHasher(time, memory uint32, threads uint8) *Argon2idHasher {
+	return &Argon2idHasher{time: time, memory: memory, threads: threads, keyLen: 32, saltLen: 16}
+}
+
+// Hash 生成 PHC 格式的 argon2id 哈希
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.time, h.memory, h.threads, h.keyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.memory, h.time, h.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 校验密码，兼容 bcrypt 哈希与历史明文密码
+func (h *Argon2idHasher) Verify(stored, password string) (bool, error) {
+	p, err := parseArgon2id(stored)
+	if err != nil {
+		if err := verifyForeign(stored, password); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	key := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	if subtle.ConstantTimeCompare(key, p.key) != 1 {
+		return false, ErrPasswordMismatch
+	}
+	return p.time != h.time || p.memory != h.memory || p.threads != h.threads, nil
+}
+
+// argon2Params 解析后的 argon2id 哈希参数
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2id 解析 $argon2id$v=19$m=65536,t=3,p=2$salt$key 格式的哈希
+func parseArgon2id(stored string) (*argon2Params, error) {
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return nil, ErrUnsupportedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnsupportedHash
+	}
+	p := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, ErrUnsupportedHash
+	}
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnsupportedHash
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, ErrUnsupportedHash
+	}
+	return p, nil
+}
+
+// isBcryptHash 判断是否为格式完整的 bcrypt 哈希 (仅前缀相同的明文密码不算)
+func isBcryptHash(stored string) bool {
+	if !strings.HasPrefix(stored, "$2a$") && !strings.HasPrefix(stored, "$2b$") && !strings.HasPrefix(stored, "$2y$") {
+		return false
+	}
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
+
+// verifyForeign 校验非当前算法的存储值：其它已知哈希算法或历史明文
+// 无法识别为哈希的值 (包括以 $ 开头的明文密码) 一律按明文比较
+func verifyForeign(stored, password string) error {
+	if isBcryptHash(stored) {
+		if bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) != nil {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+	if _, err := parseArgon2id(stored); err == nil {
+		_, err := NewArgon2idHasher(1, 64*1024, 1).Verify(stored, password)
+		return err
+	}
+
+	// 历史明文密码：使用常量时间比较
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(password)) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxPasswordBytes bcrypt 只接受不超过 72 字节的密码
+const MaxPasswordBytes = 72
+
+// ErrPasswordPolicy 密码不符合安全策略
+var ErrPasswordPolicy = errors.New("PASSWORD_POLICY")
+
+// 密码策略违规代码
+const (
+	ViolationTooShort         = "too_short"
+	ViolationTooLong          = "too_long"
+	ViolationMissingUpper     = "missing_upper"
+	ViolationMissingLower     = "missing_lower"
+	ViolationMissingDigit     = "missing_digit"
+	ViolationMissingSymbol    = "missing_symbol"
+	ViolationBlocklisted      = "blocklisted"
+	ViolationContainsUsername = "contains_username"
+	ViolationReused           = "reused"
+)
+
+// PolicyError 密码策略校验失败详情 (errors.Is(err, ErrPasswordPolicy) 为真)
+type PolicyError struct {
+	Violations []models.PasswordViolation
+}
+
+func (e *PolicyError) Error() string {
+	codes := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		codes[i] = v.Code
+	}
+	return ErrPasswordPolicy.Error() + ": " + strings.Join(codes, ",")
+}
+
+// Unwrap 使 PolicyError 可与 ErrPasswordPolicy 比较
+func (e *PolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
+
+// PasswordPolicy 密码策略；HistoryCount、MaxAge 为 0 表示关闭对应规则
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	HistoryCount  int
+	MaxAge        time.Duration
+}
+
+// commonPasswords 内置的常见弱密码 (小写)，可通过黑名单文件补充
+var commonPasswords = []string{
+	"123456", "1234567", "12345678", "123456789", "1234567890", "111111", "000000", "666666", "888888",
+	"123123", "654321", "112233", "121212", "abc123", "abcd1234", "a123456", "qwerty", "qwerty123",
+	"qwertyuiop", "1q2w3e4r", "1qaz2wsx", "asdfgh", "zxcvbnm", "password", "password1", "password123",
+	"passw0rd", "p@ssw0rd", "iloveyou", "admin", "admin123", "administrator", "root", "welcome",
+	"letmein", "monkey", "dragon", "sunshine", "football", "baseball", "superman", "woaini", "woaini1314",
+	"5201314", "changeme", "secret", "test123", "guest",
+}
+
+// LoadPasswordBlocklist 读取密码黑名单文件 (每行一个，忽略空行与 # 开头的注释)
+// 参数: path 文件路径，为空时返回空列表
+// 返回: []string 黑名单, error 错误信息
+func LoadPasswordBlocklist(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
+
+// PasswordPolicyService 密码策略服务：注册、管理员创建、本人修改与找回密码统一在此校验
+type PasswordPolicyService struct {
+	policy      PasswordPolicy
+	blocklist   map[string]struct{}
+	historyRepo *repository.PasswordHistoryRepository
+	hasher      PasswordHasher
+}
+
+// NewPasswordPolicyService 创建密码策略服务实例
+// 参数: blocklist 额外的黑名单 (与内置常见弱密码合并，不区分大小写)
+func NewPasswordPolicyService(policy PasswordPolicy, blocklist []string, historyRepo *repository.PasswordHistoryRepository, hasher PasswordHasher) *PasswordPolicyService {
+	words := make(map[string]struct{}, len(commonPasswords)+len(blocklist))
+	for _, list := range [][]string{commonPasswords, blocklist} {
+		for _, w := range list {
+			words[strings.ToLower(w)] = struct{}{}
+		}
+	}
+	return &PasswordPolicyService{policy: policy, blocklist: words, historyRepo: historyRepo, hasher: hasher}
+}
+
+// Info 返回对外公开的策略描述
+func (s *PasswordPolicyService) Info() models.PasswordPolicyInfo {
+	return models.PasswordPolicyInfo{
+		MinLength:     s.policy.MinLength,
+		MaxLength:     s.policy.MaxLength,
+		RequireUpper:  s.policy.RequireUpper,
+		RequireLower:  s.policy.RequireLower,
+		RequireDigit:  s.policy.RequireDigit,
+		RequireSymbol: s.policy.RequireSymbol,
+		HistoryCount:  s.policy.HistoryCount,
+		MaxAgeDays:    int(s.policy.MaxAge / (24 * time.Hour)),
+	}
+}
+
+// Validate 校验新账号的密码 (长度、字符类别、黑名单、是否包含用户名)
+// 参数: username 用户名, password 明文密码
+// 返回: error 不符合策略时为 *PolicyError
+func (s *PasswordPolicyService) Validate(username, password string) error {
+	if v := s.violations(username, password); len(v) > 0 {
+		return &PolicyError{Violations: v}
+	}
+	return nil
+}
+
+// Check 校验已有账号的新密码：在 Validate 的基础上禁止与当前及最近使用过的密码相同
+// 参数: user 用户对象 (须含当前密码哈希), password 新的明文密码
+// 返回: error 不符合策略时为 *PolicyError
+func (s *PasswordPolicyService) Check(user *models.User, password string) error {
+	v := s.violations(user.Username, password)
+	if s.policy.HistoryCount > 0 {
+		reused, err := s.reused(user, password)
+		if err != nil {
+			return err
+		}
+		if reused {
+			v = append(v, models.PasswordViolation{
+				Code:    ViolationReused,
+				Message: fmt.Sprintf("不能与最近 %d 次使用过的密码相同", s.policy.HistoryCount),
+			})
+		}
+	}
+	if len(v) > 0 {
+		return &PolicyError{Violations: v}
+	}
+	return nil
+}
+
+// Remember 密码修改成功后保存被替换的旧哈希 (失败仅记录日志)
+// 参数: userID 用户ID, oldHash 修改前的密码哈希
+func (s *PasswordPolicyService) Remember(userID int64, oldHash string) {
+	if s.policy.HistoryCount <= 1 || oldHash == "" {
+		return
+	}
+	// 当前密码本身计入最近 N 次，历史表只需保留 N-1 个旧密码
+	if err := s.historyRepo.Add(userID, oldHash, s.policy.HistoryCount-1); err != nil {
+		utils.AuthLogger.Error("保存用户 %d 的历史密码失败: %v", userID, err)
+	}
+}
+
+// generatedPasswordLength 自动生成密码的默认长度 (受策略最小、最大长度约束)
+const generatedPasswordLength = 16
+
+// 自动生成密码使用的字符集 (去掉了 0/O、1/l/I 等易混淆字符)
+const (
+	generatedUpper  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	generatedLower  = "abcdefghijkmnopqrstuvwxyz"
+	generatedDigit  = "23456789"
+	generatedSymbol = "!@#$%^&*-_=+?"
+)
+
+// Generate 生成符合当前策略的随机密码 (用于批量导入等无法由用户设置密码的场景)
+// 参数: username 用户名 (生成结果不会包含用户名)
+// 返回: string 密码, error 错误信息
+func (s *PasswordPolicyService) Generate(username string) (string, error) {
+	n := generatedPasswordLength
+	if n < s.policy.MinLength {
+		n = s.policy.MinLength
+	}
+	if n > s.policy.MaxLength {
+		n = s.policy.MaxLength
+	}
+
+	// 每类必需字符至少一个，其余从全部字符中随机选取
+	classes := []string{generatedUpper, generatedLower, generatedDigit}
+	if s.policy.RequireSymbol {
+		classes = append(classes, generatedSymbol)
+	}
+	all := strings.Join(classes, "")
+	for attempt := 0; attempt < 10; attempt++ {
+		buf := make([]byte, n)
+		for i := range buf {
+			set := all
+			if i < len(classes) {
+				set = classes[i]
+			}
+			c, err := randomChar(set)
+			if err != nil {
+				return "", err
+			}
+			buf[i] = c
+		}
+		if err := shuffleBytes(buf); err != nil {
+			return "", err
+		}
+		if pw := string(buf); len(s.violations(username, pw)) == 0 {
+			return pw, nil
+		}
+	}
+	return "", errors.New("无法生成符合密码策略的随机密码")
+}
+
+// randomChar 从字符集中均匀随机选取一个字符
+func randomChar(set string) (byte, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		return 0, err
+	}
+	return set[i.Int64()], nil
+}
+
+// shuffleBytes 随机打乱字节顺序 (Fisher-Yates)
+func shuffleBytes(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return err
+		}
+		b[i], b[j.Int64()] = b[j.Int64()], b[i]
+	}
+	return nil
+}
+
+// Expired 判断密码是否已超过最长有效期
+// 参数: changedAt 密码最近一次修改时间 (零值表示未知，视为未过期)
+func (s *PasswordPolicyService) Expired(changedAt time.Time) bool {
+	if s.policy.MaxAge <= 0 || changedAt.IsZero() {
+		return false
+	}
+	return time.Since(changedAt) > s.policy.MaxAge
+}
+
+// violations 逐条检查与账号历史无关的规则，返回全部违规项
+func (s *PasswordPolicyService) violations(username, password string) []models.PasswordViolation {
+	var v []models.PasswordViolation
+	add := func(code, format string, args ...interface{}) {
+		v = append(v, models.PasswordViolation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	n := utf8.RuneCountInString(password)
+	if n < s.policy.MinLength {
+		add(ViolationTooShort, "密码长度不能少于 %d 位", s.policy.MinLength)
+	}
+	if n > s.policy.MaxLength || len(password) > MaxPasswordBytes {
+		add(ViolationTooLong, "密码长度不能超过 %d 位", s.policy.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if s.policy.RequireUpper && !upper {
+		add(ViolationMissingUpper, "密码须包含大写字母")
+	}
+	if s.policy.RequireLower && !lower {
+		add(ViolationMissingLower, "密码须包含小写字母")
+	}
+	if s.policy.RequireDigit && !digit {
+		add(ViolationMissingDigit, "密码须包含数字")
+	}
+	if s.policy.RequireSymbol && !symbol {
+		add(ViolationMissingSymbol, "密码须包含特殊符号")
+	}
+
+	lowered := strings.ToLower(password)
+	if _, ok := s.blocklist[lowered]; ok {
+		add(ViolationBlocklisted, "密码过于常见，容易被猜到")
+	}
+	// 过短的用户名容易误伤，只检查 3 个字符以上的
+	if name := strings.ToLower(username); utf8.RuneCountInString(name) >= 3 && strings.Contains(lowered, name) {
+		add(ViolationContainsUsername, "密码不能包含用户名")
+	}
+	return v
+}
+
+// reused 判断新密码是否与当前密码或最近的历史密码相同
+func (s *PasswordPolicyService) reused(user *models.User, password string) (bool, error) {
+	hashes := []string{user.Password}
+	if s.policy.HistoryCount > 1 {
+		recent, err := s.historyRepo.Recent(user.ID, s.policy.HistoryCount-1)
+		if err != nil {
+			return false, err
+		}
+		hashes = append(hashes, recent...)
+	}
+	for _, h := range hashes {
+		if h == "" {
+			continue
+		}
+		// 无法解析的旧哈希视为不匹配
+		if _, err := s.hasher.Verify(h, password); err == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"GoWork_7/internal/mailer"
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PasswordResetTTL 密码重置令牌有效期
+const PasswordResetTTL = 30 * time.Minute
+
+// 同一用户每小时最多申请的重置邮件数量
+const (
+	passwordResetLimit       = 5
+	passwordResetLimitWindow = time.Hour
+)
+
+// ErrResetTokenInvalid 重置令牌无效、过期或已使用
+var ErrResetTokenInvalid = errors.New("RESET_TOKEN_INVALID")
+
+// PasswordExpiredError 密码已超过最长有效期：登录时不签发正式令牌，改为返回一次性的重置令牌
+// 客户端凭 Token 提交新密码至 POST /api/auth/reset-password，再使用新密码重新登录
+type PasswordExpiredError struct {
+	Token     string
+	ExpiresIn int64
+}
+
+func (e *PasswordExpiredError) Error() string {
+	return "PASSWORD_EXPIRED"
+}
+
+// PasswordResetService 找回密码服务：签发一次性重置令牌并通过邮件发送，凭令牌设置新密码
+type PasswordResetService struct {
+	userRepo     *repository.UserRepository
+	resetRepo    *repository.PasswordResetRepository
+	hasher       PasswordHasher
+	policy       *PasswordPolicyService
+	tokenService *TokenService
+	lockout      *LockoutService
+	mailer       mailer.Mailer
+	audit        *AuditService
+	publicURL    string
+}
+
+// NewPasswordResetService 创建找回密码服务实例
+// 参数: publicURL 对外访问地址，用于生成邮件中的重置链接
+func NewPasswordResetService(userRepo *repository.UserRepository, resetRepo *repository.PasswordResetRepository, hasher PasswordHasher,
+	policy *PasswordPolicyService, tokenService *TokenService, lockout *LockoutService, m mailer.Mailer, audit *AuditService, publicURL string) *PasswordResetService {
+	return &PasswordResetService{
+		userRepo:     userRepo,
+		resetRepo:    resetRepo,
+		hasher:       hasher,
+		policy:       policy,
+		tokenService: tokenService,
+		lockout:      lockout,
+		mailer:       m,
+		audit:        audit,
+		publicURL:    publicURL,
+	}
+}
+
+// RequestReset 申请重置密码
+// 无论账号是否存在都返回 nil，且邮件异步发送，避免通过响应内容或耗时枚举用户名
+// 参数: actor 请求来源, login 用户名或邮箱
+// 返回: error 仅在数据库异常时返回
+func (s *PasswordResetService) RequestReset(actor models.Actor, login string) error {
+	user, err := s.userRepo.GetByLogin(login)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !user.Enable {
+		return nil
+	}
+	to, ok := s.recipient(user)
+	if !ok {
+		utils.AuthLogger.Info("用户 %d 没有已验证的邮箱地址，忽略密码重置申请", user.ID)
+		return nil
+	}
+
+	n, err := s.resetRepo.CountSince(user.ID, time.Now().Add(-passwordResetLimitWindow))
+	if err != nil {
+		return err
+	}
+	if n >= passwordResetLimit {
+		utils.AuthLogger.Info("用户 %d 的密码重置申请过于频繁，已忽略", user.ID)
+		return nil
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := s.resetRepo.Create(user.ID, utils.HashToken(token), time.Now().Add(PasswordResetTTL), actor.IP); err != nil {
+		return err
+	}
+
+	actor.UserID, actor.Username = user.ID, user.Username
+	s.audit.Record(actor, AuditPasswordResetRequest, AuditTargetUser, strconv.FormatInt(user.ID, 10), nil, nil)
+
+	msg := mailer.Message{
+		To:      to,
+		Subject: "重置密码",
+		Body: fmt.Sprintf("%s，您好：\n\n我们收到了重置您账号密码的申请。请在 %d 分钟内打开以下链接设置新密码：\n\n%s\n\n"+
+			"该链接只能使用一次。如果不是您本人操作，请忽略本邮件，您的密码不会被修改。\n",
+			user.Username, int(PasswordResetTTL.Minutes()), s.resetLink(token)),
+	}
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			utils.SystemLogger.Error("发送密码重置邮件给用户 %d 失败: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// CheckExpired 签发正式令牌前检查密码是否已过期；已过期时签发一次性重置令牌 (同时作废此前未使用的重置令牌)
+// 调用方须已完成密码及二次验证，重置令牌不经邮件直接返回给客户端
+// 参数: actor 请求来源, user 登录的用户
+// 返回: error 未过期为 nil，已过期为 *PasswordExpiredError
+func (s *PasswordResetService) CheckExpired(actor models.Actor, user *models.User) error {
+	if !s.policy.Expired(user.PasswordChangedAt) {
+		return nil
+	}
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := s.resetRepo.Create(user.ID, utils.HashToken(token), time.Now().Add(PasswordResetTTL), actor.IP); err != nil {
+		return err
+	}
+	return &PasswordExpiredError{Token: token, ExpiresIn: int64(PasswordResetTTL.Seconds())}
+}
+
+// ResetPassword 使用重置令牌设置新密码；成功后撤销该用户的全部会话并解除登录锁定
+// 新密码不符合策略时令牌不会被消耗，用户可修改后重新提交
+// 参数: actor 请求来源, token 邮件中的重置令牌, newPassword 新密码
+// 返回: error 错误信息 (令牌无效时为 ErrResetTokenInvalid, 密码不符合策略时为 *PolicyError)
+func (s *PasswordResetService) ResetPassword(actor models.Actor, token, newPassword string) error {
+	if token == "" {
+		return ErrResetTokenInvalid
+	}
+	tokenHash := utils.HashToken(token)
+	userID, err := s.resetRepo.Find(tokenHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrResetTokenNotFound) {
+			return ErrResetTokenInvalid
+		}
+		return err
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrResetTokenInvalid
+		}
+		return err
+	}
+	if err := s.policy.Check(user, newPassword); err != nil {
+		return err
+	}
+
+	// 校验通过后才消耗令牌；并发请求中只有一个能成功
+	if _, err := s.resetRepo.Consume(tokenHash); err != nil {
+		if errors.Is(err, repository.ErrResetTokenNotFound) {
+			return ErrResetTokenInvalid
+		}
+		return err
+	}
+
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.ChangePassword(user.ID, hash); err != nil {
+		return err
+	}
+	s.policy.Remember(user.ID, user.Password)
+
+	// 密码已变更：旧会话全部失效，并清除此前的登录失败计数
+	revoked, err := s.tokenService.RevokeAllSessions(user.ID)
+	if err != nil {
+		utils.AuthLogger.Error("密码重置后撤销用户 %d 的会话失败: %v", user.ID, err)
+	}
+	s.lockout.RecordSuccess(user.Username)
+
+	actor.UserID, actor.Username = user.ID, user.Username
+	s.audit.Record(actor, AuditPasswordReset, AuditTargetUser, strconv.FormatInt(user.ID, 10), nil,
+		map[string]interface{}{"password_changed": true, "refresh_tokens_revoked": revoked})
+	return nil
+}
+
+// recipient 确定重置邮件的收件地址 (仅发送到已验证的邮箱，避免重置链接落入填错的地址)
+func (s *PasswordResetService) recipient(user *models.User) (string, bool) {
+	if user.Email == "" || !user.EmailVerified {
+		return "", false
+	}
+	return user.Email, true
+}
+
+// resetLink 生成重置页面链接
+func (s *PasswordResetService) resetLink(token string) string {
+	return s.publicURL + "/html/reset-password.html?token=" + url.QueryEscape(token)
+}
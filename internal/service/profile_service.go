@@ -0,0 +1,126 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"errors"
+	"strconv"
+)
+
+// ErrCurrentPasswordInvalid 修改密码时当前密码不正确
+var ErrCurrentPasswordInvalid = errors.New("CURRENT_PASSWORD_INVALID")
+
+// ProfileService 个人资料服务：用户查看和修改自己的资料与密码
+// 只开放白名单内的字段，角色、状态等仍需管理员通过用户管理接口修改
+type ProfileService struct {
+	userRepo     *repository.UserRepository
+	hasher       PasswordHasher
+	policy       *PasswordPolicyService
+	verifier     *EmailVerificationService
+	lockout      *LockoutService
+	tokenService *TokenService
+	audit        *AuditService
+}
+
+// NewProfileService 创建个人资料服务实例
+func NewProfileService(userRepo *repository.UserRepository, hasher PasswordHasher, policy *PasswordPolicyService, verifier *EmailVerificationService,
+	lockout *LockoutService, tokenService *TokenService, audit *AuditService) *ProfileService {
+	return &ProfileService{
+		userRepo:     userRepo,
+		hasher:       hasher,
+		policy:       policy,
+		verifier:     verifier,
+		lockout:      lockout,
+		tokenService: tokenService,
+		audit:        audit,
+	}
+}
+
+// Get 获取当前登录用户在当前组织内的资料
+func (s *ProfileService) Get(actor models.Actor) (*models.User, error) {
+	return s.userRepo.GetByIDInOrg(actor.OrgID, actor.UserID)
+}
+
+// PasswordExpired 判断用户密码是否已超过最长使用期限
+func (s *ProfileService) PasswordExpired(user *models.User) bool {
+	return s.policy.Expired(user.PasswordChangedAt)
+}
+
+// UpdateProfile 修改本人资料 (目前仅邮箱)；邮箱变更后清除验证状态并发送验证链接
+// 返回: *models.User 修改后的资料, error 错误信息 (邮箱格式错误为 ErrInvalidEmail, 已被占用为 ErrEmailExists)
+func (s *ProfileService) UpdateProfile(actor models.Actor, req models.ProfileUpdateRequest) (*models.User, error) {
+	before, err := s.Get(actor)
+	if err != nil {
+		return nil, err
+	}
+	if req.Email == nil {
+		return before, nil
+	}
+
+	email := *req.Email
+	if email != "" {
+		if email, err = checkEmail(s.userRepo, email, before.ID); err != nil {
+			return nil, err
+		}
+	}
+	if email == before.Email {
+		return before, nil
+	}
+	if err := s.userRepo.UpdateEmail(before.ID, email); err != nil {
+		return nil, err
+	}
+
+	after := *before
+	after.Email, after.EmailVerified = email, false
+	s.audit.Record(actor, AuditProfileUpdate, AuditTargetUser, strconv.FormatInt(before.ID, 10),
+		map[string]interface{}{"email": before.Email}, map[string]interface{}{"email": email})
+	if err := s.verifier.Send(actor, &after); err != nil {
+		utils.UserLogger.Error("向用户 %d 发送邮箱验证链接失败: %v", after.ID, err)
+	}
+	return &after, nil
+}
+
+// ChangePassword 校验当前密码后修改本人密码；成功后撤销该用户的全部会话，需重新登录
+// 当前密码错误计入登录失败次数，防止借此接口暴力猜测密码
+// 返回: error 错误信息 (当前密码错误为 ErrCurrentPasswordInvalid, 锁定中为 *LockedError, 新密码不符合策略为 *PolicyError)
+func (s *ProfileService) ChangePassword(actor models.Actor, currentPassword, newPassword string) error {
+	user, err := s.Get(actor)
+	if err != nil {
+		return err
+	}
+	if err := s.lockout.Check(user.Username, actor.IP); err != nil {
+		return err
+	}
+	if _, err := s.hasher.Verify(user.Password, currentPassword); err != nil {
+		if !errors.Is(err, ErrPasswordMismatch) {
+			return err
+		}
+		if locked := s.lockout.RecordFailure(user.Username, actor.IP); locked != nil {
+			return locked
+		}
+		return ErrCurrentPasswordInvalid
+	}
+	if err := s.policy.Check(user, newPassword); err != nil {
+		return err
+	}
+
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.ChangePassword(user.ID, hash); err != nil {
+		return err
+	}
+	s.policy.Remember(user.ID, user.Password)
+	s.lockout.RecordSuccess(user.Username)
+
+	// 密码已变更：包括当前会话在内的全部令牌失效，客户端需使用新密码重新登录
+	revoked, err := s.tokenService.RevokeAllSessions(user.ID)
+	if err != nil {
+		utils.AuthLogger.Error("修改密码后撤销用户 %d 的会话失败: %v", user.ID, err)
+	}
+	s.audit.Record(actor, AuditPasswordChange, AuditTargetUser, strconv.FormatInt(user.ID, 10), nil,
+		map[string]interface{}{"password_changed": true, "refresh_tokens_revoked": revoked})
+	return nil
+}
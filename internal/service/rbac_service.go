@@ -0,0 +1,331 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"errors"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 内置权限
+const (
+	PermUsersRead      = "users:read"
+	PermUsersCreate    = "users:create"
+	PermUsersUpdate    = "users:update"
+	PermUsersDelete    = "users:delete"
+	PermUsersExport    = "users:export"
+	PermSessionsRevoke = "sessions:revoke"
+	PermRolesManage    = "roles:manage"
+	PermOrgsManage     = "orgs:manage"
+	PermAuditRead      = "audit:read"
+)
+
+// permissionCacheTTL 权限缓存有效期 (多实例部署时其它实例的修改最迟在此时间后生效)
+const permissionCacheTTL = time.Minute
+
+var (
+	// ErrRoleExists 角色已存在
+	ErrRoleExists = errors.New("ROLE_EXISTS")
+	// ErrRoleInUse 角色仍被用户使用
+	ErrRoleInUse = errors.New("ROLE_IN_USE")
+	// ErrSystemRole 内置角色不可删除，其权限不可修改
+	ErrSystemRole = errors.New("SYSTEM_ROLE")
+	// ErrRoleEscalation 角色的权限超出操作者自身权限
+	ErrRoleEscalation = errors.New("ROLE_ESCALATION")
+	// ErrInvalidRoleName 角色名不合法
+	ErrInvalidRoleName = errors.New("INVALID_ROLE_NAME")
+)
+
+// roleNameRe 角色名规则：小写字母开头，可包含小写字母、数字、下划线与连字符
+var roleNameRe = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+// RBACService 基于角色的权限服务
+type RBACService struct {
+	roleRepo *repository.RoleRepository
+	audit    *AuditService
+
+	mu       sync.RWMutex
+	grants   map[string]map[string]bool
+	loadedAt time.Time
+}
+
+// NewRBACService 创建权限服务实例
+func NewRBACService(roleRepo *repository.RoleRepository, audit *AuditService) *RBACService {
+	return &RBACService{roleRepo: roleRepo, audit: audit}
+}
+
+// HasPermission 判断角色是否拥有指定权限
+func (s *RBACService) HasPermission(role, permission string) bool {
+	grants := s.loadGrants()
+	return grants[role][permission]
+}
+
+// Permissions 获取角色拥有的全部权限 (用于前端按权限渲染)
+func (s *RBACService) Permissions(role string) []string {
+	grants := s.loadGrants()
+	perms := make([]string, 0, len(grants[role]))
+	for p := range grants[role] {
+		perms = append(perms, p)
+	}
+	sort.Strings(perms)
+	return perms
+}
+
+// CanAssign 判断操作者能否授予目标角色：目标角色的权限必须是操作者权限的子集，防止越权提升
+func (s *RBACService) CanAssign(operatorRole, targetRole string) bool {
+	grants := s.loadGrants()
+	for p := range grants[targetRole] {
+		if !grants[operatorRole][p] {
+			return false
+		}
+	}
+	return true
+}
+
+// CanManageUser 判断操作者能否删除、恢复或彻底删除目标角色的用户
+// 目标角色的权限须为操作者权限的子集；同样拥有 users:delete 的其他管理员仅平台管理员 (orgs:manage) 可删除
+func (s *RBACService) CanManageUser(operatorRole, targetRole string) bool {
+	if !s.CanAssign(operatorRole, targetRole) {
+		return false
+	}
+	return !s.HasPermission(targetRole, PermUsersDelete) || s.HasPermission(operatorRole, PermOrgsManage)
+}
+
+// RoleExists 判断角色是否存在
+func (s *RBACService) RoleExists(name string) (bool, error) {
+	_, err := s.roleRepo.GetByName(name)
+	if errors.Is(err, repository.ErrRoleNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MFARequired 判断角色是否要求登录二次验证
+func (s *RBACService) MFARequired(role string) (bool, error) {
+	r, err := s.roleRepo.GetByName(role)
+	if err != nil {
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return r.MFARequired, nil
+}
+
+// ListRoles 获取全部角色
+func (s *RBACService) ListRoles() ([]models.Role, error) {
+	return s.roleRepo.List()
+}
+
+// ListPermissions 获取全部权限
+func (s *RBACService) ListPermissions() ([]models.Permission, error) {
+	return s.roleRepo.ListPermissions()
+}
+
+// CreateRole 创建自定义角色 (权限必须是操作者自身权限的子集)
+func (s *RBACService) CreateRole(actor models.Actor, req models.RoleRequest) (int64, error) {
+	if !roleNameRe.MatchString(req.Name) {
+		return 0, ErrInvalidRoleName
+	}
+	perms := uniqueStrings(req.Permissions)
+	if err := s.checkGrantable(actor.Role, perms); err != nil {
+		return 0, err
+	}
+	if exists, err := s.RoleExists(req.Name); err != nil {
+		return 0, err
+	} else if exists {
+		return 0, ErrRoleExists
+	}
+
+	id, err := s.roleRepo.Create(req.Name, req.Description, req.MFARequired, perms)
+	if err != nil {
+		return 0, err
+	}
+	s.invalidate()
+	s.audit.Record(actor, AuditRoleCreate, AuditTargetRole, req.Name, nil,
+		map[string]interface{}{"description": req.Description, "mfa_required": req.MFARequired, "permissions": sortedCopy(perms)})
+	return id, nil
+}
+
+// UpdateRole 修改角色描述、二次验证要求并整体替换权限
+// 角色为全局共享，操作者只能修改自己可授予的角色 (见 CanAssign)，新权限同样不能超出操作者自身权限；
+// 内置角色只能修改描述与二次验证要求，权限不可修改
+func (s *RBACService) UpdateRole(actor models.Actor, name string, req models.RoleRequest) error {
+	before, err := s.roleRepo.GetByName(name)
+	if err != nil {
+		return err
+	}
+	if !s.CanAssign(actor.Role, name) {
+		return ErrRoleEscalation
+	}
+	beforePerms, err := s.rolePermissions(name)
+	if err != nil {
+		return err
+	}
+	perms := uniqueStrings(req.Permissions)
+	if before.System && !sameStrings(beforePerms, perms) {
+		return ErrSystemRole
+	}
+	if err := s.checkGrantable(actor.Role, perms); err != nil {
+		return err
+	}
+	if err := s.roleRepo.Update(name, req.Description, req.MFARequired, perms); err != nil {
+		return err
+	}
+	s.invalidate()
+	s.audit.Record(actor, AuditRoleUpdate, AuditTargetRole, name,
+		map[string]interface{}{"description": before.Description, "mfa_required": before.MFARequired, "permissions": beforePerms},
+		map[string]interface{}{"description": req.Description, "mfa_required": req.MFARequired, "permissions": sortedCopy(perms)})
+	return nil
+}
+
+// DeleteRole 删除自定义角色 (内置角色与仍被使用的角色不可删除)
+func (s *RBACService) DeleteRole(actor models.Actor, name string) error {
+	role, err := s.roleRepo.GetByName(name)
+	if err != nil {
+		return err
+	}
+	if role.System {
+		return ErrSystemRole
+	}
+	n, err := s.roleRepo.CountUsers(name)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrRoleInUse
+	}
+	perms, err := s.rolePermissions(name)
+	if err != nil {
+		return err
+	}
+	if _, err := s.roleRepo.Delete(name); err != nil {
+		return err
+	}
+	s.invalidate()
+	s.audit.Record(actor, AuditRoleDelete, AuditTargetRole, name,
+		map[string]interface{}{"description": role.Description, "mfa_required": role.MFARequired, "permissions": perms}, nil)
+	return nil
+}
+
+// checkGrantable 校验操作者能否将这些权限授予角色：每个权限都必须存在且为操作者自身拥有
+// 返回: error 错误信息 (权限不存在为 repository.ErrPermissionNotFound, 超出操作者权限为 ErrRoleEscalation)
+func (s *RBACService) checkGrantable(operatorRole string, perms []string) error {
+	grants := s.loadGrants()
+	var missing []string
+	for _, p := range perms {
+		if !grants[operatorRole][p] {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	all, err := s.roleRepo.ListPermissions()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(all))
+	for _, p := range all {
+		known[p.Name] = true
+	}
+	for _, p := range missing {
+		if !known[p] {
+			return repository.ErrPermissionNotFound
+		}
+	}
+	return ErrRoleEscalation
+}
+
+// loadGrants 读取权限缓存，过期时从数据库重新加载；加载失败时沿用旧缓存 (首次失败则拒绝一切权限)
+func (s *RBACService) loadGrants() map[string]map[string]bool {
+	s.mu.RLock()
+	grants, fresh := s.grants, time.Since(s.loadedAt) < permissionCacheTTL
+	s.mu.RUnlock()
+	if grants != nil && fresh {
+		return grants
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.grants != nil && time.Since(s.loadedAt) < permissionCacheTTL {
+		return s.grants
+	}
+
+	byRole, err := s.roleRepo.PermissionsByRole()
+	if err != nil {
+		utils.SystemLogger.Error("加载角色权限失败: %v", err)
+		return s.grants
+	}
+	loaded := make(map[string]map[string]bool, len(byRole))
+	for role, perms := range byRole {
+		set := make(map[string]bool, len(perms))
+		for _, p := range perms {
+			set[p] = true
+		}
+		loaded[role] = set
+	}
+	s.grants, s.loadedAt = loaded, time.Now()
+	return loaded
+}
+
+// invalidate 使权限缓存失效
+func (s *RBACService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// rolePermissions 从数据库读取角色当前的权限 (按名称排序，绕过缓存，用于审计快照)
+func (s *RBACService) rolePermissions(name string) ([]string, error) {
+	byRole, err := s.roleRepo.PermissionsByRole()
+	if err != nil {
+		return nil, err
+	}
+	perms := byRole[name]
+	if perms == nil {
+		perms = []string{}
+	}
+	return perms, nil
+}
+
+// sortedCopy 返回排序后的副本
+func sortedCopy(items []string) []string {
+	out := append([]string(nil), items...)
+	sort.Strings(out)
+	return out
+}
+
+// sameStrings 判断两组字符串 (忽略顺序，均无重复) 是否相同
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, item := range a {
+		set[item] = true
+	}
+	for _, item := range b {
+		if !set[item] {
+			return false
+		}
+	}
+	return true
+}
+
+// uniqueStrings 去重并保持顺序
+func uniqueStrings(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -1,23 +1,63 @@
 package service
 
 import (
+	"GoWork_7/internal/models"
 	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"strconv"
 )
 
 // RegisterService 注册业务服务
 type RegisterService struct {
-	userRepo *repository.UserRepository
+	userRepo    *repository.UserRepository
+	hasher      PasswordHasher
+	policy      *PasswordPolicyService
+	verifier    *EmailVerificationService
+	audit       *AuditService
+	defaultRole string
+	orgID       int64
 }
 
 // NewRegisterService 创建注册服务实例
-func NewRegisterService(userRepo *repository.UserRepository) *RegisterService {
-	return &RegisterService{userRepo: userRepo}
+// 参数: defaultRole 自助注册用户的默认角色, orgID 自助注册用户加入的组织 (均来自配置)
+func NewRegisterService(userRepo *repository.UserRepository, hasher PasswordHasher, policy *PasswordPolicyService, verifier *EmailVerificationService,
+	audit *AuditService, defaultRole string, orgID int64) *RegisterService {
+	return &RegisterService{userRepo: userRepo, hasher: hasher, policy: policy, verifier: verifier, audit: audit, defaultRole: defaultRole, orgID: orgID}
 }
 
-// Register 处理注册业务逻辑
-// 参数: username 用户名, password 密码
-// 返回: int64 新用户ID, error 错误信息
-func (s *RegisterService) Register(username, password string) (int64, error) {
-	// 这里可以添加业务逻辑，比如校验用户名是否已存在、密码强度校验等
-	return s.userRepo.Create(username, password)
+// Register 处理注册业务逻辑：新账号处于待验证状态，并向注册邮箱发送验证链接，验证后方可登录
+// 参数: actor 请求来源 (IP/UserAgent), username 用户名, email 邮箱, password 密码
+// 返回: *models.User 新用户 (Email 已规范化), error 错误信息 (用户名不合法见 CheckUsername, 密码不符合策略为 *PolicyError,
+// 邮箱格式错误为 ErrInvalidEmail, 已被占用为 ErrEmailExists)
+func (s *RegisterService) Register(actor models.Actor, username, email, password string) (*models.User, error) {
+	if err := CheckUsername(username); err != nil {
+		return nil, err
+	}
+	if err := s.policy.Validate(username, password); err != nil {
+		return nil, err
+	}
+	email, err := checkEmail(s.userRepo, email, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{Username: username, Email: email, Password: hash, Role: s.defaultRole, OrgID: s.orgID, Status: models.UserStatusPending}
+	user.ID, err = s.userRepo.Create(s.orgID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// 自助注册的操作者即新用户本人
+	actor.UserID, actor.Username, actor.Role, actor.OrgID = user.ID, username, s.defaultRole, s.orgID
+	s.audit.Record(actor, AuditUserRegister, AuditTargetUser, strconv.FormatInt(user.ID, 10), nil, auditUser(user))
+
+	// 账号已创建：验证邮件发送失败不影响注册结果，用户可在验证页面重新发送
+	if err := s.verifier.Send(actor, user); err != nil {
+		utils.AuthLogger.Error("用户 %d 注册后生成验证邮件失败: %v", user.ID, err)
+	}
+	return user, nil
 }
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// RefreshTokenTTL 刷新令牌有效期
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrRefreshTokenInvalid 刷新令牌无效、过期或已被撤销
+	ErrRefreshTokenInvalid = errors.New("REFRESH_TOKEN_INVALID")
+	// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用，整个令牌家族已撤销
+	ErrRefreshTokenReused = errors.New("REFRESH_TOKEN_REUSED")
+)
+
+// TokenService 令牌签发与轮换服务
+type TokenService struct {
+	userRepo    *repository.UserRepository
+	refreshRepo *repository.RefreshTokenRepository
+	revocations repository.RevocationStore
+	audit       *AuditService
+}
+
+// NewTokenService 创建令牌服务实例
+func NewTokenService(userRepo *repository.UserRepository, refreshRepo *repository.RefreshTokenRepository, revocations repository.RevocationStore, audit *AuditService) *TokenService {
+	return &TokenService{userRepo: userRepo, refreshRepo: refreshRepo, revocations: revocations, audit: audit}
+}
+
+// IssueTokens 为用户签发访问令牌和新家族的刷新令牌 (用于登录)
+// 参数: user 用户对象 (Role/OrgID 须为当前组织内的角色与组织)
+func (s *TokenService) IssueTokens(user *models.User) (*models.TokenPair, error) {
+	familyID, err := utils.RandomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, _, err := s.createRefreshToken(user.ID, user.OrgID, familyID)
+	if err != nil {
+		return nil, err
+	}
+	return s.newPair(user, refreshToken)
+}
+
+// Refresh 轮换刷新令牌
+// 旧令牌被标记为已轮换；若已轮换的令牌再次出现，视为泄露并撤销整个家族
+// 参数: rawToken 客户端提交的刷新令牌
+// 返回: *models.User 用户对象, *models.TokenPair 新令牌对, error 错误信息
+func (s *TokenService) Refresh(rawToken string) (*models.User, *models.TokenPair, error) {
+	if rawToken == "" {
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	current, err := s.refreshRepo.GetByHash(utils.HashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil, nil, ErrRefreshTokenInvalid
+		}
+		return nil, nil, err
+	}
+
+	// 1. 重用检测：已轮换过的令牌再次被提交
+	if current.ReplacedBy != nil {
+		s.revokeFamily(current, "已轮换的刷新令牌被重复使用")
+		return nil, nil, ErrRefreshTokenReused
+	}
+	if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	// 2. 校验用户仍然有效且仍属于签发时的组织
+	user, err := s.userRepo.GetByIDInOrg(current.OrgID, current.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, nil, ErrRefreshTokenInvalid
+		}
+		return nil, nil, err
+	}
+	if !user.Enable {
+		return nil, nil, errors.New("ACCOUNT_DISABLED")
+	}
+
+	// 3. 在同一家族内签发新令牌，并以条件更新方式标记旧令牌
+	refreshToken, newID, err := s.createRefreshToken(user.ID, current.OrgID, current.FamilyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ok, err := s.refreshRepo.MarkRotated(current.ID, newID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		// 并发请求已抢先轮换该令牌，同样视为重用
+		s.revokeFamily(current, "刷新令牌被并发重复使用")
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	pair, err := s.newPair(user, refreshToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, pair, nil
+}
+
+// Logout 注销当前会话：撤销当前访问令牌，并撤销随请求提交的刷新令牌所属家族
+// 参数: userID 当前用户ID, jti 访问令牌ID, expiresAt 访问令牌过期时间, refreshToken 可选的刷新令牌
+func (s *TokenService) Logout(userID int64, jti string, expiresAt time.Time, refreshToken string) error {
+	ref, err := s.Session(userID, jti, expiresAt, refreshToken)
+	if err != nil {
+		return err
+	}
+	return s.RevokeSession(ref)
+}
+
+// Session 记录当前会话 (访问令牌及随请求提交的刷新令牌所属家族)，供稍后 RevokeSession 撤销
+// 参数: userID 当前用户ID, jti 访问令牌ID, expiresAt 访问令牌过期时间, refreshToken 可选的刷新令牌 (不属于该用户时忽略)
+func (s *TokenService) Session(userID int64, jti string, expiresAt time.Time, refreshToken string) (*utils.SessionRef, error) {
+	ref := &utils.SessionRef{JTI: jti, ExpiresAt: expiresAt.Unix()}
+	if refreshToken == "" {
+		return ref, nil
+	}
+	t, err := s.refreshRepo.GetByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return ref, nil
+		}
+		return nil, err
+	}
+	// 只允许注销属于自己的会话
+	if t.UserID == userID {
+		ref.FamilyID = t.FamilyID
+	}
+	return ref, nil
+}
+
+// RevokeSession 撤销会话的访问令牌及刷新令牌家族
+func (s *TokenService) RevokeSession(ref *utils.SessionRef) error {
+	if err := s.revocations.RevokeToken(ref.JTI, time.Unix(ref.ExpiresAt, 0)); err != nil {
+		return err
+	}
+	if ref.FamilyID == "" {
+		return nil
+	}
+	_, err := s.refreshRepo.RevokeFamily(ref.FamilyID)
+	return err
+}
+
+// RevokeUserSessions 撤销组织内用户的全部会话：此前签发的访问令牌与全部刷新令牌
+// 参数: actor 操作者, userID 目标用户ID (不属于操作者所在组织时返回 ErrUserNotFound)
+func (s *TokenService) RevokeUserSessions(actor models.Actor, userID int64) error {
+	if _, err := s.userRepo.GetByIDInOrg(actor.OrgID, userID); err != nil {
+		return err
+	}
+	affected, err := s.RevokeAllSessions(userID)
+	if err != nil {
+		return err
+	}
+	s.audit.Record(actor, AuditSessionsRevoke, AuditTargetUser, strconv.FormatInt(userID, 10), nil,
+		map[string]interface{}{"refresh_tokens_revoked": affected})
+	return nil
+}
+
+// RevokeAllSessions 撤销用户在所有组织中的全部会话 (用于密码重置等由用户本人触发的场景)
+// 返回: int64 撤销的刷新令牌数量, error 错误信息
+func (s *TokenService) RevokeAllSessions(userID int64) (int64, error) {
+	if err := s.revocations.RevokeUser(userID, time.Now().Truncate(time.Second)); err != nil {
+		return 0, err
+	}
+	return s.refreshRepo.RevokeByUser(userID)
+}
+
+// AwaitRevocationSecond 签发令牌前调用：令牌的签发时间 (iat) 只精确到秒，用户级撤销对撤销所在的整秒生效，
+// 当前这一秒内刚撤销过该用户的全部令牌时等到下一秒再返回，避免撤销后立即重新登录得到的令牌被视为已撤销
+func (s *TokenService) AwaitRevocationSecond(userID int64) error {
+	now := time.Now()
+	revoked, err := s.revocations.IsRevoked("", userID, now.Truncate(time.Second))
+	if err != nil || !revoked {
+		return err
+	}
+	time.Sleep(now.Truncate(time.Second).Add(time.Second).Sub(now))
+	return nil
+}
+
+// createRefreshToken 生成并保存刷新令牌，返回明文令牌及记录ID
+func (s *TokenService) createRefreshToken(userID, orgID int64, familyID string) (string, int64, error) {
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return "", 0, err
+	}
+	id, err := s.refreshRepo.Create(&models.RefreshToken{
+		UserID:    userID,
+		OrgID:     orgID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return raw, id, nil
+}
+
+// newPair 组装令牌对
+func (s *TokenService) newPair(user *models.User, refreshToken string) (*models.TokenPair, error) {
+	if err := s.AwaitRevocationSecond(user.ID); err != nil {
+		return nil, err
+	}
+	accessToken, err := utils.GenerateToken(user.ID, user.Username, user.Role, user.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeFamily 撤销令牌家族并记录安全日志
+func (s *TokenService) revokeFamily(t *models.RefreshToken, reason string) {
+	affected, err := s.refreshRepo.RevokeFamily(t.FamilyID)
+	if err != nil {
+		utils.AuthLogger.Error("撤销令牌家族 %s 失败: %v", t.FamilyID, err)
+		return
+	}
+	utils.AuthLogger.Error("%s，用户 %d 的令牌家族 %s 已撤销 (%d 个令牌)", reason, t.UserID, t.FamilyID, affected)
+}
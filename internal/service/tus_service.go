@@ -0,0 +1,389 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/storage"
+	"GoWork_7/internal/utils"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// tusPrefix 可续传上传在存储中的目录：uploads/{上传ID}/ 下保存各分片及合并后的文件
+const tusPrefix = "uploads/"
+
+// tusMaxMetadata Upload-Metadata 的最大长度 (与数据库列一致)
+const tusMaxMetadata = 4096
+
+var (
+	// ErrTusTooLarge 文件超过单个上传的大小上限，或请求体超出声明的总长度
+	ErrTusTooLarge = errors.New("TUS_TOO_LARGE")
+	// ErrTusInvalidMetadata Upload-Metadata 格式错误
+	ErrTusInvalidMetadata = errors.New("TUS_INVALID_METADATA")
+	// ErrTusOffsetMismatch 请求的 Upload-Offset 与已接收的字节数不一致
+	ErrTusOffsetMismatch = errors.New("TUS_OFFSET_MISMATCH")
+	// ErrTusExpired 未完成的上传已过期
+	ErrTusExpired = errors.New("TUS_EXPIRED")
+)
+
+// TusPolicy 可续传上传的限制
+type TusPolicy struct {
+	MaxSize int64         // 单个文件的大小上限 (字节)
+	Quota   int64         // 每个用户可占用的空间 (字节，含已完成及未过期的未完成上传)
+	Expiry  time.Duration // 未完成的上传在最后一次写入后的保留时长
+}
+
+// TusService 可续传上传服务 (tus 1.0)：每次 PATCH 收到的数据作为一个分片写入存储后端，全部接收后合并为一个文件
+// 分片与偏移量记录在数据库中，多实例部署时任一实例均可继续同一上传
+type TusService struct {
+	store  storage.Storage
+	repo   *repository.TusUploadRepository
+	policy TusPolicy
+}
+
+// NewTusService 创建可续传上传服务实例
+func NewTusService(store storage.Storage, repo *repository.TusUploadRepository, policy TusPolicy) *TusService {
+	return &TusService{store: store, repo: repo, policy: policy}
+}
+
+// MaxSize 单个文件的大小上限 (用于 Tus-Max-Size 响应头)
+func (s *TusService) MaxSize() int64 {
+	return s.policy.MaxSize
+}
+
+// Create 为操作者创建上传
+// 参数: length 文件总字节数 (Upload-Length), metadata Upload-Metadata 原文
+// 返回: *models.TusUpload 上传记录, error 错误信息 (超过大小上限为 ErrTusTooLarge, 超出配额为 repository.ErrTusQuotaExceeded)
+func (s *TusService) Create(ctx context.Context, actor models.Actor, length int64, metadata string) (*models.TusUpload, error) {
+	if length > s.policy.MaxSize {
+		return nil, ErrTusTooLarge
+	}
+	if !validTusMetadata(metadata) {
+		return nil, ErrTusInvalidMetadata
+	}
+	id, err := utils.RandomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	u := &models.TusUpload{
+		ID:        id,
+		UserID:    actor.UserID,
+		OrgID:     actor.OrgID,
+		Length:    length,
+		Metadata:  metadata,
+		ExpiresAt: time.Now().Add(s.policy.Expiry),
+	}
+	if err := s.repo.CreateWithinQuota(u, s.policy.Quota); err != nil {
+		return nil, err
+	}
+	// 空文件无需 PATCH，创建即完成
+	if length == 0 {
+		if err := s.complete(ctx, u); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// Get 查询操作者本人的上传
+// 返回: *models.TusUpload 上传记录, error 错误信息 (不存在或不属于操作者为 repository.ErrTusUploadNotFound, 未完成且已过期为 ErrTusExpired)
+func (s *TusService) Get(actor models.Actor, id string) (*models.TusUpload, error) {
+	u, err := s.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.UserID != actor.UserID {
+		return nil, repository.ErrTusUploadNotFound
+	}
+	if !u.Completed() && time.Now().After(u.ExpiresAt) {
+		return nil, ErrTusExpired
+	}
+	return u, nil
+}
+
+// Write 从 offset 处追加数据 (PATCH)
+// 请求体先写入本地临时文件，连接中断时已收到的部分同样保存，客户端可从新的偏移量继续；接收完全部数据后合并为最终文件
+// 返回: *models.TusUpload 更新后的上传记录, error 错误信息 (偏移量不一致为 ErrTusOffsetMismatch, 超出声明长度为 ErrTusTooLarge；
+// 读取请求体出错时返回已保存部分后的记录及该错误)
+func (s *TusService) Write(ctx context.Context, actor models.Actor, id string, offset int64, body io.Reader) (*models.TusUpload, error) {
+	u, err := s.Get(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != u.Offset {
+		return nil, ErrTusOffsetMismatch
+	}
+	if u.Completed() {
+		return u, nil
+	}
+
+	tmp, err := os.CreateTemp("", "tus-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	remaining := u.Length - u.Offset
+	n, readErr := io.Copy(tmp, io.LimitReader(body, remaining+1))
+	if n > remaining {
+		return nil, ErrTusTooLarge
+	}
+	if n > 0 {
+		if err := s.appendPart(ctx, u, tmp, n); err != nil {
+			return nil, err
+		}
+	}
+	if u.Offset == u.Length {
+		if err := s.complete(ctx, u); err != nil {
+			return nil, err
+		}
+	}
+	return u, readErr
+}
+
+// appendPart 将临时文件中的 n 个字节保存为分片并推进偏移量
+func (s *TusService) appendPart(ctx context.Context, u *models.TusUpload, tmp *os.File, n int64) error {
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	suffix, err := utils.RandomHex(4)
+	if err != nil {
+		return err
+	}
+	// 分片名包含随机后缀：并发写入同一偏移量时互不覆盖，未能登记的一方删除自己的分片
+	key := fmt.Sprintf("%s%s/part-%020d-%s", tusPrefix, u.ID, u.Offset, suffix)
+	if err := s.store.Put(ctx, key, tmp, n, "application/octet-stream"); err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.policy.Expiry)
+	err = s.repo.AppendPart(u.ID, repository.TusUploadPart{Offset: u.Offset, Size: n, StorageKey: key}, expiresAt)
+	if err != nil {
+		if delErr := s.store.Delete(ctx, key); delErr != nil {
+			utils.UserLogger.Error("删除未登记的上传分片 %s 失败: %v", key, delErr)
+		}
+		if errors.Is(err, repository.ErrTusOffsetConflict) {
+			return ErrTusOffsetMismatch
+		}
+		return err
+	}
+	u.Offset += n
+	u.ExpiresAt = expiresAt
+	return nil
+}
+
+// complete 按偏移量顺序合并全部分片为最终文件，标记完成后删除分片
+func (s *TusService) complete(ctx context.Context, u *models.TusUpload) error {
+	parts, err := s.repo.Parts(u.ID)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(parts))
+	var next int64
+	for _, p := range parts {
+		if p.Offset != next {
+			return fmt.Errorf("上传 %s 的分片不连续: 期望偏移量 %d，实际 %d", u.ID, next, p.Offset)
+		}
+		next += p.Size
+		keys = append(keys, p.StorageKey)
+	}
+	if next != u.Length {
+		return fmt.Errorf("上传 %s 的分片总长度 %d 与声明长度 %d 不一致", u.ID, next, u.Length)
+	}
+
+	key := tusPrefix + u.ID + "/file"
+	r := &partsReader{ctx: ctx, store: s.store, keys: keys}
+	defer r.Close()
+	if err := s.store.Put(ctx, key, r, u.Length, "application/octet-stream"); err != nil {
+		return err
+	}
+	if err := s.repo.Complete(u.ID, key); err != nil {
+		return err
+	}
+	now := time.Now()
+	u.StorageKey = key
+	u.CompletedAt = &now
+	for _, k := range keys {
+		if err := s.store.Delete(ctx, k); err != nil {
+			utils.UserLogger.Error("删除已合并的上传分片 %s 失败: %v", k, err)
+		}
+	}
+	return nil
+}
+
+// Delete 删除操作者本人的上传 (未完成时终止上传，已完成时删除文件并释放配额)
+func (s *TusService) Delete(ctx context.Context, actor models.Actor, id string) error {
+	u, err := s.repo.Get(id)
+	if err != nil {
+		return err
+	}
+	if u.UserID != actor.UserID {
+		return repository.ErrTusUploadNotFound
+	}
+	return s.remove(ctx, id)
+}
+
+// remove 删除上传记录及其在存储中的全部文件 (文件删除失败时由清理任务兜底)
+func (s *TusService) remove(ctx context.Context, id string) error {
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	var keys []string
+	err := s.store.List(ctx, tusPrefix+id+"/", func(obj storage.Object) error {
+		keys = append(keys, obj.Key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := s.store.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunJanitor 按固定间隔清理过期的未完成上传，阻塞运行，应在独立 goroutine 中调用
+// 参数: interval 检查间隔
+func (s *TusService) RunJanitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.Clean(context.Background())
+		if err != nil {
+			utils.UserLogger.Error("清理过期上传失败: %v", err)
+		} else if n > 0 {
+			utils.UserLogger.Info("已删除 %d 个过期的未完成上传", n)
+		}
+		<-ticker.C
+	}
+}
+
+// Clean 删除过期的未完成上传，以及存储中已没有对应上传记录的文件 (如用户被彻底删除后遗留的文件)
+// 返回: int 删除的过期上传数, error 错误信息
+func (s *TusService) Clean(ctx context.Context) (int, error) {
+	now := time.Now()
+	deleted := 0
+	for {
+		ids, err := s.repo.ExpiredIDs(now, 100)
+		if err != nil {
+			return deleted, err
+		}
+		for _, id := range ids {
+			if err := s.remove(ctx, id); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if len(ids) < 100 {
+			break
+		}
+	}
+
+	// 写入时间早于保留时长的文件才可能是遗留文件，避免误删刚创建的上传
+	cutoff := now.Add(-s.policy.Expiry)
+	objects := make(map[string][]string)
+	err := s.store.List(ctx, tusPrefix, func(obj storage.Object) error {
+		id, _, ok := strings.Cut(strings.TrimPrefix(obj.Key, tusPrefix), "/")
+		if ok && obj.ModTime.Before(cutoff) {
+			objects[id] = append(objects[id], obj.Key)
+		}
+		return nil
+	})
+	if err != nil || len(objects) == 0 {
+		return deleted, err
+	}
+	ids := make([]string, 0, len(objects))
+	for id := range objects {
+		ids = append(ids, id)
+	}
+	existing, err := s.repo.ExistingIDs(ids)
+	if err != nil {
+		return deleted, err
+	}
+	for id, keys := range objects {
+		if existing[id] {
+			continue
+		}
+		for _, k := range keys {
+			if err := s.store.Delete(ctx, k); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// validTusMetadata 校验 Upload-Metadata：以逗号分隔的 "键 值" 对，键不能为空且不含空格与逗号，值为 Base64 编码 (可省略)
+func validTusMetadata(metadata string) bool {
+	if metadata == "" {
+		return true
+	}
+	if len(metadata) > tusMaxMetadata {
+		return false
+	}
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(metadata, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 || seen[fields[0]] {
+			return false
+		}
+		seen[fields[0]] = true
+		if len(fields) == 2 {
+			if _, err := base64.StdEncoding.DecodeString(fields[1]); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// partsReader 依次读取各分片，读到一个分片末尾时才打开下一个
+type partsReader struct {
+	ctx   context.Context
+	store storage.Storage
+	keys  []string
+	cur   io.ReadCloser
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := r.store.Open(r.ctx, r.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			r.cur = rc
+			r.keys = r.keys[1:]
+		}
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.cur.Close()
+			r.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *partsReader) Close() error {
+	if r.cur == nil {
+		return nil
+	}
+	err := r.cur.Close()
+	r.cur = nil
+	return err
+}
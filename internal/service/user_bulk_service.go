@@ -0,0 +1,277 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"errors"
+	"strconv"
+)
+
+// UserBulkMaxUsers 单次批量操作最多涉及的用户数
+const UserBulkMaxUsers = 1000
+
+var (
+	// ErrBulkInvalidAction 不支持的批量操作类型
+	ErrBulkInvalidAction = errors.New("BULK_INVALID_ACTION")
+	// ErrBulkNoTarget 未指定操作对象，或同时指定了 ids 与 filter
+	ErrBulkNoTarget = errors.New("BULK_NO_TARGET")
+	// ErrBulkTooManyUsers 操作对象超过上限
+	ErrBulkTooManyUsers = errors.New("BULK_TOO_MANY_USERS")
+	// ErrBulkForbidden 操作者没有执行该操作所需的权限
+	ErrBulkForbidden = errors.New("BULK_FORBIDDEN")
+	// ErrBulkRoleNotFound set-role 的目标角色不存在
+	ErrBulkRoleNotFound = errors.New("BULK_ROLE_NOT_FOUND")
+	// ErrBulkRoleForbidden set-role 的目标角色超出操作者自身权限
+	ErrBulkRoleForbidden = errors.New("BULK_ROLE_FORBIDDEN")
+)
+
+// 批量操作中单个用户的失败原因代码
+const (
+	BulkErrNotFound = "not_found" // 用户不属于当前组织或已在回收站中
+	BulkErrSelf     = "self"      // 不能对自己执行批量操作
+	BulkErrAdmin    = "admin"     // 不能修改其他管理员
+	BulkErrShared   = "shared"    // 用户同时属于其它组织，仅平台管理员可修改
+)
+
+// UserBulkService 批量操作用户服务：逐个检查与单个操作相同的安全规则，通过检查的用户在同一事务中修改
+type UserBulkService struct {
+	userRepo     *repository.UserRepository
+	hasher       PasswordHasher
+	policy       *PasswordPolicyService
+	rbac         *RBACService
+	tokenService *TokenService
+	audit        *AuditService
+}
+
+// NewUserBulkService 创建批量操作用户服务实例
+func NewUserBulkService(userRepo *repository.UserRepository, hasher PasswordHasher, policy *PasswordPolicyService, rbac *RBACService,
+	tokenService *TokenService, audit *AuditService) *UserBulkService {
+	return &UserBulkService{userRepo: userRepo, hasher: hasher, policy: policy, rbac: rbac, tokenService: tokenService, audit: audit}
+}
+
+// Apply 对操作者所在组织内的一批用户执行批量操作
+// 被安全规则拒绝或找不到的用户在结果中逐个标记失败，其余用户在同一事务中修改 (数据库出错时全部回滚)
+// 参数: actor 操作者, req 批量操作请求 (ids 与 filter 二选一)
+// 返回: *models.UserBulkReport 逐个用户的结果, error 错误信息 (请求本身不合法时为 ErrBulk* 错误)
+func (s *UserBulkService) Apply(actor models.Actor, req models.UserBulkRequest) (*models.UserBulkReport, error) {
+	if err := s.checkAction(actor, req); err != nil {
+		return nil, err
+	}
+	ids, err := s.targets(actor, req)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepo.GetManyInOrg(actor.OrgID, ids)
+	if err != nil {
+		return nil, err
+	}
+	// 账号信息在组织间共享：同时属于其它组织的用户只能由平台管理员修改 (移出组织不受此限制)
+	shared := map[int64]bool{}
+	if req.Action != models.UserBulkDelete && !s.rbac.HasPermission(actor.Role, PermOrgsManage) {
+		if shared, err = s.userRepo.SharedUsers(ids); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &models.UserBulkReport{Action: req.Action, Total: len(ids), Results: make([]models.UserBulkResult, len(ids))}
+	var changes []*repository.UserBulkChange
+	pending := make(map[int64]int) // 用户ID -> 结果下标
+	passwords := make(map[int64]string)
+	for i, id := range ids {
+		res := &report.Results[i]
+		res.ID = id
+		u, ok := users[id]
+		if !ok {
+			bulkFail(res, BulkErrNotFound, "找不到用户")
+			continue
+		}
+		res.Username = u.Username
+		if code, msg := s.reject(actor, req.Action, u, shared[id]); code != "" {
+			bulkFail(res, code, msg)
+			continue
+		}
+
+		change := &repository.UserBulkChange{ID: id}
+		switch req.Action {
+		case models.UserBulkEnable:
+			change.Status = models.UserStatusEnabled
+			res.Unchanged = u.Status == models.UserStatusEnabled
+		case models.UserBulkDisable:
+			// 与单个修改一致：待验证邮箱的账号保持待验证
+			change.Status = models.UserStatusDisabled
+			res.Unchanged = u.Status != models.UserStatusEnabled
+		case models.UserBulkSetRole:
+			change.Role = req.Role
+			res.Unchanged = u.Role == req.Role
+		case models.UserBulkDelete:
+			change.Delete = true
+		case models.UserBulkResetPassword:
+			password, err := s.policy.Generate(u.Username)
+			if err != nil {
+				return nil, err
+			}
+			if change.PasswordHash, err = s.hasher.Hash(password); err != nil {
+				return nil, err
+			}
+			passwords[id] = password
+		}
+		if res.Unchanged {
+			res.Success = true
+			continue
+		}
+		pending[id] = i
+		changes = append(changes, change)
+	}
+
+	if err := s.userRepo.BulkApply(actor.OrgID, actor.UserID, changes); err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		res := &report.Results[pending[c.ID]]
+		if !c.Applied {
+			bulkFail(res, BulkErrNotFound, "找不到用户")
+			continue
+		}
+		res.Success = true
+		res.Trashed = c.Trashed
+		s.afterApply(actor, users[c.ID], c)
+		if c.PasswordHash != "" {
+			res.GeneratedPassword = passwords[c.ID]
+		}
+	}
+
+	for _, res := range report.Results {
+		if res.Success {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// checkAction 校验操作类型、操作者权限与 set-role 的目标角色
+func (s *UserBulkService) checkAction(actor models.Actor, req models.UserBulkRequest) error {
+	perm := PermUsersUpdate
+	switch req.Action {
+	case models.UserBulkEnable, models.UserBulkDisable, models.UserBulkSetRole, models.UserBulkResetPassword:
+	case models.UserBulkDelete:
+		perm = PermUsersDelete
+	default:
+		return ErrBulkInvalidAction
+	}
+	if !s.rbac.HasPermission(actor.Role, perm) {
+		return ErrBulkForbidden
+	}
+	if req.Action != models.UserBulkSetRole {
+		return nil
+	}
+	exists, err := s.rbac.RoleExists(req.Role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrBulkRoleNotFound
+	}
+	if !s.rbac.CanAssign(actor.Role, req.Role) {
+		return ErrBulkRoleForbidden
+	}
+	return nil
+}
+
+// targets 解析操作对象：去重后的 ids，或按 filter 查询当前组织内的用户
+func (s *UserBulkService) targets(actor models.Actor, req models.UserBulkRequest) ([]int64, error) {
+	if (len(req.IDs) == 0) == (req.Filter == nil) {
+		return nil, ErrBulkNoTarget
+	}
+	if req.Filter != nil {
+		ids, err := s.userRepo.ListIDs(models.UserListFilter{
+			OrgID:   actor.OrgID,
+			Keyword: req.Filter.Keyword,
+			Status:  req.Filter.Status,
+			Role:    req.Filter.Role,
+		}, UserBulkMaxUsers+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) > UserBulkMaxUsers {
+			return nil, ErrBulkTooManyUsers
+		}
+		return ids, nil
+	}
+
+	seen := make(map[int64]bool, len(req.IDs))
+	ids := make([]int64, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > UserBulkMaxUsers {
+		return nil, ErrBulkTooManyUsers
+	}
+	return ids, nil
+}
+
+// reject 按单个修改/删除用户的规则检查能否对该用户执行操作
+// 返回: string 失败原因代码 (允许时为空), string 说明
+func (s *UserBulkService) reject(actor models.Actor, action string, u *models.User, shared bool) (string, string) {
+	if u.ID == actor.UserID {
+		if action == models.UserBulkDelete {
+			return BulkErrSelf, "不能删除自己"
+		}
+		return BulkErrSelf, "不能对自己执行批量操作"
+	}
+	if action == models.UserBulkDelete {
+		// 与 DELETE /api/users/{id} 相同：不能删除权限超出自身的用户或其他管理员
+		if !s.rbac.CanManageUser(actor.Role, u.Role) {
+			return BulkErrAdmin, "禁止删除权限超出自身的用户或其他管理员"
+		}
+		return "", ""
+	}
+	if s.rbac.HasPermission(u.Role, PermUsersUpdate) {
+		return BulkErrAdmin, "禁止修改其他管理员"
+	}
+	if shared {
+		return BulkErrShared, "该用户同时属于其它组织，仅平台管理员可修改"
+	}
+	return "", ""
+}
+
+// afterApply 记录单个用户的审计事件；重置密码后撤销该用户的全部会话
+func (s *UserBulkService) afterApply(actor models.Actor, before *models.User, c *repository.UserBulkChange) {
+	targetID := strconv.FormatInt(c.ID, 10)
+	if c.Delete {
+		s.audit.Record(actor, AuditUserDelete, AuditTargetUser, targetID, auditUser(before),
+			map[string]interface{}{"trashed": c.Trashed, "source": "bulk"})
+		return
+	}
+
+	after := auditUser(before)
+	if c.Status != "" {
+		after["status"] = c.Status
+		after["enable"] = c.Status == models.UserStatusEnabled
+	}
+	if c.Role != "" {
+		after["role"] = c.Role
+	}
+	if c.PasswordHash != "" {
+		after["password_changed"] = true
+		s.policy.Remember(c.ID, before.Password)
+		if _, err := s.tokenService.RevokeAllSessions(c.ID); err != nil {
+			utils.UserLogger.Error("重置用户 %d 的密码后撤销会话失败: %v", c.ID, err)
+		}
+	}
+	after["source"] = "bulk"
+	s.audit.Record(actor, AuditUserUpdate, AuditTargetUser, targetID, auditUser(before), after)
+}
+
+// bulkFail 将结果标记为失败
+func bulkFail(res *models.UserBulkResult, code, msg string) {
+	res.Success = false
+	res.Code = code
+	res.Message = msg
+}
@@ -0,0 +1,466 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// UserImportMaxRows 单个文件最多导入的数据行数
+const UserImportMaxRows = 1000
+
+// UserImportMaxFileSize 导入文件大小上限
+const UserImportMaxFileSize = 5 << 20
+
+// importMaxUnzipSize XLSX 解压后的总大小上限 (上传上限的 20 倍)，防止高压缩比的文件耗尽内存与磁盘
+const importMaxUnzipSize = 20 * UserImportMaxFileSize
+
+var (
+	// ErrImportUnsupportedFormat 导入文件既不是 CSV 也不是 XLSX
+	ErrImportUnsupportedFormat = errors.New("IMPORT_UNSUPPORTED_FORMAT")
+	// ErrImportNoRows 导入文件没有数据行
+	ErrImportNoRows = errors.New("IMPORT_NO_ROWS")
+	// ErrImportTooManyRows 导入文件数据行超过上限
+	ErrImportTooManyRows = errors.New("IMPORT_TOO_MANY_ROWS")
+	// ErrImportConflict 提交时用户名或邮箱已被其他请求占用，需重新校验
+	ErrImportConflict = errors.New("IMPORT_CONFLICT")
+)
+
+// 导入行错误代码 (密码错误使用密码策略的违规代码)
+const (
+	ImportErrRequired      = "required"
+	ImportErrTooLong       = "too_long"
+	ImportErrInvalidChars  = "invalid_chars"
+	ImportErrDuplicate     = "duplicate"
+	ImportErrExists        = "exists"
+	ImportErrInvalidEmail  = "invalid_email"
+	ImportErrInvalidRole   = "invalid_role"
+	ImportErrRoleForbidden = "role_forbidden"
+	ImportErrInvalidStatus = "invalid_status"
+)
+
+// importColumns 表头别名到字段名的映射 (不区分大小写)
+var importColumns = map[string]string{
+	"username": "username", "用户名": "username",
+	"password": "password", "密码": "password",
+	"role": "role", "角色": "role",
+	"status": "status", "状态": "status",
+	"email": "email", "邮箱": "email",
+}
+
+// ImportHeaderError 表头缺少必需列或包含无法识别的列
+type ImportHeaderError struct {
+	Missing []string
+	Unknown []string
+}
+
+func (e *ImportHeaderError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "缺少列: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "无法识别的列: "+strings.Join(e.Unknown, ", "))
+	}
+	return strings.Join(parts, "；")
+}
+
+// UserImportService 批量导入用户服务：解析 CSV/XLSX，逐行校验并在同一事务中创建
+type UserImportService struct {
+	userRepo    *repository.UserRepository
+	hasher      PasswordHasher
+	policy      *PasswordPolicyService
+	rbac        *RBACService
+	verifier    *EmailVerificationService
+	audit       *AuditService
+	defaultRole string
+}
+
+// NewUserImportService 创建批量导入服务实例
+// 参数: defaultRole 角色列为空时使用的默认角色 (来自配置)
+func NewUserImportService(userRepo *repository.UserRepository, hasher PasswordHasher, policy *PasswordPolicyService, rbac *RBACService,
+	verifier *EmailVerificationService, audit *AuditService, defaultRole string) *UserImportService {
+	return &UserImportService{
+		userRepo:    userRepo,
+		hasher:      hasher,
+		policy:      policy,
+		rbac:        rbac,
+		verifier:    verifier,
+		audit:       audit,
+		defaultRole: defaultRole,
+	}
+}
+
+// importRecord 文件中的一条记录
+type importRecord struct {
+	line  int // 行号 (从 1 开始)
+	cells []string
+}
+
+// importRow 校验过程中的单行数据
+type importRow struct {
+	result   *models.UserImportRow
+	password string
+}
+
+// Import 解析并校验导入文件；dryRun 为 false 时在同一事务中创建全部校验通过的行
+// 参数: actor 操作者 (用户加入其当前组织，角色须在其授予范围内), filename 原始文件名 (用于识别格式), data 文件内容, dryRun 是否仅校验
+// 返回: *models.UserImportReport 逐行结果, error 文件级错误 (格式、表头、行数) 或数据库错误
+func (s *UserImportService) Import(actor models.Actor, filename string, data []byte, dryRun bool) (*models.UserImportReport, error) {
+	records, err := readImportRecords(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.parseRows(records)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(actor, rows); err != nil {
+		return nil, err
+	}
+
+	report := &models.UserImportReport{DryRun: dryRun, Total: len(rows), Rows: make([]models.UserImportRow, len(rows))}
+	var valid []*importRow
+	for _, row := range rows {
+		if len(row.result.Errors) == 0 {
+			valid = append(valid, row)
+		}
+	}
+	report.Valid, report.Invalid = len(valid), len(rows)-len(valid)
+
+	if !dryRun && len(valid) > 0 {
+		if err := s.commit(actor, valid); err != nil {
+			return nil, err
+		}
+		report.Created = len(valid)
+	}
+	for i, row := range rows {
+		report.Rows[i] = *row.result
+	}
+	return report, nil
+}
+
+// parseRows 根据表头把记录映射为行数据 (跳过空行)
+func (s *UserImportService) parseRows(records []importRecord) ([]*importRow, error) {
+	if len(records) == 0 {
+		return nil, ErrImportNoRows
+	}
+
+	index := make(map[string]int)
+	headerErr := &ImportHeaderError{}
+	for i, name := range records[0].cells {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field, ok := importColumns[strings.ToLower(name)]
+		if !ok {
+			headerErr.Unknown = append(headerErr.Unknown, name)
+			continue
+		}
+		index[field] = i
+	}
+	if _, ok := index["username"]; !ok {
+		headerErr.Missing = append(headerErr.Missing, "username")
+	}
+	if len(headerErr.Missing) > 0 || len(headerErr.Unknown) > 0 {
+		return nil, headerErr
+	}
+
+	var rows []*importRow
+	for _, rec := range records[1:] {
+		record := rec.cells
+		cell := func(field string) string {
+			return strings.TrimSpace(cellRaw(record, index, field))
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if len(rows) == UserImportMaxRows {
+			return nil, ErrImportTooManyRows
+		}
+		rows = append(rows, &importRow{
+			result: &models.UserImportRow{
+				Row:      rec.line,
+				Username: cell("username"),
+				Email:    cell("email"),
+				Role:     cell("role"),
+				Status:   cell("status"),
+			},
+			// 密码保留首尾空格，与用户实际输入一致
+			password: cellRaw(record, index, "password"),
+		})
+	}
+	if len(rows) == 0 {
+		return nil, ErrImportNoRows
+	}
+	return rows, nil
+}
+
+// validate 逐行校验并把错误写入行结果，同时规范化邮箱、角色与状态
+func (s *UserImportService) validate(actor models.Actor, rows []*importRow) error {
+	seenNames := make(map[string]int)
+	seenEmails := make(map[string]int)
+	var names, emails []string
+	roleValid := make(map[string]bool)
+
+	for _, row := range rows {
+		r := row.result
+		addErr := func(field, code, msg string) {
+			r.Errors = append(r.Errors, models.UserImportError{Field: field, Code: code, Message: msg})
+		}
+
+		// 用户名：与单个创建相同的规则，且文件内不重复
+		switch err := CheckUsername(r.Username); {
+		case errors.Is(err, ErrUsernameRequired):
+			addErr("username", ImportErrRequired, "用户名不能为空")
+		case errors.Is(err, ErrUsernameTooLong):
+			addErr("username", ImportErrTooLong, fmt.Sprintf("用户名不能超过 %d 个字符", maxUsernameLength))
+		case errors.Is(err, ErrUsernameInvalid):
+			addErr("username", ImportErrInvalidChars, "用户名不能包含空白、控制字符或 @")
+		default:
+			key := strings.ToLower(r.Username)
+			if first, ok := seenNames[key]; ok {
+				addErr("username", ImportErrDuplicate, fmt.Sprintf("与第 %d 行的用户名重复", first))
+			} else {
+				seenNames[key] = r.Row
+				names = append(names, r.Username)
+			}
+		}
+
+		// 邮箱：选填，格式与文件内不重复
+		if r.Email != "" {
+			email, err := NormalizeEmail(r.Email)
+			if err != nil {
+				addErr("email", ImportErrInvalidEmail, "邮箱格式错误")
+			} else if first, ok := seenEmails[email]; ok {
+				r.Email = email
+				addErr("email", ImportErrDuplicate, fmt.Sprintf("与第 %d 行的邮箱重复", first))
+			} else {
+				r.Email = email
+				seenEmails[email] = r.Row
+				emails = append(emails, email)
+			}
+		}
+
+		// 角色：为空时使用默认角色，须存在且在操作者授予范围内
+		if r.Role == "" {
+			r.Role = s.defaultRole
+		}
+		ok, checked := roleValid[r.Role]
+		if !checked {
+			exists, err := s.rbac.RoleExists(r.Role)
+			if err != nil {
+				return err
+			}
+			ok = exists
+			roleValid[r.Role] = ok
+		}
+		if !ok {
+			addErr("role", ImportErrInvalidRole, "角色不存在")
+		} else if !s.rbac.CanAssign(actor.Role, r.Role) {
+			addErr("role", ImportErrRoleForbidden, "不能授予超出自身权限的角色")
+		}
+
+		// 状态：为空时启用
+		if status, ok := parseImportStatus(r.Status); ok {
+			r.Status = status
+		} else {
+			addErr("status", ImportErrInvalidStatus, "状态只能为 enabled / disabled (启用 / 禁用)")
+		}
+
+		// 密码：为空时提交时自动生成，否则按密码策略校验
+		if row.password == "" {
+			r.PasswordGenerated = true
+		} else if err := s.policy.Validate(r.Username, row.password); err != nil {
+			var policyErr *PolicyError
+			if !errors.As(err, &policyErr) {
+				return err
+			}
+			for _, v := range policyErr.Violations {
+				addErr("password", v.Code, v.Message)
+			}
+		}
+	}
+
+	// 与数据库中已有账号比较 (回收站中的账号同样占用用户名与邮箱)
+	takenNames, err := s.userRepo.UsernamesTaken(names)
+	if err != nil {
+		return err
+	}
+	takenEmails, err := s.userRepo.EmailsTaken(emails)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		r := row.result
+		if seenNames[strings.ToLower(r.Username)] == r.Row && takenNames[strings.ToLower(r.Username)] {
+			r.Errors = append(r.Errors, models.UserImportError{Field: "username", Code: ImportErrExists, Message: "用户名已被占用"})
+		}
+		if r.Email != "" && seenEmails[r.Email] == r.Row && takenEmails[r.Email] {
+			r.Errors = append(r.Errors, models.UserImportError{Field: "email", Code: ImportErrExists, Message: "该邮箱已被其他账号使用"})
+		}
+	}
+	return nil
+}
+
+// commit 生成密码、哈希并在同一事务中创建全部行，成功后记录审计并发送邮箱验证
+func (s *UserImportService) commit(actor models.Actor, rows []*importRow) error {
+	users := make([]*models.User, len(rows))
+	for i, row := range rows {
+		r := row.result
+		password := row.password
+		if r.PasswordGenerated {
+			var err error
+			if password, err = s.policy.Generate(r.Username); err != nil {
+				return err
+			}
+		}
+		hash, err := s.hasher.Hash(password)
+		if err != nil {
+			return err
+		}
+		if r.PasswordGenerated {
+			r.GeneratedPassword = password
+		}
+		users[i] = &models.User{
+			Username: r.Username,
+			Email:    r.Email,
+			Password: hash,
+			Role:     r.Role,
+			OrgID:    actor.OrgID,
+			Status:   r.Status,
+			Enable:   r.Status == models.UserStatusEnabled,
+		}
+	}
+
+	if err := s.userRepo.CreateBatch(actor.OrgID, users); err != nil {
+		for _, row := range rows {
+			row.result.GeneratedPassword = ""
+		}
+		if errors.Is(err, repository.ErrUserExists) {
+			return ErrImportConflict
+		}
+		return err
+	}
+
+	for i, u := range users {
+		rows[i].result.ID = u.ID
+		after := auditUser(u)
+		after["source"] = "import"
+		s.audit.Record(actor, AuditUserCreate, AuditTargetUser, strconv.FormatInt(u.ID, 10), nil, after)
+		if err := s.verifier.Send(actor, u); err != nil {
+			utils.UserLogger.Error("向用户 %d 发送邮箱验证链接失败: %v", u.ID, err)
+		}
+	}
+	utils.UserLogger.Info("用户 %d 在组织 %d 批量导入了 %d 个用户", actor.UserID, actor.OrgID, len(users))
+	return nil
+}
+
+// parseImportStatus 解析状态列 (为空视为启用)
+func parseImportStatus(v string) (string, bool) {
+	switch strings.ToLower(v) {
+	case "", "enabled", "enable", "1", "true", "启用":
+		return models.UserStatusEnabled, true
+	case "disabled", "disable", "0", "false", "禁用":
+		return models.UserStatusDisabled, true
+	}
+	return "", false
+}
+
+// cellRaw 读取单元格原始内容 (不去除空格)
+func cellRaw(record []string, index map[string]int, field string) string {
+	if j, ok := index[field]; ok && j < len(record) {
+		return record[j]
+	}
+	return ""
+}
+
+// readImportRecords 按扩展名与文件头识别 CSV / XLSX 并读取全部记录 (XLSX 读取第一个工作表)
+func readImportRecords(filename string, data []byte) ([]importRecord, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	isZip := bytes.HasPrefix(data, []byte("PK\x03\x04"))
+	switch {
+	case ext == ".xlsx" || (ext != ".csv" && isZip):
+		if !isZip {
+			return nil, ErrImportUnsupportedFormat
+		}
+		return readXLSX(data)
+	case ext == ".csv" || ext == "" || ext == ".txt":
+		if isZip || !utf8.Valid(data) {
+			return nil, ErrImportUnsupportedFormat
+		}
+		return readCSV(data)
+	}
+	return nil, ErrImportUnsupportedFormat
+}
+
+// readCSV 读取 CSV (去除 Excel 导出的 UTF-8 BOM，允许各行列数不同)
+func readCSV(data []byte) ([]importRecord, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var records []importRecord
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrImportUnsupportedFormat, err)
+		}
+		// 空行会被跳过，行号取记录起始位置
+		line, _ := reader.FieldPos(0)
+		records = append(records, importRecord{line: line, cells: record})
+	}
+	return records, nil
+}
+
+// readXLSX 读取 XLSX 第一个工作表 (跳过表头之后的空行)
+// 读到表头及 UserImportMaxRows+1 个数据行后即停止，剩余行不再读入内存，由 parseRows 判定超出上限
+func readXLSX(data []byte) ([]importRecord, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data), excelize.Options{UnzipSizeLimit: importMaxUnzipSize})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportUnsupportedFormat, err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, ErrImportNoRows
+	}
+	rows, err := f.Rows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportUnsupportedFormat, err)
+	}
+	defer rows.Close()
+
+	var records []importRecord
+	for line := 1; rows.Next(); line++ {
+		record, err := rows.Columns()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrImportUnsupportedFormat, err)
+		}
+		if len(records) > 0 && strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		records = append(records, importRecord{line: line, cells: record})
+		if len(records) > UserImportMaxRows+1 {
+			break
+		}
+	}
+	return records, rows.Error()
+}
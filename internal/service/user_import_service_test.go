@@ -0,0 +1,306 @@
+package service
+
+import (
+	"GoWork_7/internal/models"
+	"GoWork_7/internal/repository"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// importTestDB 最小的 database/sql 驱动：只应答 validate 用到的角色、权限与用户名/邮箱占用查询
+type importTestDB struct {
+	grants map[string][]string // 角色 -> 权限
+	taken  map[string]bool     // 数据库中已存在的用户名或邮箱 (小写)
+}
+
+func (d *importTestDB) Open(string) (driver.Conn, error)             { return importTestConn{d}, nil }
+func (d *importTestDB) Connect(context.Context) (driver.Conn, error) { return importTestConn{d}, nil }
+func (d *importTestDB) Driver() driver.Driver                        { return d }
+
+type importTestConn struct{ db *importTestDB }
+
+func (c importTestConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c importTestConn) Close() error                        { return nil }
+func (c importTestConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c importTestConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	switch {
+	case strings.Contains(query, "FROM roles WHERE name = ?"):
+		name := args[0].Value.(string)
+		rows := &importTestRows{cols: []string{"id", "name", "description", "is_system", "mfa_required"}}
+		if _, ok := c.db.grants[name]; ok {
+			rows.data = append(rows.data, []driver.Value{int64(1), name, "", false, false})
+		}
+		return rows, nil
+	case strings.Contains(query, "FROM role_permissions"):
+		rows := &importTestRows{cols: []string{"role", "permission"}}
+		for role, perms := range c.db.grants {
+			for _, p := range perms {
+				rows.data = append(rows.data, []driver.Value{role, p})
+			}
+		}
+		return rows, nil
+	case strings.HasPrefix(query, "SELECT username FROM users"), strings.HasPrefix(query, "SELECT email FROM users"):
+		rows := &importTestRows{cols: []string{"value"}}
+		for _, arg := range args {
+			if v := arg.Value.(string); c.db.taken[strings.ToLower(v)] {
+				rows.data = append(rows.data, []driver.Value{v})
+			}
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+type importTestRows struct {
+	cols []string
+	data [][]driver.Value
+	next int
+}
+
+func (r *importTestRows) Columns() []string { return r.cols }
+func (r *importTestRows) Close() error      { return nil }
+
+func (r *importTestRows) Next(dest []driver.Value) error {
+	if r.next == len(r.data) {
+		return io.EOF
+	}
+	copy(dest, r.data[r.next])
+	r.next++
+	return nil
+}
+
+// newImportTestService 创建连接到 importTestDB 的导入服务 (默认角色 user)
+func newImportTestService(t *testing.T, taken ...string) *UserImportService {
+	t.Helper()
+	fake := &importTestDB{
+		grants: map[string][]string{
+			"superadmin": {PermUsersCreate, PermUsersUpdate, PermOrgsManage},
+			"admin":      {PermUsersCreate, PermUsersUpdate},
+			"user":       {},
+		},
+		taken: make(map[string]bool),
+	}
+	for _, v := range taken {
+		fake.taken[strings.ToLower(v)] = true
+	}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+
+	policy := NewPasswordPolicyService(PasswordPolicy{MinLength: 8, MaxLength: MaxPasswordBytes, RequireDigit: true}, nil, nil, nil)
+	rbac := NewRBACService(repository.NewRoleRepository(db), nil)
+	return NewUserImportService(repository.NewUserRepository(db), nil, policy, rbac, nil, nil, "user")
+}
+
+// csvRecords 把 CSV 文本读取为记录
+func csvRecords(t *testing.T, data string) []importRecord {
+	t.Helper()
+	records, err := readImportRecords("users.csv", []byte(data))
+	if err != nil {
+		t.Fatalf("readImportRecords: %v", err)
+	}
+	return records
+}
+
+// errorCodes 行结果中 字段 -> 错误代码 列表
+func errorCodes(r *models.UserImportRow) map[string][]string {
+	codes := make(map[string][]string)
+	for _, e := range r.Errors {
+		codes[e.Field] = append(codes[e.Field], e.Code)
+	}
+	return codes
+}
+
+func TestParseRowsHeaders(t *testing.T) {
+	s := &UserImportService{}
+	tests := []struct {
+		name    string
+		csv     string
+		missing []string
+		unknown []string
+	}{
+		{"缺少用户名列", "email,password\na@example.com,Secret123\n", []string{"username"}, nil},
+		{"无法识别的列", "username,phone,nickname\nalice,123,Al\n", nil, []string{"phone", "nickname"}},
+		{"同时缺少与无法识别", "邮箱,电话\na@example.com,123\n", []string{"username"}, []string{"电话"}},
+	}
+	for _, tt := range tests {
+		_, err := s.parseRows(csvRecords(t, tt.csv))
+		var headerErr *ImportHeaderError
+		if !errors.As(err, &headerErr) {
+			t.Errorf("%s: 错误 = %v, 期望 *ImportHeaderError", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(headerErr.Missing, tt.missing) || !reflect.DeepEqual(headerErr.Unknown, tt.unknown) {
+			t.Errorf("%s: 缺少 %v 无法识别 %v, 期望 %v / %v", tt.name, headerErr.Missing, headerErr.Unknown, tt.missing, tt.unknown)
+		}
+	}
+}
+
+func TestParseRowsMapsColumns(t *testing.T) {
+	s := &UserImportService{}
+	// 中文表头不区分大小写地映射，空行跳过，密码保留首尾空格
+	rows, err := s.parseRows(csvRecords(t, "用户名, EMAIL ,密码,角色,状态\n alice ,A@Example.com, pw 1 ,admin,禁用\n\n,,,,\nbob\n"))
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("行数 = %d, 期望 2", len(rows))
+	}
+	want := models.UserImportRow{Row: 2, Username: "alice", Email: "A@Example.com", Role: "admin", Status: "禁用"}
+	if got := *rows[0].result; !reflect.DeepEqual(got, want) {
+		t.Errorf("第一行 = %+v, 期望 %+v", got, want)
+	}
+	if rows[0].password != " pw 1 " {
+		t.Errorf("密码 = %q, 期望保留首尾空格", rows[0].password)
+	}
+	if rows[1].result.Row != 5 || rows[1].result.Username != "bob" {
+		t.Errorf("第二行 = %+v, 期望第 5 行的 bob", rows[1].result)
+	}
+}
+
+func TestParseRowsNoRows(t *testing.T) {
+	s := &UserImportService{}
+	for name, records := range map[string][]importRecord{
+		"空文件": nil,
+		"仅表头": csvRecords(t, "username,password\n"),
+		"仅空行": csvRecords(t, "username,password\n,\n , \n"),
+	} {
+		if _, err := s.parseRows(records); !errors.Is(err, ErrImportNoRows) {
+			t.Errorf("%s: 错误 = %v, 期望 ErrImportNoRows", name, err)
+		}
+	}
+}
+
+func TestParseRowsMaxRows(t *testing.T) {
+	s := &UserImportService{}
+	build := func(n int) string {
+		var b strings.Builder
+		b.WriteString("username\n")
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "user%d\n,\n", i)
+		}
+		return b.String()
+	}
+
+	// 空行不计入上限
+	rows, err := s.parseRows(csvRecords(t, build(UserImportMaxRows)))
+	if err != nil || len(rows) != UserImportMaxRows {
+		t.Fatalf("%d 行: (%d, %v), 期望全部通过", UserImportMaxRows, len(rows), err)
+	}
+	if _, err := s.parseRows(csvRecords(t, build(UserImportMaxRows+1))); !errors.Is(err, ErrImportTooManyRows) {
+		t.Errorf("%d 行: 错误 = %v, 期望 ErrImportTooManyRows", UserImportMaxRows+1, err)
+	}
+}
+
+func TestReadCSVStripsBOM(t *testing.T) {
+	s := &UserImportService{}
+	rows, err := s.parseRows(csvRecords(t, "\xef\xbb\xbfusername,password\r\nalice,Secret123\r\n"))
+	if err != nil {
+		t.Fatalf("带 BOM 的表头未被识别: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("行数 = %d, 期望 1", len(rows))
+	}
+	if rows[0].result.Username != "alice" || rows[0].password != "Secret123" {
+		t.Errorf("行 = %+v, 期望 alice", rows[0].result)
+	}
+}
+
+func TestReadImportRecordsRejectsNonUTF8(t *testing.T) {
+	// GBK 编码的 "用户名"
+	if _, err := readImportRecords("users.csv", []byte("\xd3\xc3\xbb\xa7\xc3\xfb\nalice\n")); !errors.Is(err, ErrImportUnsupportedFormat) {
+		t.Errorf("错误 = %v, 期望 ErrImportUnsupportedFormat", err)
+	}
+}
+
+func TestValidateDuplicates(t *testing.T) {
+	s := newImportTestService(t)
+	rows, err := s.parseRows(csvRecords(t, "username,email\nAlice,alice@example.com\nalice,ALICE@example.com\nbob,Alice@Example.com\n"))
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+	if err := s.validate(models.Actor{Role: "admin"}, rows); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	if len(rows[0].result.Errors) != 0 {
+		t.Errorf("第 2 行不应有错误: %+v", rows[0].result.Errors)
+	}
+	// 用户名不区分大小写，邮箱规范化为小写后比较
+	if got, want := errorCodes(rows[1].result), map[string][]string{"username": {ImportErrDuplicate}, "email": {ImportErrDuplicate}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("第 3 行错误 = %v, 期望 %v", got, want)
+	}
+	if got, want := errorCodes(rows[2].result), map[string][]string{"email": {ImportErrDuplicate}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("第 4 行错误 = %v, 期望 %v", got, want)
+	}
+	if msg := rows[1].result.Errors[0].Message; !strings.Contains(msg, "第 2 行") {
+		t.Errorf("重复提示 %q 未指出首次出现的行号", msg)
+	}
+}
+
+func TestValidateExisting(t *testing.T) {
+	s := newImportTestService(t, "carol", "dave@example.com")
+	rows, err := s.parseRows(csvRecords(t, "username,email\nCAROL,\nerin,Dave@Example.com\nCarol,\n"))
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+	if err := s.validate(models.Actor{Role: "admin"}, rows); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	want := []map[string][]string{
+		{"username": {ImportErrExists}},
+		{"email": {ImportErrExists}},
+		// 文件内重复只报告重复，不再重复报告已被占用
+		{"username": {ImportErrDuplicate}},
+	}
+	for i, w := range want {
+		if got := errorCodes(rows[i].result); !reflect.DeepEqual(got, w) {
+			t.Errorf("第 %d 行错误 = %v, 期望 %v", rows[i].result.Row, got, w)
+		}
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	s := newImportTestService(t)
+	csv := "username,email,password,role,status\n" +
+		"frank,,,,\n" +
+		"grace@example.com,not-an-email,Secret123,admin,enabled\n" +
+		"heidi,,short,superadmin,disabled\n" +
+		"ivan,,Secret123,nobody,maybe\n" +
+		",,,,\n" +
+		strings.Repeat("x", maxUsernameLength+1) + ",,,,\n"
+	rows, err := s.parseRows(csvRecords(t, csv))
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+	if err := s.validate(models.Actor{Role: "admin"}, rows); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	// 空角色、状态与密码分别使用默认角色、启用与自动生成
+	frank := rows[0].result
+	if len(frank.Errors) != 0 || frank.Role != "user" || frank.Status != models.UserStatusEnabled || !frank.PasswordGenerated {
+		t.Errorf("frank = %+v, 期望默认角色、启用且自动生成密码", frank)
+	}
+	want := []map[string][]string{
+		{"username": {ImportErrInvalidChars}, "email": {ImportErrInvalidEmail}},
+		{"role": {ImportErrRoleForbidden}, "password": {ViolationTooShort, ViolationMissingDigit}},
+		{"role": {ImportErrInvalidRole}, "status": {ImportErrInvalidStatus}},
+	}
+	for i, w := range want {
+		if got := errorCodes(rows[i+1].result); !reflect.DeepEqual(got, w) {
+			t.Errorf("第 %d 行错误 = %v, 期望 %v", rows[i+1].result.Row, got, w)
+		}
+	}
+	// 仅用户名为空的行 (其余列含分隔符) 在 parseRows 中按空行跳过，超长用户名报 too_long
+	if last := rows[len(rows)-1].result; !reflect.DeepEqual(errorCodes(last), map[string][]string{"username": {ImportErrTooLong}}) {
+		t.Errorf("超长用户名错误 = %v", errorCodes(last))
+	}
+}
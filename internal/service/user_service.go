@@ -3,39 +3,356 @@ package service
 import (
 	"GoWork_7/internal/models"
 	"GoWork_7/internal/repository"
+	"GoWork_7/internal/utils"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// maxUsernameLength 用户名最大长度 (users.username VARCHAR(50))
+const maxUsernameLength = 50
+
+var (
+	// ErrSelfPasswordChange 通过用户管理接口修改自己的密码 (须使用 POST /api/users/me/password 并验证当前密码)
+	ErrSelfPasswordChange = errors.New("SELF_PASSWORD_CHANGE")
+	// ErrUsernameRequired 用户名为空
+	ErrUsernameRequired = errors.New("USERNAME_REQUIRED")
+	// ErrUsernameTooLong 用户名超过 maxUsernameLength 个字符
+	ErrUsernameTooLong = errors.New("USERNAME_TOO_LONG")
+	// ErrUsernameInvalid 用户名包含空白、控制字符或 @
+	ErrUsernameInvalid = errors.New("USERNAME_INVALID")
+	// ErrUserManageForbidden 目标用户的权限超出操作者，或是其他管理员 (见 RBACService.CanManageUser)
+	ErrUserManageForbidden = errors.New("USER_MANAGE_FORBIDDEN")
+)
+
+// CheckUsername 校验新用户名 (注册、创建、导入及改名时使用)
+// 用户名不能包含 @：登录时用户名与邮箱共用一个输入框，形如邮箱的用户名会抢占他人的邮箱登录
+// 返回: error 为空为 ErrUsernameRequired, 过长为 ErrUsernameTooLong, 包含空白、控制字符或 @ 为 ErrUsernameInvalid
+func CheckUsername(username string) error {
+	switch {
+	case username == "":
+		return ErrUsernameRequired
+	case utf8.RuneCountInString(username) > maxUsernameLength:
+		return ErrUsernameTooLong
+	case strings.IndexFunc(username, func(c rune) bool { return c == '@' || unicode.IsSpace(c) || unicode.IsControl(c) }) >= 0:
+		return ErrUsernameInvalid
+	}
+	return nil
+}
+
 // UserService 用户管理业务服务
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo     *repository.UserRepository
+	hasher       PasswordHasher
+	policy       *PasswordPolicyService
+	verifier     *EmailVerificationService
+	rbac         *RBACService
+	tokenService *TokenService
+	audit        *AuditService
+	defaultRole  string
+	avatarTTL    time.Duration
 }
 
 // NewUserService 创建用户服务实例
-func NewUserService(userRepo *repository.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+// 参数: defaultRole 未指定角色时新用户的默认角色, avatarTTL 临时上传头像的有效期 (均来自配置)
+func NewUserService(userRepo *repository.UserRepository, hasher PasswordHasher, policy *PasswordPolicyService, verifier *EmailVerificationService,
+	rbac *RBACService, tokenService *TokenService, audit *AuditService, defaultRole string, avatarTTL time.Duration) *UserService {
+	return &UserService{userRepo: userRepo, hasher: hasher, policy: policy, verifier: verifier, rbac: rbac, tokenService: tokenService, audit: audit,
+		defaultRole: defaultRole, avatarTTL: avatarTTL}
+}
+
+// GetAllUsers 获取组织内的用户列表（分页+筛选+排序）
+// 返回: []models.User 用户切片, int 总记录数 (游标分页时为 -1), string 下一页游标, error 错误信息
+func (s *UserService) GetAllUsers(f models.UserListFilter) ([]models.User, int, string, error) {
+	return s.userRepo.FetchWithPagination(f)
+}
+
+// ExportUsers 按列表筛选与排序条件逐行导出操作者所在组织内的全部用户，导出完成后记录审计事件
+// 参数: format 导出格式 (仅用于审计), f 与用户列表相同的筛选与排序条件 (忽略分页), fn 逐行回调 (返回错误时中止导出)
+// 返回: int 已导出的行数, error 错误信息
+func (s *UserService) ExportUsers(actor models.Actor, format string, f models.UserListFilter, fn func(u *models.User) error) (int, error) {
+	f.OrgID = actor.OrgID
+	count := 0
+	err := s.userRepo.StreamUsers(f, func(u *models.User) error {
+		count++
+		return fn(u)
+	})
+	if err != nil {
+		return count, err
+	}
+	s.audit.Record(actor, AuditUserExport, AuditTargetUser, "", nil, map[string]interface{}{
+		"format":  format,
+		"keyword": f.Keyword,
+		"status":  f.Status,
+		"role":    f.Role,
+		"count":   count,
+	})
+	return count, nil
+}
+
+// CreateUser 在操作者所在组织内创建新用户 (role 为空时使用默认角色)
+// 管理员创建的账号直接启用；填写邮箱时向该邮箱发送验证链接
+// avatar 非空时须为操作者在有效期内通过通用接口上传的头像，与创建用户在同一事务中认领
+// 返回: int64 新用户ID, error 错误信息 (用户名不合法见 CheckUsername, 密码不符合策略为 *PolicyError, 邮箱格式错误为 ErrInvalidEmail,
+// 已被占用为 ErrEmailExists, 头像不可用为 repository.ErrAvatarUploadNotFound)
+func (s *UserService) CreateUser(actor models.Actor, username, email, password, role, avatar string) (int64, error) {
+	if err := CheckUsername(username); err != nil {
+		return 0, err
+	}
+	if err := s.policy.Validate(username, password); err != nil {
+		return 0, err
+	}
+	if email != "" {
+		var err error
+		if email, err = checkEmail(s.userRepo, email, 0); err != nil {
+			return 0, err
+		}
+	}
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return 0, err
+	}
+	if role == "" {
+		role = s.defaultRole
+	}
+	user := &models.User{Username: username, Email: email, Password: hash, Role: role, OrgID: actor.OrgID, Status: models.UserStatusEnabled, Enable: true,
+		Avatar: avatar}
+	if avatar != "" {
+		user.ID, err = s.userRepo.CreateWithAvatarUpload(actor.OrgID, user, actor.UserID, time.Now().Add(-s.avatarTTL))
+	} else {
+		user.ID, err = s.userRepo.Create(actor.OrgID, user)
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.audit.Record(actor, AuditUserCreate, AuditTargetUser, strconv.FormatInt(user.ID, 10), nil, auditUser(user))
+	s.sendVerification(actor, user)
+	return user.ID, nil
+}
+
+// GetUserByID 根据ID获取组织内的用户信息 (含组织内角色)
+func (s *UserService) GetUserByID(orgID, id int64) (*models.User, error) {
+	return s.userRepo.GetByIDInOrg(orgID, id)
+}
+
+// UpdateUser 更新操作者所在组织内的用户信息 (邮箱变更后需重新验证)
+// 密码非空时按策略校验后哈希落库，并撤销该用户的全部会话；只能重置他人的密码，本人修改密码须验证当前密码
+// 用户名变更时按 CheckUsername 校验 (历史账号的用户名不变时不受新规则限制)
+// 返回: error 错误信息 (修改自己的密码为 ErrSelfPasswordChange, 新用户名不合法见 CheckUsername, 用户名已被占用为 repository.ErrUserExists,
+// 密码不符合策略为 *PolicyError, 邮箱格式错误为 ErrInvalidEmail, 已被占用为 ErrEmailExists)
+func (s *UserService) UpdateUser(actor models.Actor, user *models.User) error {
+	if user.Password != "" && user.ID == actor.UserID {
+		return ErrSelfPasswordChange
+	}
+	before, err := s.userRepo.GetByIDInOrg(actor.OrgID, user.ID)
+	if err != nil {
+		return err
+	}
+	if user.Username != before.Username {
+		if err := CheckUsername(user.Username); err != nil {
+			return err
+		}
+	}
+
+	if user.Email != "" {
+		if user.Email, err = checkEmail(s.userRepo, user.Email, user.ID); err != nil {
+			return err
+		}
+	}
+	emailChanged := user.Email != before.Email
+	user.EmailVerified = before.EmailVerified && !emailChanged
+	// 头像只能通过上传接口修改，不接受请求中的文件名
+	user.Avatar = before.Avatar
+	// 状态由 Enable 决定；待验证的账号未被启用时保持待验证，而不是变为禁用
+	switch {
+	case user.Enable:
+		user.Status = models.UserStatusEnabled
+	case before.Status == models.UserStatusPending:
+		user.Status = models.UserStatusPending
+	default:
+		user.Status = models.UserStatusDisabled
+	}
+
+	passwordChanged := user.Password != ""
+	if passwordChanged {
+		candidate := *before
+		candidate.Username = user.Username
+		if err := s.policy.Check(&candidate, user.Password); err != nil {
+			return err
+		}
+		hash, err := s.hasher.Hash(user.Password)
+		if err != nil {
+			return err
+		}
+		user.Password = hash
+	}
+	if err := s.userRepo.Update(actor.OrgID, user); err != nil {
+		return err
+	}
+	if passwordChanged {
+		s.policy.Remember(user.ID, before.Password)
+		if _, err := s.tokenService.RevokeAllSessions(user.ID); err != nil {
+			utils.UserLogger.Error("重置用户 %d 的密码后撤销会话失败: %v", user.ID, err)
+		}
+	}
+
+	after := auditUser(user)
+	if passwordChanged {
+		after["password_changed"] = true
+	}
+	s.audit.Record(actor, AuditUserUpdate, AuditTargetUser, strconv.FormatInt(user.ID, 10), auditUser(before), after)
+	if emailChanged {
+		s.sendVerification(actor, user)
+	}
+	return nil
+}
+
+// UpdateAvatar 更新操作者所在组织内用户的头像
+func (s *UserService) UpdateAvatar(actor models.Actor, id int64, avatar string) error {
+	before, err := s.userRepo.GetByIDInOrg(actor.OrgID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdateAvatar(id, avatar); err != nil {
+		return err
+	}
+	s.audit.Record(actor, AuditUserAvatar, AuditTargetUser, strconv.FormatInt(id, 10),
+		map[string]interface{}{"avatar": before.Avatar}, map[string]interface{}{"avatar": avatar})
+	return nil
+}
+
+// DeleteUser 将用户移出操作者所在组织；该组织是用户最后一个组织时将账号移入回收站，可在保留期内恢复
+// 返回: int64 影响行数 (用户不存在时为 0), error 错误信息 (无权删除该用户为 ErrUserManageForbidden)
+func (s *UserService) DeleteUser(actor models.Actor, id int64) (int64, error) {
+	before, err := s.userRepo.GetByIDInOrg(actor.OrgID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if !s.rbac.CanManageUser(actor.Role, before.Role) {
+		return 0, ErrUserManageForbidden
+	}
+	affected, trashed, err := s.userRepo.Delete(actor.OrgID, id, actor.UserID)
+	if err != nil || affected == 0 {
+		return affected, err
+	}
+	s.audit.Record(actor, AuditUserDelete, AuditTargetUser, strconv.FormatInt(id, 10), auditUser(before),
+		map[string]interface{}{"trashed": trashed})
+	return affected, nil
+}
+
+// GetDeletedUsers 获取操作者所在组织回收站中的用户 (分页)
+func (s *UserService) GetDeletedUsers(orgID int64, page, limit int) ([]models.User, int, error) {
+	return s.userRepo.FetchDeleted(orgID, page, limit)
+}
+
+// RestoreUser 从回收站恢复操作者所在组织内的用户
+// 返回: error 错误信息 (不在回收站中时为 repository.ErrUserNotFound, 无权恢复该用户为 ErrUserManageForbidden)
+func (s *UserService) RestoreUser(actor models.Actor, id int64) error {
+	before, err := s.userRepo.GetDeletedInOrg(actor.OrgID, id)
+	if err != nil {
+		return err
+	}
+	if !s.rbac.CanManageUser(actor.Role, before.Role) {
+		return ErrUserManageForbidden
+	}
+	affected, err := s.userRepo.Restore(actor.OrgID, id)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.ErrUserNotFound
+	}
+	s.audit.Record(actor, AuditUserRestore, AuditTargetUser, strconv.FormatInt(id, 10),
+		map[string]interface{}{"deleted_at": before.DeletedAt, "deleted_by": before.DeletedBy}, auditUser(before))
+	return nil
+}
+
+// PurgeUser 彻底删除操作者所在组织回收站中的用户，删除后无法恢复
+// 返回: error 错误信息 (不在回收站中时为 repository.ErrUserNotFound, 无权删除该用户为 ErrUserManageForbidden)
+func (s *UserService) PurgeUser(actor models.Actor, id int64) error {
+	before, err := s.userRepo.GetDeletedInOrg(actor.OrgID, id)
+	if err != nil {
+		return err
+	}
+	if !s.rbac.CanManageUser(actor.Role, before.Role) {
+		return ErrUserManageForbidden
+	}
+	affected, err := s.userRepo.Purge(actor.OrgID, id)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.ErrUserNotFound
+	}
+	s.audit.Record(actor, AuditUserPurge, AuditTargetUser, strconv.FormatInt(id, 10), auditUser(before), nil)
+	return nil
 }
 
-// GetAllUsers 获取所有用户（分页+搜索）
-func (s *UserService) GetAllUsers(page, limit int, keyword, status string) ([]models.User, int, error) {
-	return s.userRepo.FetchWithPagination(page, limit, keyword, status)
+// PurgeExpired 彻底删除在回收站中超过保留期的用户
+// 参数: retention 保留时长
+// 返回: int 删除的用户数, error 错误信息
+func (s *UserService) PurgeExpired(retention time.Duration) (int, error) {
+	purged, err := s.userRepo.PurgeDeletedBefore(time.Now().Add(-retention))
+	for _, u := range purged {
+		s.audit.Record(models.Actor{OrgID: u.OrgID}, AuditUserPurge, AuditTargetUser, strconv.FormatInt(u.ID, 10),
+			map[string]interface{}{"username": u.Username}, map[string]interface{}{"reason": "retention_expired"})
+	}
+	return len(purged), err
 }
 
-// CreateUser 创建新用户
-func (s *UserService) CreateUser(username, password string) (int64, error) {
-	return s.userRepo.Create(username, password)
+// RunTrashPurger 按固定间隔清理回收站中超过保留期的用户，阻塞运行，应在独立 goroutine 中调用
+// 参数: retention 保留时长, interval 检查间隔
+func (s *UserService) RunTrashPurger(retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.PurgeExpired(retention)
+		if err != nil {
+			utils.UserLogger.Error("清理回收站中过期用户失败: %v", err)
+		} else if n > 0 {
+			utils.UserLogger.Info("已彻底删除回收站中超过保留期的 %d 个用户", n)
+		}
+		<-ticker.C
+	}
 }
 
-// GetUserByID 根据ID获取用户信息
-func (s *UserService) GetUserByID(id int64) (*models.User, error) {
-	return s.userRepo.GetByID(id)
+// checkEmail 规范化邮箱并确认未被其他账号使用
+func checkEmail(userRepo *repository.UserRepository, email string, excludeID int64) (string, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return "", err
+	}
+	taken, err := userRepo.EmailTaken(email, excludeID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrEmailExists
+	}
+	return email, nil
 }
 
-// UpdateUser 更新用户信息
-func (s *UserService) UpdateUser(user *models.User) error {
-	return s.userRepo.Update(user)
+// sendVerification 向用户的新邮箱发送验证链接 (失败仅记录日志，不影响本次操作)
+func (s *UserService) sendVerification(actor models.Actor, user *models.User) {
+	if err := s.verifier.Send(actor, user); err != nil {
+		utils.UserLogger.Error("向用户 %d 发送邮箱验证链接失败: %v", user.ID, err)
+	}
 }
 
-// DeleteUser 删除用户
-func (s *UserService) DeleteUser(id int64) (int64, error) {
-	return s.userRepo.Delete(id)
+// auditUser 生成用于审计的用户快照 (不含密码)
+func auditUser(u *models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"username": u.Username,
+		"email":    u.Email,
+		"role":     u.Role,
+		"enable":   u.Enable,
+		"status":   u.Status,
+		"avatar":   u.Avatar,
+	}
 }
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config S3 兼容对象存储配置 (AWS S3、MinIO 等)
+type S3Config struct {
+	Endpoint  string // 如 s3.amazonaws.com 或 minio:9000 (不含协议)
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// PathStyle 使用 endpoint/bucket/key 形式的地址 (MinIO 等自建服务通常需要)
+	PathStyle bool
+	// PublicURL 桶可公开读取时的访问前缀；为空时生成预签名地址
+	PublicURL string
+	// PresignExpiry 预签名地址有效期
+	PresignExpiry time.Duration
+}
+
+// S3Storage S3 兼容对象存储
+type S3Storage struct {
+	client *minio.Client
+	cfg    S3Config
+}
+
+// NewS3Storage 创建 S3 兼容对象存储 (不会连接服务端，桶需事先创建)
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	lookup := minio.BucketLookupAuto
+	if cfg.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure:       cfg.UseSSL,
+		Region:       cfg.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Storage{client: client, cfg: cfg}, nil
+}
+
+// Put 上传对象 (对象存储的单次 PUT 是原子的)
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	_, err := s.client.PutObject(ctx, s.cfg.Bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+// Open 读取对象 (先查询对象信息，使对象不存在的错误在打开时返回)
+func (s *S3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+	obj, err := s.client.GetObject(ctx, s.cfg.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Delete 删除对象 (S3 删除不存在的对象同样返回成功)
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	return s.client.RemoveObject(ctx, s.cfg.Bucket, key, minio.RemoveObjectOptions{})
+}
+
+// URL 配置了 PublicURL 时返回公开地址，否则生成预签名 GET 地址 (配置了 Region 时在本地计算签名，不请求服务端)
+func (s *S3Storage) URL(ctx context.Context, key string) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	if s.cfg.PublicURL != "" {
+		return joinURL(s.cfg.PublicURL, key), nil
+	}
+	u, err := s.client.PresignedGetObject(ctx, s.cfg.Bucket, key, s.cfg.PresignExpiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// List 分页列出桶中的对象
+func (s *S3Storage) List(ctx context.Context, prefix string, fn func(obj Object) error) error {
+	// 提前返回时取消 ctx，结束后台的分页请求
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for info := range s.client.ListObjects(ctx, s.cfg.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if info.Err != nil {
+			return info.Err
+		}
+		if err := fn(Object{Key: info.Key, ModTime: info.LastModified}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Storage 上传文件的存储后端 (头像等)，多实例部署时使用共享的对象存储
+// key 为以 / 分隔的相对路径，如 "avatars/1_admin_256.jpg"
+type Storage interface {
+	// Put 写入对象 (已存在时覆盖)，写入完成前读取方看不到新内容
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取对象内容 (对象不存在时返回 ErrNotFound)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// URL 返回对象的访问地址 (公开地址或带有效期的预签名地址)
+	URL(ctx context.Context, key string) (string, error)
+	// List 逐个列出 key 以 prefix 开头的对象 (fn 返回错误时中止)
+	List(ctx context.Context, prefix string, fn func(obj Object) error) error
+}
+
+// Object 对象信息
+type Object struct {
+	Key     string
+	ModTime time.Time // 最后写入时间
+}
+
+var (
+	// ErrInvalidKey 对象 key 为空、为绝对路径或包含 .. 等可越出存储目录的片段
+	ErrInvalidKey = errors.New("INVALID_STORAGE_KEY")
+	// ErrNotFound 对象不存在
+	ErrNotFound = errors.New("STORAGE_OBJECT_NOT_FOUND")
+)
+
+// validKey 校验对象 key
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// joinURL 将 key 按路径片段转义后拼接到地址前缀
+func joinURL(base, key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.TrimRight(base, "/") + "/" + path.Join(parts...)
+}
+
+// LocalStorage 本地磁盘存储，由应用的静态文件路由对外提供访问 (仅适用于单实例或共享磁盘部署)
+type LocalStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage 创建本地磁盘存储
+// 参数: dir 保存目录, baseURL 对外访问前缀 (如 /images 或 https://cdn.example.com/images)
+func NewLocalStorage(dir, baseURL string) *LocalStorage {
+	return &LocalStorage{dir: dir, baseURL: baseURL}
+}
+
+// Dir 返回保存目录
+func (s *LocalStorage) Dir() string {
+	return s.dir
+}
+
+// Put 先写临时文件再重命名，避免读取方看到写了一半的文件
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	dst := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// Open 打开文件
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+	f, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete 删除文件
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// URL 返回 baseURL/key
+func (s *LocalStorage) URL(ctx context.Context, key string) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	return joinURL(s.baseURL, key), nil
+}
+
+// List 遍历保存目录 (跳过写入中的临时文件)
+func (s *LocalStorage) List(ctx context.Context, prefix string, fn func(obj Object) error) error {
+	err := filepath.WalkDir(s.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fn(Object{Key: key, ModTime: info.ModTime()})
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
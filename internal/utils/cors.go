@@ -13,6 +13,6 @@ func SetCORSHeaders(w http.ResponseWriter, methods string) {
 
 // HandleOPTIONS 处理OPTIONS预检请求
 func HandleOPTIONS(w http.ResponseWriter) {
-	SetCORSHeaders(w, "GET, POST, PUT, DELETE, OPTIONS")
+	SetCORSHeaders(w, "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 	w.WriteHeader(http.StatusOK)
 }
@@ -10,7 +10,7 @@ import (
 func ErrorResponse(w http.ResponseWriter, code int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	// 设置跨域头部
-	SetCORSHeaders(w, "GET, POST, PUT, DELETE, OPTIONS")
+	SetCORSHeaders(w, "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(models.APIResponse{
 		Success: false,
@@ -19,11 +19,25 @@ func ErrorResponse(w http.ResponseWriter, code int, message string) {
 	})
 }
 
+// ErrorDetailResponse 返回带结构化详情的错误响应 (如逐条的校验失败原因)
+func ErrorDetailResponse(w http.ResponseWriter, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	// 设置跨域头部
+	SetCORSHeaders(w, "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(models.APIResponse{
+		Success: false,
+		Code:    code,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // SuccessResponse 返回统一的成功响应
 func SuccessResponse(w http.ResponseWriter, message string, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	// 设置跨域头部
-	SetCORSHeaders(w, "GET, POST, PUT, DELETE, OPTIONS")
+	SetCORSHeaders(w, "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 	json.NewEncoder(w).Encode(models.APIResponse{
 		Success: true,
 		Code:    http.StatusOK,
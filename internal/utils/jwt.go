@@ -2,44 +2,92 @@ package utils
 
 import (
 	"errors"
-	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"time"
 )
 
-var jwtKey = []byte("my_secret_key")
+// AccessTokenTTL 访问令牌有效期
+const AccessTokenTTL = 30 * time.Minute
+
+// MFATokenTTL 二次验证待定令牌有效期
+const MFATokenTTL = 5 * time.Minute
+
+// PurposeMFAPending 密码校验通过、等待二次验证的令牌用途 (不可用于访问接口)
+const PurposeMFAPending = "mfa_pending"
+
+// SessionRef 切换组织等待二次验证时记录的旧会话，验证通过后撤销
+type SessionRef struct {
+	JTI       string `json:"jti"`           // 旧访问令牌ID
+	ExpiresAt int64  `json:"exp"`           // 旧访问令牌过期时间 (Unix 秒)
+	FamilyID  string `json:"fam,omitempty"` // 旧刷新令牌家族 (未提交刷新令牌时为空)
+}
 
 type Claims struct {
-	ID                   int64  `json:"ID,omitempty"`
-	Username             string `json:"Username,omitempty"`
-	Role                 string `json:"Role,omitempty"`
+	ID                   int64       `json:"ID,omitempty"`
+	Username             string      `json:"Username,omitempty"`
+	Role                 string      `json:"Role,omitempty"`
+	OrgID                int64       `json:"OrgID,omitempty"`    // 当前激活的组织 (租户)
+	Purpose              string      `json:"Purpose,omitempty"`  // 非空时为特殊用途令牌，不能作为访问令牌使用
+	Replaces             *SessionRef `json:"Replaces,omitempty"` // mfa_pending 令牌：验证通过后需撤销的旧会话
 	jwt.RegisteredClaims `json:"Jwt.RegisteredClaims"`
 }
 
-func GenerateToken(id int64, username, role string) (string, error) {
-	expirationTime := time.Now().Add(30 * time.Minute)
-	claims := Claims{
-		ID:       id,
-		Username: username,
-		Role:     role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "myapp",
-		},
+func GenerateToken(id int64, username, role string, orgID int64) (string, error) {
+	return signClaims(Claims{ID: id, Username: username, Role: role, OrgID: orgID}, AccessTokenTTL)
+}
+
+// GenerateMFAToken 签发等待二次验证的短期令牌 (只能在 /api/auth/mfa/verify 换取正式令牌)
+// 参数: replaces 切换组织时验证通过后需撤销的旧会话 (登录时为 nil)
+func GenerateMFAToken(id int64, username string, orgID int64, replaces *SessionRef) (string, error) {
+	return signClaims(Claims{ID: id, Username: username, OrgID: orgID, Purpose: PurposeMFAPending, Replaces: replaces}, MFATokenTTL)
+}
+
+// signClaims 补全 jti/iat/exp 后签名
+func signClaims(claims Claims, ttl time.Duration) (string, error) {
+	// 每个令牌携带唯一 jti，用于注销与撤销
+	jti, err := RandomHex(16)
+	if err != nil {
+		return "", err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		Issuer:    "myapp",
+		ID:        jti,
+	}
+	return CurrentKeyManager().Sign(claims)
 }
 
+// ParseToken 解析访问令牌 (拒绝特殊用途令牌)
 func ParseToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// 1. 【核心安全优化】校验签名算法是否匹配，防止算法替换攻击
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtKey, nil
-	})
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("token 不是访问令牌")
+	}
+	return claims, nil
+}
+
+// ParseMFAToken 解析等待二次验证的令牌
+func ParseMFAToken(tokenString string) (*Claims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != PurposeMFAPending {
+		return nil, errors.New("token 不是二次验证令牌")
+	}
+	return claims, nil
+}
+
+// parseClaims 校验签名与有效期并返回声明
+func parseClaims(tokenString string) (*Claims, error) {
+	// 1. 【核心安全优化】按 kid 选择验签密钥并校验算法是否匹配，防止算法替换攻击
+	keys := CurrentKeyManager()
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.Keyfunc, jwt.WithValidMethods(keys.Algorithms()))
 
 	if err != nil {
 		// 2. 【错误处理优化】利用 v5 内置的错误判断返回更清晰的提示
@@ -54,6 +102,10 @@ func ParseToken(tokenString string) (*Claims, error) {
 
 	// 3. 【逻辑优化】类型转换并返回。只要 err 为 nil，token.Valid 就为 true
 	if claims, ok := token.Claims.(*Claims); ok {
+		// 4. 不带 jti/iat 的令牌无法注销，一律拒绝
+		if claims.RegisteredClaims.ID == "" || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+			return nil, errors.New("token 缺少 jti")
+		}
 		return claims, nil
 	}
 
@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// KeySpec 签名密钥配置
+// HS256 使用 Secret 或 SecretFile；RS256/EdDSA 使用 PEM 格式的 PrivateKeyFile (签名) 或 PublicKeyFile (仅验签)
+type KeySpec struct {
+	ID             string
+	Algorithm      string
+	Secret         string
+	SecretFile     string
+	PrivateKeyFile string
+	PublicKeyFile  string
+}
+
+// SigningKey 已加载的签名/验签密钥
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// CanSign 是否持有私钥 (可用于签发令牌)
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// KeyManager JWT 密钥管理器
+// 使用当前激活密钥签名并写入 kid 头；轮换窗口内同时接受多把验签密钥
+type KeyManager struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// NewKeyManager 创建密钥管理器
+// 参数: active 当前签名密钥, verify 轮换窗口内仍需接受的旧密钥
+func NewKeyManager(active *SigningKey, verify ...*SigningKey) (*KeyManager, error) {
+	if active == nil || !active.CanSign() {
+		return nil, errors.New("激活密钥必须包含签名私钥")
+	}
+	m := &KeyManager{active: active, keys: map[string]*SigningKey{active.ID: active}}
+	for _, k := range verify {
+		if _, dup := m.keys[k.ID]; dup {
+			return nil, fmt.Errorf("密钥 kid 重复: %s", k.ID)
+		}
+		m.keys[k.ID] = k
+	}
+	return m, nil
+}
+
+// Sign 使用激活密钥签发令牌，并写入 kid 头
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(m.active.Algorithm), claims)
+	token.Header["kid"] = m.active.ID
+	return token.SignedString(m.active.signKey)
+}
+
+// Keyfunc 根据 kid 头选择验签密钥，并校验算法与密钥是否匹配，防止算法替换攻击
+func (m *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token 缺少 kid")
+	}
+
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("未知的 kid: %s", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// Algorithms 返回当前接受的全部签名算法
+func (m *KeyManager) Algorithms() []string {
+	seen := make(map[string]bool)
+	var algs []string
+	for _, k := range m.keys {
+		if !seen[k.Algorithm] {
+			seen[k.Algorithm] = true
+			algs = append(algs, k.Algorithm)
+		}
+	}
+	return algs
+}
+
+// JWKS 导出公钥集合 (RFC 7517)，对称密钥不会被导出
+func (m *KeyManager) JWKS() map[string]interface{} {
+	keys := make([]map[string]string, 0, len(m.keys))
+	for _, k := range m.keys {
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": k.Algorithm,
+				"kid": k.ID,
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"use": "sig",
+				"alg": k.Algorithm,
+				"kid": k.ID,
+				"x":   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+// LoadSigningKey 根据配置加载密钥
+func LoadSigningKey(spec KeySpec) (*SigningKey, error) {
+	if spec.ID == "" {
+		return nil, errors.New("密钥缺少 id")
+	}
+	k := &SigningKey{ID: spec.ID, Algorithm: spec.Algorithm}
+
+	switch spec.Algorithm {
+	case AlgHS256:
+		secret := []byte(spec.Secret)
+		if spec.SecretFile != "" {
+			b, err := os.ReadFile(spec.SecretFile)
+			if err != nil {
+				return nil, fmt.Errorf("读取密钥 %s 失败: %w", spec.ID, err)
+			}
+			secret = []byte(strings.TrimSpace(string(b)))
+		}
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("HS256 密钥 %s 长度不足 32 字节", spec.ID)
+		}
+		k.signKey, k.verifyKey = secret, secret
+
+	case AlgRS256, AlgEdDSA:
+		if spec.PrivateKeyFile != "" {
+			priv, err := readPEMKey(spec.PrivateKeyFile, true)
+			if err != nil {
+				return nil, fmt.Errorf("加载密钥 %s 失败: %w", spec.ID, err)
+			}
+			signer, ok := priv.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("密钥 %s 不是有效的私钥", spec.ID)
+			}
+			k.signKey, k.verifyKey = priv, signer.Public()
+		} else if spec.PublicKeyFile != "" {
+			pub, err := readPEMKey(spec.PublicKeyFile, false)
+			if err != nil {
+				return nil, fmt.Errorf("加载密钥 %s 失败: %w", spec.ID, err)
+			}
+			k.verifyKey = pub
+		} else {
+			return nil, fmt.Errorf("密钥 %s 未指定 private_key_file 或 public_key_file", spec.ID)
+		}
+		if err := checkKeyType(k); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("不支持的签名算法: %s", spec.Algorithm)
+	}
+	return k, nil
+}
+
+// GenerateEphemeralKey 生成仅存在于本进程内存中的随机 HS256 密钥 (未配置密钥时的开发兜底)
+func GenerateEphemeralKey() (*SigningKey, error) {
+	secret, err := RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: "ephemeral", Algorithm: AlgHS256, signKey: []byte(secret), verifyKey: []byte(secret)}, nil
+}
+
+// readPEMKey 读取 PEM 文件并解析私钥 (PKCS#8 / PKCS#1) 或公钥 (PKIX)
+func readPEMKey(path string, private bool) (interface{}, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("无效的 PEM 文件")
+	}
+	if !private {
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// checkKeyType 校验密钥类型与声明的算法一致
+func checkKeyType(k *SigningKey) error {
+	switch k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		if k.Algorithm == AlgRS256 {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if k.Algorithm == AlgEdDSA {
+			return nil
+		}
+	}
+	return fmt.Errorf("密钥 %s 的类型与算法 %s 不匹配", k.ID, k.Algorithm)
+}
+
+// 全局密钥管理器
+var (
+	keyManagerMu sync.RWMutex
+	keyManager   *KeyManager
+)
+
+// SetKeyManager 设置全局密钥管理器
+func SetKeyManager(m *KeyManager) {
+	keyManagerMu.Lock()
+	defer keyManagerMu.Unlock()
+	keyManager = m
+}
+
+// CurrentKeyManager 获取全局密钥管理器
+func CurrentKeyManager() *KeyManager {
+	keyManagerMu.RLock()
+	defer keyManagerMu.RUnlock()
+	return keyManager
+}
+
+// InitKeyManager 根据签名密钥与轮换密钥配置初始化全局密钥管理器
+// 未配置签名密钥时生成临时随机密钥：重启后已签发的令牌全部失效，仅适用于开发环境
+func InitKeyManager(active *KeySpec, verify []KeySpec) error {
+	var activeKey *SigningKey
+	var err error
+	if active == nil || active.ID == "" {
+		SystemLogger.Error("未配置 JWT 签名密钥，使用临时随机密钥 (重启后令牌全部失效，请勿用于生产环境)")
+		activeKey, err = GenerateEphemeralKey()
+	} else {
+		activeKey, err = LoadSigningKey(*active)
+	}
+	if err != nil {
+		return err
+	}
+
+	verifyKeys := make([]*SigningKey, 0, len(verify))
+	for _, spec := range verify {
+		k, err := LoadSigningKey(spec)
+		if err != nil {
+			return err
+		}
+		verifyKeys = append(verifyKeys, k)
+	}
+
+	m, err := NewKeyManager(activeKey, verifyKeys...)
+	if err != nil {
+		return err
+	}
+	SetKeyManager(m)
+	SystemLogger.Info("JWT 密钥已加载: 签名密钥 %s (%s)，验签密钥 %d 把", activeKey.ID, activeKey.Algorithm, len(m.keys))
+	return nil
+}
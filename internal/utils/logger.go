@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -15,24 +16,28 @@ type Logger struct {
 }
 
 // NewLogger 创建新的日志器
-func NewLogger(logDir string) *Logger {
+func NewLogger(logDir string) (*Logger, error) {
 	// 确保日志目录存在
 	if err := os.MkdirAll(logDir, 0755); err != nil {
-		log.Fatalf("创建日志目录失败: %v", err)
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
 	}
 
 	// 创建统一的日志文件
 	logFile := filepath.Join(logDir, "app.log")
 	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
-		log.Fatalf("打开日志文件失败: %v", err)
+		return nil, fmt.Errorf("打开日志文件失败: %w", err)
 	}
 
-	// 创建日志器
+	return newLogger(file), nil
+}
+
+// newLogger 基于指定输出创建日志器
+func newLogger(out io.Writer) *Logger {
 	return &Logger{
-		infoLogger:  log.New(file, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile),
-		errorLogger: log.New(file, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile),
-		debugLogger: log.New(file, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile),
+		infoLogger:  log.New(out, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile),
+		errorLogger: log.New(out, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile),
+		debugLogger: log.New(out, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile),
 	}
 }
 
@@ -56,8 +61,8 @@ func (l *Logger) Debug(format string, v ...interface{}) {
 
 // 全局日志器
 var (
-	// AppLogger 统一应用日志记录器
-	AppLogger = NewLogger("logs/app")
+	// AppLogger 统一应用日志记录器 (InitLoggers 之前仅输出到控制台)
+	AppLogger = newLogger(io.Discard)
 
 	// 为了兼容旧代码，暂时保留这些引用，但全部指向 AppLogger
 	AuthLogger   = AppLogger
@@ -66,7 +71,15 @@ var (
 )
 
 // InitLoggers 初始化日志记录器
-func InitLoggers() error {
-	// 日志记录器在包初始化时已经创建，这里可以添加额外的初始化逻辑
+// 参数: logDir 日志目录 (来自配置)
+func InitLoggers(logDir string) error {
+	logger, err := NewLogger(logDir)
+	if err != nil {
+		return err
+	}
+	AppLogger = logger
+	AuthLogger = logger
+	SystemLogger = logger
+	UserLogger = logger
 	return nil
 }
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP 获取客户端 IP
+// 仅当直连对端为本机 (同机部署的反向代理) 时才信任 X-Real-IP / X-Forwarded-For，防止客户端伪造
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return host
+	}
+
+	if v := strings.TrimSpace(r.Header.Get("X-Real-IP")); v != "" {
+		return v
+	}
+	if v := r.Header.Get("X-Forwarded-For"); v != "" {
+		// 最右侧的地址由本机代理追加，最可信
+		parts := strings.Split(v, ",")
+		return strings.TrimSpace(parts[len(parts)-1])
+	}
+	return host
+}
@@ -4,6 +4,9 @@
 document.addEventListener('DOMContentLoaded', () => {
     const loginForm = document.getElementById('loginForm');
     const submitBtn = document.getElementById('submitBtn');
+    const mfaForm = document.getElementById('mfaForm');
+    const mfaSubmitBtn = document.getElementById('mfaSubmitBtn');
+    let mfaToken = '';
 
     // 监听表单提交事件
     loginForm.addEventListener('submit', async (e) => {
@@ -42,22 +45,17 @@ document.addEventListener('DOMContentLoaded', () => {
                 // 【核心修改】：从 result.data 中提取 token 和用户信息
                 const userData = result.data;
 
-                if (userData && userData.token) {
-                    console.log("登录成功，正在保存 Token");
-
-                    // 存储 Token 和相关信息
-                    localStorage.setItem('auth_token', userData.token);
-                    localStorage.setItem('user_id'  , userData.id || '');
-                    localStorage.setItem('user_role', userData.role || '');
-                    localStorage.setItem('user_name', userData.username || '');
-
-                    // 【关键跳转】：确保路径正确
-                    console.log("即将跳转至项目首页...");
-                    window.location.href = '/html/index.html';
+                if (userData && userData.mfa_required) {
+                    // 需要二次验证：切换到验证码输入
+                    showMfaForm(userData);
+                } else if (userData && userData.token) {
+                    completeLogin(userData);
                 } else {
                     console.error("跳转失败：在 result.data 中未找到 token 字段", result);
                     alert("服务器响应数据异常，请检查后端结构");
                 }
+            } else if (result.data && result.data.password_expired) {
+                passwordExpired(result.data);
             } else {
                 // 情况 B: 校验失败 (HTTP 401, 403, 500 等)
                 // 对应你在 Go 中处理的各种错误返回
@@ -73,6 +71,88 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     });
 
+    /**
+     * 显示二次验证表单；角色要求二次验证但尚未登记时同时显示二维码
+     */
+    function showMfaForm(data) {
+        mfaToken = data.mfa_token;
+        loginForm.classList.add('hidden');
+        mfaForm.classList.remove('hidden');
+        if (data.enrollment) {
+            document.getElementById('mfaQRCode').src = data.enrollment.qr_png;
+            document.getElementById('mfaSecret').innerText = data.enrollment.secret;
+            document.getElementById('mfaEnrollment').classList.remove('hidden');
+        }
+        document.getElementById('mfaCode').focus();
+    }
+
+    // 提交二次验证码，换取正式令牌
+    mfaForm.addEventListener('submit', async (e) => {
+        e.preventDefault();
+        const code = document.getElementById('mfaCode').value.trim();
+        mfaSubmitBtn.disabled = true;
+
+        try {
+            const response = await fetch('/api/auth/mfa/verify', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ mfa_token: mfaToken, code: code })
+            });
+            const result = await response.json();
+            if (response.ok && result.data && result.data.token) {
+                if (result.data.recovery_codes) {
+                    alert('二次验证已启用。请妥善保存以下恢复码，每个只能使用一次：\n\n' + result.data.recovery_codes.join('\n'));
+                }
+                completeLogin(result.data);
+            } else if (result.data && result.data.password_expired) {
+                if (result.data.recovery_codes) {
+                    alert('二次验证已启用。请妥善保存以下恢复码，每个只能使用一次：\n\n' + result.data.recovery_codes.join('\n'));
+                }
+                passwordExpired(result.data);
+            } else if (response.status === 401) {
+                // mfa_token 过期或已使用，需重新输入密码
+                alert(result.message || '验证已过期，请重新登录');
+                window.location.reload();
+            } else {
+                handleErrorResponse(response.status, result.message);
+            }
+        } catch (error) {
+            console.error('Fetch Error:', error);
+            alert('无法连接到服务器，请检查后端程序是否运行');
+        } finally {
+            mfaSubmitBtn.disabled = false;
+        }
+    });
+
+    /**
+     * 保存令牌与用户信息并进入首页
+     */
+    function completeLogin(userData) {
+        console.log("登录成功，正在保存 Token");
+
+        // 存储 Token 和相关信息
+        localStorage.setItem('auth_token', userData.token);
+        localStorage.setItem('refresh_token', userData.refresh_token || '');
+        localStorage.setItem('user_id'  , userData.id || '');
+        localStorage.setItem('user_role', userData.role || '');
+        localStorage.setItem('user_name', userData.username || '');
+        localStorage.setItem('user_permissions', JSON.stringify(userData.permissions || []));
+        localStorage.setItem('org_id', userData.org_id || '');
+        localStorage.setItem('user_orgs', JSON.stringify(userData.orgs || []));
+
+        // 【关键跳转】：确保路径正确
+        console.log("即将跳转至项目首页...");
+        window.location.href = '/html/index.html';
+    }
+
+    /**
+     * 密码超过最长有效期：服务端不签发令牌，凭返回的一次性重置令牌设置新密码后重新登录
+     */
+    function passwordExpired(data) {
+        alert('您的密码已过期，请设置新密码后重新登录');
+        window.location.href = '/html/reset-password.html?token=' + encodeURIComponent(data.reset_token);
+    }
+
     /**
      * 根据不同的 HTTP 状态码提供精确反馈
      */
@@ -84,6 +164,13 @@ document.addEventListener('DOMContentLoaded', () => {
             case 403:
                 alert('访问受限：' + (message || '您的账号已被禁用'));
                 break;
+            case 400:
+                alert(message || '请求参数错误');
+                break;
+            case 423:
+            case 429:
+                alert('账户已锁定：' + (message || '登录失败次数过多，请稍后再试'));
+                break;
             case 500:
                 alert('服务器内部错误，请检查后端数据库连接');
                 break;
@@ -36,10 +36,27 @@ function setPageTitle(title) {
 
 /**
  * 4. 退出登录逻辑
- * 清除本地缓存并跳转至登录页
+ * 通知服务端注销会话，清除本地缓存并跳转至登录页
  */
-function logout() {
+async function logout() {
     if (confirm('确定要退出系统吗？')) {
+        // 通知服务端撤销当前访问令牌与刷新令牌 (失败不影响本地退出)
+        const token = localStorage.getItem('auth_token');
+        if (token) {
+            try {
+                await fetch('/api/auth/logout', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json',
+                        'Authorization': `Bearer ${token}`
+                    },
+                    body: JSON.stringify({ refresh_token: localStorage.getItem('refresh_token') || '' })
+                });
+            } catch (error) {
+                console.warn('服务端注销失败:', error);
+            }
+        }
+
         // 清除存储的 Token 或用户信息
         localStorage.clear();
         sessionStorage.clear();
@@ -71,10 +88,55 @@ function formatDateTime(dateSource) {
 }
 
 /**
- * 6. 通用 Fetch 请求封装
- * 自动处理：Token 携带、New-Token 更新、401 自动跳转
+ * 判断当前登录用户是否拥有指定权限 (仅用于界面展示，最终以后端校验为准)
+ * @param {string} permission 权限标识，如 users:create
+ * @returns {boolean}
+ */
+function hasPermission(permission) {
+    try {
+        const perms = JSON.parse(localStorage.getItem('user_permissions') || '[]');
+        return Array.isArray(perms) && perms.includes(permission);
+    } catch (e) {
+        return false;
+    }
+}
+
+/**
+ * 6. 使用刷新令牌换取新的访问令牌
+ * 多个请求同时遇到 401 时共享同一次刷新，避免旧刷新令牌被重复提交导致整个会话被撤销
+ * @returns {Promise<boolean>} 是否刷新成功
  */
-async function request(url, options = {}) {
+let refreshPromise = null;
+function refreshAccessToken() {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (!refreshToken) return Promise.resolve(false);
+
+    if (!refreshPromise) {
+        refreshPromise = fetch('/api/auth/refresh', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken })
+        }).then(async (response) => {
+            if (!response.ok) return false;
+            const result = await response.json();
+            localStorage.setItem('auth_token', result.data.token);
+            localStorage.setItem('refresh_token', result.data.refresh_token);
+            localStorage.setItem('user_role', result.data.role || '');
+            localStorage.setItem('user_permissions', JSON.stringify(result.data.permissions || []));
+            localStorage.setItem('org_id', result.data.org_id || '');
+            return true;
+        }).catch(() => false).finally(() => {
+            refreshPromise = null;
+        });
+    }
+    return refreshPromise;
+}
+
+/**
+ * 7. 通用 Fetch 请求封装
+ * 自动处理：Token 携带、New-Token 更新、401 自动刷新令牌并重试、刷新失败跳转登录
+ */
+async function request(url, options = {}, retried = false) {
     // 从本地获取 Token
     const token = localStorage.getItem('auth_token');
 
@@ -107,7 +169,10 @@ async function request(url, options = {}) {
             console.log("Token 已根据权限变更自动更新");
         }
 
-        // B. 处理身份失效 (401)
+        // B. 处理身份失效 (401)：先尝试刷新令牌，成功后重试一次
+        if (response.status === 401 && !retried && await refreshAccessToken()) {
+            return request(url, options, true);
+        }
         if (response.status === 401) {
             alert("登录已过期，请重新登录");
             localStorage.clear();
@@ -0,0 +1,55 @@
+/**
+ * 密码策略：从后端读取当前策略，用于页面提示、提交前预校验以及展示后端返回的逐条违规原因
+ * 黑名单与历史密码只能由后端校验，前端仅检查长度与字符类别
+ */
+
+// 读取密码策略，失败时返回 null (提交后仍以后端校验为准)
+async function loadPasswordPolicy() {
+    try {
+        const response = await fetch('/api/auth/password-policy');
+        if (!response.ok) return null;
+        const result = await response.json();
+        return result.data || null;
+    } catch (error) {
+        console.error('读取密码策略失败:', error);
+        return null;
+    }
+}
+
+// 生成策略说明，如 "8-64位，须包含小写字母、数字"
+function describePasswordPolicy(policy) {
+    if (!policy) return '';
+    const classes = [];
+    if (policy.require_upper) classes.push('大写字母');
+    if (policy.require_lower) classes.push('小写字母');
+    if (policy.require_digit) classes.push('数字');
+    if (policy.require_symbol) classes.push('特殊符号');
+
+    let text = `${policy.min_length}-${policy.max_length}位`;
+    if (classes.length) text += `，须包含${classes.join('、')}`;
+    if (policy.history_count > 0) text += `，不能与最近${policy.history_count}次的密码相同`;
+    return text;
+}
+
+// 按策略预校验密码，返回未满足的规则说明列表 (空数组表示通过)
+function checkPasswordPolicy(policy, password) {
+    if (!policy) return password ? [] : ['密码不能为空'];
+    const problems = [];
+    const length = Array.from(password).length;
+    if (length < policy.min_length) problems.push(`密码长度不能少于 ${policy.min_length} 位`);
+    if (length > policy.max_length) problems.push(`密码长度不能超过 ${policy.max_length} 位`);
+    if (policy.require_upper && !/\p{Lu}/u.test(password)) problems.push('密码须包含大写字母');
+    if (policy.require_lower && !/\p{Ll}/u.test(password)) problems.push('密码须包含小写字母');
+    if (policy.require_digit && !/\p{Nd}/u.test(password)) problems.push('密码须包含数字');
+    if (policy.require_symbol && !/[^\p{L}\p{Nd}]/u.test(password)) problems.push('密码须包含特殊符号');
+    return problems;
+}
+
+// 将后端响应转换为提示文字：密码策略错误时逐条列出违规原因
+function formatPasswordViolations(result) {
+    const violations = result && result.data && result.data.violations;
+    if (!Array.isArray(violations) || violations.length === 0) {
+        return (result && result.message) || '';
+    }
+    return result.message + '：\n' + violations.map(v => '· ' + v.message).join('\n');
+}
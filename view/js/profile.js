@@ -0,0 +1,112 @@
+/**
+ * profile.js
+ * 职责：个人资料页，查看本人资料、修改邮箱与修改密码
+ */
+
+let passwordPolicy = null;
+
+document.addEventListener('DOMContentLoaded', async () => {
+    document.getElementById('profileForm').addEventListener('submit', saveProfile);
+    document.getElementById('passwordForm').addEventListener('submit', changePassword);
+
+    passwordPolicy = await loadPasswordPolicy();
+    document.getElementById('passwordHint').textContent = describePasswordPolicy(passwordPolicy);
+
+    await loadProfile();
+});
+
+// 读取本人资料并渲染
+async function loadProfile() {
+    const response = await request('/api/users/me');
+    if (!response) return;
+    const result = await response.json();
+    if (!response.ok) {
+        alert(result.message || '读取个人资料失败');
+        return;
+    }
+    renderProfile(result.data);
+}
+
+// 渲染资料卡片
+function renderProfile(data) {
+    const user = data.user;
+    document.getElementById('profileUsername').textContent = user.username;
+    document.getElementById('profileRole').textContent = user.role || '';
+    document.getElementById('profileEmail').value = user.email || '';
+
+    const avatar = document.getElementById('profileAvatar');
+    if (user.avatar) {
+        avatar.src = user.avatar;
+        avatar.classList.remove('hidden');
+    }
+
+    const badge = document.getElementById('emailBadge');
+    if (!user.email) {
+        badge.textContent = '';
+        badge.className = 'hidden';
+    } else if (user.email_verified) {
+        badge.textContent = '已验证';
+        badge.className = 'ml-1 text-xs px-2 py-0.5 rounded-full bg-green-100 text-green-800';
+    } else {
+        badge.textContent = '未验证';
+        badge.className = 'ml-1 text-xs px-2 py-0.5 rounded-full bg-yellow-100 text-yellow-800';
+    }
+
+    document.getElementById('passwordExpiredTip').classList.toggle('hidden', !data.password_expired);
+    localStorage.setItem('user_permissions', JSON.stringify(data.permissions || []));
+}
+
+// 保存资料 (仅邮箱)
+async function saveProfile(event) {
+    event.preventDefault();
+    const email = document.getElementById('profileEmail').value.trim();
+
+    const response = await request('/api/users/me', {
+        method: 'PATCH',
+        body: JSON.stringify({ email })
+    });
+    if (!response) return;
+    const result = await response.json();
+    if (!response.ok) {
+        alert(result.message || '保存失败');
+        return;
+    }
+    renderProfile(result.data);
+    alert(result.data.user.email && !result.data.user.email_verified
+        ? '资料已保存，验证链接已发送到新邮箱'
+        : '资料已保存');
+}
+
+// 修改密码，成功后清除本地登录状态并跳转登录页
+async function changePassword(event) {
+    event.preventDefault();
+    const currentPassword = document.getElementById('currentPassword').value;
+    const newPassword = document.getElementById('newPassword').value;
+    const confirmPassword = document.getElementById('confirmPassword').value;
+
+    if (newPassword !== confirmPassword) {
+        alert('两次输入的新密码不一致');
+        return;
+    }
+    const problems = checkPasswordPolicy(passwordPolicy, newPassword);
+    if (problems.length) {
+        alert(problems.join('\n'));
+        return;
+    }
+
+    const response = await request('/api/users/me/password', {
+        method: 'POST',
+        body: JSON.stringify({ current_password: currentPassword, new_password: newPassword })
+    });
+    if (!response) return;
+    const result = await response.json();
+    if (!response.ok) {
+        alert(formatPasswordViolations(result) || '修改密码失败');
+        return;
+    }
+
+    alert(result.message || '密码已修改，请重新登录');
+    localStorage.clear();
+    sessionStorage.clear();
+    window.location.href = '/html/login.html';
+}
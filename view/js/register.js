@@ -1,17 +1,24 @@
 document.addEventListener('DOMContentLoaded', () => {
     const form = document.getElementById('registerForm');
     const usernameInput = document.getElementById('username');
+    const emailInput = document.getElementById('email');
     const passwordInput = document.getElementById('password');
     const confirmInput = document.getElementById('confirm_password');
 
     // 提示元素
     const userHint = document.getElementById('usernameHint');
+    const emailHint = document.getElementById('emailHint');
     const passHint = document.getElementById('passwordHint');
     const passError = document.getElementById('passwordError');
 
-    // 正则表达式：4-16位用户名，6位数字密码
+    // 正则表达式：4-16位用户名，邮箱；密码规则由后端密码策略决定
     const userRegex = /^[a-zA-Z0-9_]{4,16}$/;
-    const passRegex = /^\d{6}$/;
+    const emailRegex = /^[^\s@]+@[^\s@]+\.[^\s@]+$/;
+    let passwordPolicy = null;
+    loadPasswordPolicy().then(policy => {
+        passwordPolicy = policy;
+        if (policy) passHint.textContent = describePasswordPolicy(policy);
+    });
 
     // --- 校验函数 ---
     const validateUsername = () => {
@@ -28,14 +35,29 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     };
 
+    const validateEmail = () => {
+        if (emailRegex.test(emailInput.value.trim())) {
+            emailHint.textContent = "邮箱格式正确 ✅";
+            emailHint.className = "mt-1 text-xs text-green-600";
+            emailInput.classList.replace('border-gray-300', 'border-green-500');
+            return true;
+        } else {
+            emailHint.textContent = "格式错误：请输入有效的邮箱地址";
+            emailHint.className = "mt-1 text-xs text-red-500";
+            emailInput.classList.add('border-red-500');
+            return false;
+        }
+    };
+
     const validatePassword = () => {
-        if (passRegex.test(passwordInput.value)) {
+        const problems = checkPasswordPolicy(passwordPolicy, passwordInput.value);
+        if (problems.length === 0) {
             passHint.textContent = "密码格式正确 ✅";
             passHint.className = "mt-1 text-xs text-green-600";
             passwordInput.classList.replace('border-gray-300', 'border-green-500');
             return true;
         } else {
-            passHint.textContent = "错误：必须是6位数字";
+            passHint.textContent = "错误：" + problems.join('；');
             passHint.className = "mt-1 text-xs text-red-500";
             passwordInput.classList.add('border-red-500');
             return false;
@@ -57,6 +79,7 @@ document.addEventListener('DOMContentLoaded', () => {
 
     // --- 事件监听 ---
     usernameInput.addEventListener('input', validateUsername);
+    emailInput.addEventListener('input', validateEmail);
     passwordInput.addEventListener('input', validatePassword);
     confirmInput.addEventListener('input', validateMatch);
 
@@ -65,10 +88,11 @@ document.addEventListener('DOMContentLoaded', () => {
         e.preventDefault(); // 阻止表单默认提交行为，改为 AJAX 提交
 
         const isUserOk = validateUsername(); //
+        const isEmailOk = validateEmail();
         const isPassOk = validatePassword(); //
         const isMatch = validateMatch();     //
 
-        if (!isUserOk || !isPassOk || !isMatch) {
+        if (!isUserOk || !isEmailOk || !isPassOk || !isMatch) {
             alert("请完善信息后再提交！");
             return;
         }
@@ -76,6 +100,7 @@ document.addEventListener('DOMContentLoaded', () => {
         // 构建 JSON 数据
         const registerData = {
             username: usernameInput.value,
+            email: emailInput.value.trim(),
             password: passwordInput.value
         };
 
@@ -91,13 +116,14 @@ document.addEventListener('DOMContentLoaded', () => {
             .then(res => {
                 // 根据你后端的输出格式：{"code": 200, "message": "注册成功", "data": {...}}
                 if (res.code === 200) {
-                    alert(res.message + "！点击确定跳转登录。");
+                    // 账号需先验证邮箱才能登录
+                    alert(res.message + "！验证完成后即可登录。");
 
                     // 成功跳转：这里路径需对应你的 main.go 静态资源挂载路径
                     window.location.href = "/html/login.html";
                 } else {
-                    // 注册失败提示（如用户名已存在）
-                    alert("注册失败: " + res.message);
+                    // 注册失败提示（如用户名已存在、密码不符合策略）
+                    alert("注册失败: " + formatPasswordViolations(res));
                 }
             })
             .catch(error => {
@@ -0,0 +1,84 @@
+/**
+ * 找回密码页面：无 token 时申请重置邮件，带 token 时设置新密码
+ */
+document.addEventListener('DOMContentLoaded', () => {
+    const forgotForm = document.getElementById('forgotForm');
+    const resetForm = document.getElementById('resetForm');
+    const passwordInput = document.getElementById('password');
+    const confirmInput = document.getElementById('confirm_password');
+    const passError = document.getElementById('passwordError');
+
+    // 与注册页保持一致：密码规则由后端密码策略决定
+    let passwordPolicy = null;
+    loadPasswordPolicy().then(policy => {
+        passwordPolicy = policy;
+        if (policy) document.getElementById('passwordHint').textContent = describePasswordPolicy(policy);
+    });
+
+    // 邮件链接形如 /html/reset-password.html?token=xxx
+    const token = new URLSearchParams(window.location.search).get('token');
+    if (token) {
+        document.getElementById('pageTitle').textContent = '设置新密码';
+        resetForm.classList.remove('hidden');
+    } else {
+        forgotForm.classList.remove('hidden');
+    }
+
+    // 第一步：申请重置邮件
+    forgotForm.addEventListener('submit', async (e) => {
+        e.preventDefault();
+        const btn = document.getElementById('forgotBtn');
+        btn.disabled = true;
+
+        try {
+            const response = await fetch('/api/auth/forgot-password', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ username: document.getElementById('username').value.trim() })
+            });
+            const result = await response.json();
+            alert(result.message || (response.ok ? '请求已提交' : '请求失败'));
+        } catch (error) {
+            console.error('Fetch Error:', error);
+            alert('无法连接到服务器，请检查后端程序是否运行');
+        } finally {
+            btn.disabled = false;
+        }
+    });
+
+    // 第二步：设置新密码
+    resetForm.addEventListener('submit', async (e) => {
+        e.preventDefault();
+        const problems = checkPasswordPolicy(passwordPolicy, passwordInput.value);
+        if (problems.length > 0) {
+            alert(problems.join('\n'));
+            return;
+        }
+        if (passwordInput.value !== confirmInput.value) {
+            passError.classList.remove('hidden');
+            return;
+        }
+        passError.classList.add('hidden');
+
+        const btn = document.getElementById('resetBtn');
+        btn.disabled = true;
+
+        try {
+            const response = await fetch('/api/auth/reset-password', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ token: token, password: passwordInput.value })
+            });
+            const result = await response.json();
+            alert(formatPasswordViolations(result) || (response.ok ? '密码已重置' : '重置失败'));
+            if (response.ok) {
+                window.location.href = '/html/login.html';
+            }
+        } catch (error) {
+            console.error('Fetch Error:', error);
+            alert('无法连接到服务器，请检查后端程序是否运行');
+        } finally {
+            btn.disabled = false;
+        }
+    });
+});
@@ -11,18 +11,42 @@ let totalItems = 0;       // 总记录数（从后端获取）
 let cachedUsers = [];     // 用于存放当前页数据缓存，实现快速回显
 let searchKeyword = '';   // 搜索关键词
 let statusFilter = '';    // 状态筛选
+let roleFilter = '';      // 角色筛选
+let sortOption = '';      // 排序，格式为 字段:方向 (如 last_login:desc)，为空时按 ID 正序
+let trashPage = 1;        // 回收站当前页码
+let trashTotal = 0;       // 回收站总记录数
+let importReport = null;  // 最近一次导入的结果报告
+let selectedUserIDs = new Set(); // 批量操作勾选的用户ID
 
 // 2. 页面加载初始化
 document.addEventListener('DOMContentLoaded', () => {
-    const role = localStorage.getItem('user_role');
-
-    if (role !== "admin"){
+    if (!hasPermission('users:create')){
         const createBtn = document.querySelector('button[onclick="openUserModal()"]');
         if (createBtn){
             createBtn.remove();
-            console.log("检测到非管理员身份，已移除");
+            console.log("当前账号没有 users:create 权限，已移除新增按钮");
         }
     }
+    if (!hasPermission('users:create')) {
+        const importBtn = document.getElementById('importBtn');
+        if (importBtn) importBtn.remove();
+    }
+    // 批量操作：按权限保留可执行的操作，均无权限时隐藏勾选列
+    document.querySelectorAll('#bulkAction option').forEach(option => {
+        if (!hasPermission(option.dataset.perm)) option.remove();
+    });
+    if (!canBulkOperate()) {
+        const header = document.getElementById('bulkSelectHeader');
+        if (header) header.remove();
+    }
+    if (!hasPermission('users:export')) {
+        const exportGroup = document.getElementById('exportGroup');
+        if (exportGroup) exportGroup.remove();
+    }
+    if (!hasPermission('users:delete')) {
+        const trashBtn = document.getElementById('trashBtn');
+        if (trashBtn) trashBtn.remove();
+    }
     // 设置页面标题
     if (typeof setPageTitle === 'function') {
         setPageTitle('用户管理');
@@ -66,8 +90,47 @@ document.addEventListener('DOMContentLoaded', () => {
             loadUserList(currentPage);
         });
     }
+
+    // 初始化角色筛选与排序事件监听
+    const roleFilterSelect = document.getElementById('roleFilter');
+    if (roleFilterSelect) {
+        roleFilterSelect.addEventListener('change', () => {
+            roleFilter = roleFilterSelect.value;
+            currentPage = 1;
+            loadUserList(currentPage);
+        });
+    }
+    const sortSelect = document.getElementById('sortSelect');
+    if (sortSelect) {
+        sortSelect.addEventListener('change', () => {
+            sortOption = sortSelect.value;
+            currentPage = 1;
+            loadUserList(currentPage);
+        });
+    }
 });
 
+/**
+ * 用户列表与导出共用的筛选、排序参数
+ */
+function listFilterParams() {
+    let params = '';
+    if (searchKeyword) {
+        params += `&keyword=${encodeURIComponent(searchKeyword)}`;
+    }
+    if (statusFilter) {
+        params += `&status=${statusFilter}`;
+    }
+    if (roleFilter) {
+        params += `&role=${encodeURIComponent(roleFilter)}`;
+    }
+    if (sortOption) {
+        const [sort, order] = sortOption.split(':');
+        params += `&sort=${sort}&order=${order}`;
+    }
+    return params;
+}
+
 /**
  * 3. 从后端获取用户数据
  * 使用 request 封装，自动处理 Authorization Header
@@ -76,13 +139,7 @@ async function loadUserList(page) {
     try {
         currentPage = page;
         // 构建查询参数
-        let queryParams = `page=${page}&limit=${pageSize}`;
-        if (searchKeyword) {
-            queryParams += `&keyword=${encodeURIComponent(searchKeyword)}`;
-        }
-        if (statusFilter) {
-            queryParams += `&status=${statusFilter}`;
-        }
+        const queryParams = `page=${page}&limit=${pageSize}` + listFilterParams();
         // 使用 main.js 封装的 request
         const response = await request(`/api/users?${queryParams}`);
 
@@ -96,8 +153,8 @@ async function loadUserList(page) {
         cachedUsers = Array.isArray(usersData) ? usersData : [];
         totalItems = totalData || 0;
 
-        // 按用户 ID 正序排序
-        cachedUsers.sort((a, b) => a.id - b.id);
+        // 翻页或重新筛选后清空勾选
+        clearBulkSelection();
 
         // 渲染表格
         renderUserTable(cachedUsers);
@@ -107,7 +164,7 @@ async function loadUserList(page) {
     } catch (error) {
         console.error('加载用户列表失败:', error);
         document.getElementById('userTableBody').innerHTML =
-            `<tr><td colspan="5" class="text-center py-4 text-red-500">数据加载失败，请检查身份验证或网络。</td></tr>`;
+            `<tr><td colspan="6" class="text-center py-4 text-red-500">数据加载失败，请检查身份验证或网络。</td></tr>`;
     }
 }
 
@@ -121,6 +178,7 @@ function fillUserDataToForm(id) {
         const roleSelect = document.getElementById('userRole');
         document.getElementById('userId').value = user.id;
         document.getElementById('userName').value = user.username;
+        document.getElementById('userEmail').value = user.email || '';
         document.getElementById('userRole').value = user.role;
         document.getElementById('userStatus').value = user.enable ? "1" : "0";
         if (user.id === currentUserID) {
@@ -233,6 +291,7 @@ async function submitUserData() {
     // --- 2. 构造提交数据 (Payload) ---
     const payload = {
         username: document.getElementById('userName').value,
+        email: document.getElementById('userEmail').value.trim(),
         role: inputRole,
         enable: document.getElementById('userStatus').value === "1"
     };
@@ -284,7 +343,7 @@ async function submitUserData() {
             // 刷新当前页面数据
             await loadUserList(currentPage);
         } else {
-            alert("操作失败：" + (result.message || "权限不足或服务器错误"));
+            alert("操作失败：" + (formatPasswordViolations(result) || "权限不足或服务器错误"));
         }
     } catch (error) {
         console.error("提交异常:", error);
@@ -302,9 +361,9 @@ document.addEventListener('DOMContentLoaded', () => {
             const file = e.target.files[0];
             if (file) {
                 // 验证文件格式
-                const allowedTypes = ['image/jpeg', 'image/png', 'image/gif'];
+                const allowedTypes = ['image/jpeg', 'image/png', 'image/gif', 'image/webp'];
                 if (!allowedTypes.includes(file.type)) {
-                    alert('只能上传以下格式的图片: JPEG、PNG、GIF');
+                    alert('只能上传以下格式的图片: JPEG、PNG、GIF、WebP');
                     // 清空文件选择
                     avatarInput.value = '';
                     return;
@@ -369,15 +428,14 @@ async function uploadAvatar(file, url) {
 async function deleteUser(id) {
     const numericId = parseInt(id, 10);
     const currentUserID = parseInt(localStorage.getItem('user_id'), 10);
-    const currentRole = localStorage.getItem('user_role');
     
     // 管理员不能删除自己
-    if (currentRole === 'admin' && numericId === currentUserID) {
+    if (hasPermission('users:delete') && numericId === currentUserID) {
         alert('管理员不能删除自己的账号');
         return;
     }
     
-    if (!confirm(`确定要删除 ID 为 ${numericId} 的用户吗？`)) return;
+    if (!confirm(`确定要删除 ID 为 ${numericId} 的用户吗？删除后可在回收站中恢复。`)) return;
 
     try {
         const response = await request(`/api/users/${numericId}`, {
@@ -399,6 +457,343 @@ async function deleteUser(id) {
     }
 }
 
+/**
+ * 8.0 导出：按当前搜索、筛选与排序导出全部用户 (不分页)
+ */
+async function exportUsers() {
+    const format = document.getElementById('exportFormat').value;
+    const queryParams = `format=${format}` + listFilterParams();
+
+    try {
+        const response = await fetch(`/api/users/export?${queryParams}`, {
+            headers: {
+                'Authorization': `Bearer ${localStorage.getItem('auth_token')}`
+            }
+        });
+        if (!response.ok) {
+            const result = await response.json().catch(() => ({}));
+            alert(result.message || '导出失败');
+            return;
+        }
+
+        // 从 Content-Disposition 中取服务端给出的文件名
+        const disposition = response.headers.get('Content-Disposition') || '';
+        const match = disposition.match(/filename="([^"]+)"/);
+        const blob = await response.blob();
+        const link = document.createElement('a');
+        link.href = URL.createObjectURL(blob);
+        link.download = match ? match[1] : `users.${format}`;
+        link.click();
+        URL.revokeObjectURL(link.href);
+    } catch (error) {
+        console.error('导出异常:', error);
+        alert('导出失败: ' + error.message);
+    }
+}
+
+/**
+ * 8.1 批量操作：对勾选的用户或当前筛选的全部用户执行同一操作
+ */
+function canBulkOperate() {
+    return hasPermission('users:update') || hasPermission('users:delete');
+}
+
+function toggleUserSelection(id, checked) {
+    if (checked) {
+        selectedUserIDs.add(id);
+    } else {
+        selectedUserIDs.delete(id);
+    }
+    updateBulkBar();
+}
+
+function toggleSelectAll(checked) {
+    document.querySelectorAll('#userTableBody .bulk-select:not(:disabled)').forEach(box => {
+        box.checked = checked;
+        toggleUserSelection(parseInt(box.value, 10), checked);
+    });
+}
+
+function clearBulkSelection() {
+    selectedUserIDs.clear();
+    document.querySelectorAll('#userTableBody .bulk-select').forEach(box => { box.checked = false; });
+    const selectAll = document.getElementById('selectAllUsers');
+    if (selectAll) selectAll.checked = false;
+    const useFilter = document.getElementById('bulkUseFilter');
+    if (useFilter) useFilter.checked = false;
+    updateBulkBar();
+}
+
+function updateBulkBar() {
+    const bar = document.getElementById('bulkBar');
+    if (!bar) return;
+    const useFilter = document.getElementById('bulkUseFilter').checked;
+    document.getElementById('bulkCount').textContent = useFilter ? `筛选结果 ${totalItems}` : selectedUserIDs.size;
+    bar.classList.toggle('hidden', !canBulkOperate() || (selectedUserIDs.size === 0 && !useFilter));
+    document.getElementById('bulkRole').classList.toggle('hidden', document.getElementById('bulkAction').value !== 'set-role');
+}
+
+async function submitBulk() {
+    const actionSelect = document.getElementById('bulkAction');
+    const action = actionSelect.value;
+    const useFilter = document.getElementById('bulkUseFilter').checked;
+    const body = { action };
+    if (useFilter) {
+        body.filter = { keyword: searchKeyword, status: statusFilter, role: roleFilter };
+    } else {
+        body.ids = Array.from(selectedUserIDs);
+    }
+    if (action === 'set-role') {
+        body.role = document.getElementById('bulkRole').value;
+    }
+
+    const count = useFilter ? totalItems : selectedUserIDs.size;
+    const label = actionSelect.options[actionSelect.selectedIndex].text;
+    if (!confirm(`确定对 ${count} 个用户执行"${label}"吗？`)) return;
+
+    try {
+        const response = await request('/api/users/bulk', {
+            method: 'POST',
+            body: JSON.stringify(body)
+        });
+        if (!response) return;
+        const result = await response.json();
+        if (result.code !== 200) {
+            alert(result.message || '批量操作失败');
+            return;
+        }
+
+        const report = result.data;
+        const failures = report.results.filter(r => !r.success);
+        let message = result.message;
+        if (failures.length) {
+            message += '\n\n' + failures.map(r => `${r.username || 'ID ' + r.id}：${r.message}`).join('\n');
+        }
+        alert(message);
+
+        // 重置密码：生成的密码只返回一次，下载保存
+        const passwords = report.results.filter(r => r.generated_password);
+        if (passwords.length) {
+            downloadCSV('users_reset_passwords.csv', [['id', 'username', 'password']]
+                .concat(passwords.map(r => [r.id, r.username, r.generated_password])));
+        }
+        await loadUserList(currentPage);
+    } catch (error) {
+        console.error('批量操作异常:', error);
+        alert('批量操作失败: ' + error.message);
+    }
+}
+
+/**
+ * 8.2 批量导入：先校验 (dry_run)，确认无误后再导入全部校验通过的行
+ */
+function openImportModal() {
+    importReport = null;
+    document.getElementById('importFile').value = '';
+    document.getElementById('importSummary').textContent = '';
+    document.getElementById('importTableBody').innerHTML = '';
+    document.getElementById('importCommitBtn').disabled = true;
+    document.getElementById('importDownloadBtn').classList.add('hidden');
+    document.getElementById('importModal').classList.remove('hidden');
+    // 更换文件后需重新校验
+    document.getElementById('importFile').onchange = () => {
+        document.getElementById('importCommitBtn').disabled = true;
+    };
+}
+
+function closeImportModal() {
+    if (importReport && !importReport.dry_run && importReport.rows.some(r => r.generated_password)
+        && !confirm('生成的密码只显示这一次，确定已下载导入结果吗？')) {
+        return;
+    }
+    document.getElementById('importModal').classList.add('hidden');
+}
+
+async function submitImport(dryRun) {
+    const file = document.getElementById('importFile').files[0];
+    if (!file) {
+        alert('请选择要导入的文件');
+        return;
+    }
+    if (!dryRun && !confirm(`确定导入 ${importReport ? importReport.valid : 0} 个校验通过的用户吗？`)) return;
+
+    const formData = new FormData();
+    formData.append('file', file);
+    formData.append('dry_run', dryRun ? 'true' : 'false');
+
+    try {
+        const response = await fetch('/api/users/import', {
+            method: 'POST',
+            headers: {
+                'Authorization': `Bearer ${localStorage.getItem('auth_token')}`
+            },
+            body: formData
+        });
+        const result = await response.json();
+        if (!response.ok || !result.success) {
+            alert(result.message || '导入失败');
+            return;
+        }
+
+        importReport = result.data;
+        document.getElementById('importSummary').textContent = result.message;
+        renderImportReport(importReport);
+        document.getElementById('importCommitBtn').disabled = !dryRun || importReport.valid === 0;
+        if (!dryRun) {
+            document.getElementById('importDownloadBtn').classList.remove('hidden');
+            await loadUserList(currentPage);
+        }
+    } catch (error) {
+        console.error('导入异常:', error);
+        alert('导入失败: ' + error.message);
+    }
+}
+
+function renderImportReport(report) {
+    const tbody = document.getElementById('importTableBody');
+    tbody.innerHTML = report.rows.map(row => {
+        let outcome;
+        if (row.errors && row.errors.length) {
+            outcome = `<ul class="text-xs text-red-600 list-disc pl-4">${row.errors.map(e => `<li>${e.field}：${e.message}</li>`).join('')}</ul>`;
+        } else if (row.id) {
+            outcome = `<span class="text-xs text-green-700">已创建 (ID ${row.id})</span>`
+                + (row.generated_password ? `<p class="text-xs font-mono text-gray-600">初始密码：${row.generated_password}</p>` : '');
+        } else {
+            outcome = `<span class="text-xs text-green-700">校验通过${row.password_generated ? '，将自动生成密码' : ''}</span>`;
+        }
+        return `
+            <tr>
+                <td class="px-4 py-2 text-sm">${row.row}</td>
+                <td class="px-4 py-2 text-sm">${row.username}${row.email ? `<p class="text-xs text-gray-500">${row.email}</p>` : ''}</td>
+                <td class="px-4 py-2 text-sm">${row.role} / ${row.status || '-'}</td>
+                <td class="px-4 py-2">${outcome}</td>
+            </tr>`;
+    }).join('');
+}
+
+// 导出 CSV 单元格 (含逗号、引号或换行时加引号)
+function csvCell(value) {
+    const s = String(value ?? '');
+    return /[",\n]/.test(s) ? `"${s.replace(/"/g, '""')}"` : s;
+}
+
+function downloadCSV(filename, lines) {
+    const blob = new Blob(['\ufeff' + lines.map(l => l.map(csvCell).join(',')).join('\n')], { type: 'text/csv;charset=utf-8' });
+    const link = document.createElement('a');
+    link.href = URL.createObjectURL(blob);
+    link.download = filename;
+    link.click();
+    URL.revokeObjectURL(link.href);
+}
+
+function downloadImportTemplate() {
+    downloadCSV('users_template.csv', [
+        ['username', 'password', 'role', 'status', 'email'],
+        ['zhangsan', '', 'common', 'enabled', 'zhangsan@example.com']
+    ]);
+}
+
+function downloadImportResult() {
+    if (!importReport) return;
+    const lines = [['row', 'username', 'email', 'role', 'status', 'id', 'generated_password', 'errors']];
+    importReport.rows.forEach(r => lines.push([
+        r.row, r.username, r.email || '', r.role, r.status, r.id || '', r.generated_password || '',
+        (r.errors || []).map(e => `${e.field}: ${e.message}`).join('; ')
+    ]));
+    downloadCSV('users_import_result.csv', lines);
+}
+
+/**
+ * 8.3 回收站：查看已删除的用户，恢复或彻底删除
+ */
+async function openTrashModal() {
+    trashPage = 1;
+    document.getElementById('trashModal').classList.remove('hidden');
+    await loadTrash(trashPage);
+}
+
+function closeTrashModal() {
+    document.getElementById('trashModal').classList.add('hidden');
+}
+
+async function loadTrash(page) {
+    try {
+        const response = await request(`/api/users/trash?page=${page}&limit=${pageSize}`);
+        if (!response) return;
+        const result = await response.json();
+        if (result.code !== 200) {
+            alert('加载回收站失败：' + (result.message || ''));
+            return;
+        }
+        trashTotal = result.data.total || 0;
+        document.getElementById('trash-total').textContent = trashTotal;
+        const days = result.data.retention_days;
+        document.getElementById('trashRetentionTip').textContent = days > 0
+            ? `已删除的用户保留 ${days} 天，到期后自动彻底删除`
+            : '已删除的用户将一直保留，直到手动彻底删除';
+        renderTrashTable(result.data.users || []);
+    } catch (error) {
+        console.error('加载回收站异常:', error);
+    }
+}
+
+function renderTrashTable(users) {
+    const tbody = document.getElementById('trashTableBody');
+    if (users.length === 0) {
+        tbody.innerHTML = `<tr><td colspan="4" class="text-center py-8 text-gray-400">回收站为空</td></tr>`;
+        return;
+    }
+    tbody.innerHTML = users.map(user => `
+        <tr>
+            <td class="px-4 py-2">
+                <p class="font-medium">${user.username}</p>
+                <p class="text-xs text-gray-500">ID: ${user.id}${user.email ? ' · ' + user.email : ''}</p>
+            </td>
+            <td class="px-4 py-2 text-sm">${user.role}</td>
+            <td class="px-4 py-2 text-sm">${formatDateTime(user.deleted_at)}</td>
+            <td class="px-4 py-2">
+                <button onclick="restoreUser(${user.id})" class="p-2 hover:bg-green-50 text-green-600 rounded-lg" title="恢复">
+                    <i data-feather="rotate-ccw" class="w-4 h-4"></i>
+                </button>
+                <button onclick="purgeUser(${user.id})" class="p-2 hover:bg-red-50 text-red-600 rounded-lg" title="彻底删除">
+                    <i data-feather="x-circle" class="w-4 h-4"></i>
+                </button>
+            </td>
+        </tr>`).join('');
+    if (typeof feather !== 'undefined') feather.replace();
+}
+
+function changeTrashPage(delta) {
+    const next = trashPage + delta;
+    if (next < 1 || (next - 1) * pageSize >= trashTotal) return;
+    trashPage = next;
+    loadTrash(trashPage);
+}
+
+async function restoreUser(id) {
+    const response = await request(`/api/users/${id}/restore`, { method: 'POST' });
+    if (!response) return;
+    const result = await response.json();
+    if (result.code !== 200) {
+        alert('恢复失败：' + (result.message || ''));
+        return;
+    }
+    await loadTrash(trashPage);
+    await loadUserList(currentPage);
+}
+
+async function purgeUser(id) {
+    if (!confirm(`确定要彻底删除 ID 为 ${id} 的用户吗？此操作无法撤销。`)) return;
+    const response = await request(`/api/users/${id}/purge`, { method: 'DELETE' });
+    if (!response) return;
+    const result = await response.json();
+    if (result.code !== 200) {
+        alert('删除失败：' + (result.message || ''));
+        return;
+    }
+    await loadTrash(trashPage);
+}
+
 /**
  * 9. 关闭模态框
  */
@@ -415,17 +810,19 @@ function renderUserTable(users) {
     if (!tbody) return;
 
     // --- 1. 获取当前登录者的权限信息 ---
-    const currentRole = localStorage.getItem('user_role');
+    const canUpdate = hasPermission('users:update');
+    const canDelete = hasPermission('users:delete');
+    const canBulk = canBulkOperate();
     const currentUserID = parseInt(localStorage.getItem('user_id'), 10);
 
     if (!users || users.length === 0) {
-        tbody.innerHTML = `<tr><td colspan="5" class="text-center py-10 text-gray-400">暂无相关用户信息</td></tr>`;
+        tbody.innerHTML = `<tr><td colspan="6" class="text-center py-10 text-gray-400">暂无相关用户信息</td></tr>`;
         return;
     }
 
     const html = users.map(user => {
         // --- 2. 判定当前行用户的权限状态 ---
-        const isAdmin = currentRole === 'admin';
+        const isAdmin = canUpdate;
         const isSelf = user.id === currentUserID;
 
         const lastLoginTime = user.last_login
@@ -438,7 +835,9 @@ function renderUserTable(users) {
 
         const statusConfig = user.enable
             ? { label: '正常启用', class: 'bg-green-100 text-green-800', dot: 'bg-green-500' }
-            : { label: '已禁用', class: 'bg-red-100 text-red-800', dot: 'bg-red-500' };
+            : user.status === 'pending'
+                ? { label: '待验证邮箱', class: 'bg-yellow-100 text-yellow-800', dot: 'bg-yellow-500' }
+                : { label: '已禁用', class: 'bg-red-100 text-red-800', dot: 'bg-red-500' };
 
         // --- 3. 动态生成操作按钮的 HTML ---
         let actionButtons = '';
@@ -449,10 +848,10 @@ function renderUserTable(users) {
                 actionButtons = `
                     <button onclick="editUser(${user.id})" class="p-2 hover:bg-blue-50 text-blue-600 rounded-lg transition-colors">
                         <i data-feather="edit-3" class="w-4 h-4"></i>
-                    </button>
+                    </button>` + (canDelete ? `
                     <button onclick="deleteUser(${user.id})" class="p-2 hover:bg-red-50 text-red-600 rounded-lg transition-colors">
                         <i data-feather="trash-2" class="w-4 h-4"></i>
-                    </button>`;
+                    </button>` : '');
             } else if (isSelf) {
                 // 当前登录的管理员自己：只显示编辑按钮，不显示删除按钮
                 actionButtons = `
@@ -478,16 +877,21 @@ function renderUserTable(users) {
 
         return `
             <tr class="hover:bg-gray-50 border-b transition-colors" id="user-row-${user.id}">
+                ${canBulk ? `
+                <td class="px-4 py-4">
+                    <input type="checkbox" class="bulk-select" value="${user.id}" ${isSelf ? 'disabled title="不能对自己执行批量操作"' : ''}
+                           onchange="toggleUserSelection(${user.id}, this.checked)">
+                </td>` : ''}
                 <td class="px-6 py-4">
                     <div class="flex items-center gap-3">
-                            <img src="${user.avatar ? user.avatar : 'https://ui-avatars.com/api/?name=' + encodeURIComponent(user.username) + '&background=random&size=128'}" 
+                            <img src="${user.avatar_thumbnails?.['64'] || user.avatar}" 
                                  class="w-10 h-10 rounded-full border border-gray-200" alt="头像">
                             <div>
                                 <p class="font-semibold text-gray-900">
                                     ${user.username} 
                                     ${isSelf ? '<span class="ml-1 text-[10px] bg-blue-50 text-blue-500 px-1 rounded">YOU</span>' : ''}
                                 </p>
-                                <p class="text-xs text-gray-500">ID: ${user.id}</p>
+                                <p class="text-xs text-gray-500">ID: ${user.id}${user.email ? ' · ' + user.email + (user.email_verified ? '' : '（未验证）') : ''}</p>
                             </div>
                     </div>
                 </td>
@@ -570,12 +974,8 @@ async function updateHeaderUserInfo() {
                 // 更新头像
                 const headerAvatar = document.querySelector('header img');
                 if (headerAvatar) {
-                    if (currentUser.avatar) {
-                        headerAvatar.src = currentUser.avatar;
-                    } else {
-                        // 使用默认头像
-                        headerAvatar.src = `https://ui-avatars.com/api/?name=${encodeURIComponent(currentUser.username)}&background=random&size=128`;
-                    }
+                    // 未上传头像时服务端返回默认头像地址
+                    headerAvatar.src = currentUser.avatar_thumbnails?.['64'] || currentUser.avatar;
                     // 添加鼠标悬停效果
                     headerAvatar.title = currentUser.username;
                 }
@@ -611,6 +1011,9 @@ async function logout() {
     localStorage.removeItem('auth_token');
     localStorage.removeItem('user_id');
     localStorage.removeItem('user_role');
+    localStorage.removeItem('user_permissions');
+    localStorage.removeItem('org_id');
+    localStorage.removeItem('user_orgs');
 
     // 跳转到登录页面
     window.location.href = '/login.html';
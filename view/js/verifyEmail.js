@@ -0,0 +1,62 @@
+/**
+ * 邮箱验证页面：带 token 时提交验证，无 token 或验证失败时可重新发送验证邮件
+ */
+document.addEventListener('DOMContentLoaded', () => {
+    const resendForm = document.getElementById('resendForm');
+    const verifyResult = document.getElementById('verifyResult');
+
+    // 邮件链接形如 /html/verify-email.html?token=xxx
+    const token = new URLSearchParams(window.location.search).get('token');
+    if (token) {
+        verifyResult.classList.remove('hidden');
+        verify(token);
+    } else {
+        resendForm.classList.remove('hidden');
+    }
+
+    // 提交验证令牌
+    async function verify(token) {
+        try {
+            const response = await fetch('/api/auth/verify-email', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ token: token })
+            });
+            const result = await response.json();
+            if (response.ok) {
+                verifyResult.textContent = '邮箱验证成功，现在可以登录了';
+                verifyResult.className = 'mb-6 text-center text-sm text-green-600';
+            } else {
+                verifyResult.textContent = result.message || '验证失败';
+                verifyResult.className = 'mb-6 text-center text-sm text-red-500';
+                resendForm.classList.remove('hidden');
+            }
+        } catch (error) {
+            console.error('Fetch Error:', error);
+            verifyResult.textContent = '无法连接到服务器，请检查后端程序是否运行';
+            verifyResult.className = 'mb-6 text-center text-sm text-red-500';
+        }
+    }
+
+    // 重新发送验证邮件
+    resendForm.addEventListener('submit', async (e) => {
+        e.preventDefault();
+        const btn = document.getElementById('resendBtn');
+        btn.disabled = true;
+
+        try {
+            const response = await fetch('/api/auth/resend-verification', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ username: document.getElementById('username').value.trim() })
+            });
+            const result = await response.json();
+            alert(result.message || (response.ok ? '请求已提交' : '请求失败'));
+        } catch (error) {
+            console.error('Fetch Error:', error);
+            alert('无法连接到服务器，请检查后端程序是否运行');
+        } finally {
+            btn.disabled = false;
+        }
+    });
+});